@@ -0,0 +1,35 @@
+// lotus-processor-gen generates the adapter of a typed processor, e.g.
+//
+//	//go:generate lotus-processor-gen -type ParseProcessor -request ParseRequest -response ParseResponse
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/shima-park/lotus/pkg/processor/gen"
+)
+
+func main() {
+	var opts gen.Options
+	flag.StringVar(&opts.Dir, "dir", ".", "directory of the package declaring the request and response")
+	flag.StringVar(&opts.Type, "type", "", "name of the generated processor type")
+	flag.StringVar(&opts.Request, "request", "", "name of the request struct")
+	flag.StringVar(&opts.Response, "response", "", "name of the response struct, the processor only returns an error if empty")
+	flag.StringVar(&opts.Output, "output", "", "name of the generated file, default <type>_gen.go")
+	flag.Parse()
+
+	src, err := gen.Generate(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lotus-processor-gen:", err)
+		os.Exit(1)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(opts.Dir, gen.OutputName(opts)), src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "lotus-processor-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	cmd "github.com/shima-park/lotus/pkg/cmd/lotussrv"
 	_ "github.com/shima-park/lotus/pkg/component/include"
+	_ "github.com/shima-park/lotus/pkg/processor/include"
 )
 
 var (
@@ -8,9 +8,14 @@ require (
 	github.com/docker/docker v1.13.1
 	github.com/gin-gonic/gin v1.6.3
 	github.com/go-redis/redis v6.15.8+incompatible
+	github.com/go-sql-driver/mysql v1.5.0
 	github.com/google/uuid v1.1.1
+	github.com/lib/pq v1.7.0
+	github.com/mattn/go-sqlite3 v1.14.0
+	github.com/nats-io/nats.go v1.10.0
 	github.com/olivere/elastic/v7 v7.0.17
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.0.0
+	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -111,10 +111,41 @@ func NewAddPluginCmd() *cobra.Command {
 	return cmd
 }
 
+func NewAddSharedCmd() *cobra.Command {
+	var file string
+	var comp string
+	cmd := &cobra.Command{
+		Use:   "shared NAME -c COMPONENT_NAME -f CONFIG_PATH",
+		Short: "Declare a shared component which can be referenced by executors",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				handleErr(errors.New("You need to provide a shared component name."))
+			}
+			if comp == "" {
+				handleErr(errors.New("You need to provide a component name."))
+			}
+
+			var config []byte
+			if file != "" {
+				var err error
+				config, err = ioutil.ReadFile(file)
+				handleErr(err)
+			}
+
+			err := newClient().Component.AddShared(args[0], comp, string(config))
+			handleErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "path to component config")
+	cmd.Flags().StringVarP(&comp, "component", "c", "", "name of component factory")
+
+	return cmd
+}
+
 func init() {
 	rootCmd.AddCommand(
 		NewAddCmd(
-			NewAddExecutorCmd(), NewAddPluginCmd(),
+			NewAddExecutorCmd(), NewAddPluginCmd(), NewAddSharedCmd(),
 		),
 	)
 }
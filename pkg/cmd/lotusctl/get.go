@@ -167,7 +167,7 @@ func NewGetCompCmd() *cobra.Command {
 						})
 					} else {
 						rows = append(rows, []string{
-							e.Name, e.RawConfig, e.Description, e.ReflectType, e.InjectName,
+							e.Name, e.RawConfig, e.Description, e.ReflectType, e.InjectName, healthString(e.Health),
 						})
 					}
 				}
@@ -175,6 +175,9 @@ func NewGetCompCmd() *cobra.Command {
 				header := []string{
 					"name", "config", "desc", "reflect_type", "inject_name",
 				}
+				if p != "" {
+					header = append(header, "health")
+				}
 
 				renderTable(header, rows)
 			} else {
@@ -195,6 +198,30 @@ func NewGetCompCmd() *cobra.Command {
 	return cmd
 }
 
+func NewGetSharedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shared",
+		Short: "Display shared component list",
+		Run: func(cmd *cobra.Command, args []string) {
+			list, err := newClient().Component.ListShared()
+			handleErr(err)
+
+			var rows [][]string
+			for _, e := range list {
+				if len(args) > 0 && !stringInSlice(e.Name, args) {
+					continue
+				}
+				rows = append(rows, []string{
+					e.Name, e.Component, e.RawConfig, fmt.Sprint(e.Refs), fmt.Sprint(e.Started),
+				})
+			}
+
+			renderTable([]string{"name", "component", "config", "refs", "started"}, rows)
+		},
+	}
+	return cmd
+}
+
 func NewGetProcCmd() *cobra.Command {
 	var o string
 	var p string
@@ -311,7 +338,7 @@ func NewGetServerCmd() *cobra.Command {
 func init() {
 	rootCmd.AddCommand(
 		NewGetCmd(
-			NewGetPipeCmd(), NewGetCompCmd(), NewGetProcCmd(), NewGetPluginCmd(),
+			NewGetPipeCmd(), NewGetCompCmd(), NewGetSharedCmd(), NewGetProcCmd(), NewGetPluginCmd(),
 			NewGetServerCmd(),
 		),
 	)
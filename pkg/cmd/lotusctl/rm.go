@@ -51,10 +51,25 @@ func NewRMPluginCmd() *cobra.Command {
 	return cmd
 }
 
+func NewRMSharedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shared (NAME)",
+		Short: "Remove a shared component which is not referenced on the server",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				handleErr(errors.New("You must provide a shared component name"))
+			}
+			err := newClient().Component.RemoveShared(args...)
+			handleErr(err)
+		},
+	}
+	return cmd
+}
+
 func init() {
 	rootCmd.AddCommand(
 		NewRMCmd(
-			NewRMPipeCmd(), NewRMPluginCmd(),
+			NewRMPipeCmd(), NewRMPluginCmd(), NewRMSharedCmd(),
 		),
 	)
 }
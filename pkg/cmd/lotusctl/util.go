@@ -11,6 +11,7 @@ import (
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/shima-park/lotus/pkg/rpc/http/client"
+	"github.com/shima-park/lotus/pkg/rpc/proto"
 )
 
 func newClient(hosts ...string) *client.Client {
@@ -90,3 +91,13 @@ func tryDownloadAndCheckPath(path string) (string, error) {
 
 	return path, err
 }
+
+func healthString(h *proto.ComponentHealthView) string {
+	if h == nil {
+		return "-"
+	}
+	if h.Healthy {
+		return "healthy"
+	}
+	return "unhealthy: " + h.Error
+}
@@ -0,0 +1,101 @@
+// Package checkpoint tracks a single input while it flows through a stream tree,
+// so that its source can be acknowledged only after every branch has handled it.
+package checkpoint
+
+import (
+	"sync"
+)
+
+// Checkpoint is injected into processors under the name "Checkpoint".
+// A source processor registers callbacks on it to acknowledge the input
+// (e.g. mark a kafka offset) once the whole stream tree has completed.
+type Checkpoint interface {
+	// OnCommit registers f to be called after every stream reached
+	// by the input has succeeded.
+	OnCommit(f func())
+
+	// OnAbort registers f to be called after all branches finished
+	// and at least one of them failed.
+	OnAbort(f func(error))
+}
+
+// Tracker is the Checkpoint implementation used by executors.
+// It counts the branches still pending for an input, every successful
+// stream replaces its own branch with one branch per child stream.
+type Tracker struct {
+	lock     sync.Mutex
+	pending  int
+	err      error
+	resolved bool
+	commits  []func()
+	aborts   []func(error)
+}
+
+var _ Checkpoint = &Tracker{}
+
+// New returns a Tracker with a single pending branch, the root stream.
+func New() *Tracker {
+	return &Tracker{pending: 1}
+}
+
+func (t *Tracker) OnCommit(f func()) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.commits = append(t.commits, f)
+}
+
+func (t *Tracker) OnAbort(f func(error)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.aborts = append(t.aborts, f)
+}
+
+// Fork replaces the current branch with n branches, n is the number of
+// child streams which will receive the output of the current stream.
+func (t *Tracker) Fork(n int) {
+	switch {
+	case n <= 0:
+		t.Done(nil)
+	case n > 1:
+		t.lock.Lock()
+		t.pending += n - 1
+		t.lock.Unlock()
+	}
+}
+
+// Done finishes the current branch, a non-nil err marks the whole input as failed.
+// The registered callbacks are called once the last branch is done.
+func (t *Tracker) Done(err error) {
+	t.lock.Lock()
+	if t.resolved {
+		t.lock.Unlock()
+		return
+	}
+
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+
+	t.pending--
+	if t.pending > 0 {
+		t.lock.Unlock()
+		return
+	}
+
+	t.resolved = true
+	commits, aborts, err := t.commits, t.aborts, t.err
+	t.lock.Unlock()
+
+	if err != nil {
+		for _, f := range aborts {
+			f(err)
+		}
+		return
+	}
+
+	for _, f := range commits {
+		f()
+	}
+}
@@ -0,0 +1,101 @@
+package checkpoint
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+
+	tests := []struct {
+		name    string
+		run     func(t *Tracker)
+		commits int
+		aborted error
+	}{
+		{
+			name:    "single branch",
+			run:     func(t *Tracker) { t.Done(nil) },
+			commits: 1,
+		},
+		{
+			name: "fork into three branches",
+			run: func(t *Tracker) {
+				t.Fork(3)
+				t.Done(nil)
+				t.Done(nil)
+				t.Done(nil)
+			},
+			commits: 1,
+		},
+		{
+			name: "branches still pending",
+			run: func(t *Tracker) {
+				t.Fork(3)
+				t.Done(nil)
+				t.Done(nil)
+			},
+		},
+		{
+			name: "nested forks",
+			run: func(t *Tracker) {
+				t.Fork(2)
+				t.Fork(2) // 第一个分支又分成两个
+				t.Done(nil)
+				t.Done(nil)
+				t.Fork(1)
+				t.Done(nil)
+			},
+			commits: 1,
+		},
+		{
+			name: "fork without children finishes the branch",
+			run: func(t *Tracker) {
+				t.Fork(2)
+				t.Fork(0)
+				t.Done(nil)
+			},
+			commits: 1,
+		},
+		{
+			name: "one failed branch aborts with the first error",
+			run: func(t *Tracker) {
+				t.Fork(3)
+				t.Done(nil)
+				t.Done(errFirst)
+				t.Done(errSecond)
+			},
+			aborted: errFirst,
+		},
+		{
+			name: "done after resolved is ignored",
+			run: func(t *Tracker) {
+				t.Done(nil)
+				t.Done(errFirst)
+			},
+			commits: 1,
+		},
+	}
+
+	for _, test := range tests {
+		tracker := New()
+
+		var commits, aborts int
+		var aborted error
+		tracker.OnCommit(func() { commits++ })
+		tracker.OnAbort(func(err error) {
+			aborts++
+			aborted = err
+		})
+
+		test.run(tracker)
+
+		if commits != test.commits {
+			t.Errorf("%s: Expected %d commits - Got %d", test.name, test.commits, commits)
+		}
+		if aborted != test.aborted || (test.aborted != nil && aborts != 1) {
+			t.Errorf("%s: Expected abort with %v - Got %v (%d aborts)", test.name, test.aborted, aborted, aborts)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateTagKey 注入后对字段值的约束, 写法类似go-playground/validator, e.g.
+//
+//	Workers int    `inject:"workers,default=4" validate:"min=1,max=64"`
+//	Mode    string `inject:"mode,optional" validate:"oneof=sync async"`
+//
+// 支持的约束:
+//
+//	required  值不能是零值
+//	min/max   数字比较值, string/slice/map比较长度, time.Duration可以写成1s
+//	len       string/slice/map的长度
+//	oneof     空格分隔的可选值
+const ValidateTagKey = "validate"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type constraint struct {
+	name  string
+	param string
+}
+
+func parseConstraints(tag string) ([]constraint, error) {
+	var list []constraint
+	for _, s := range strings.Split(tag, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		c := constraint{name: s}
+		if idx := strings.Index(s, "="); idx != -1 {
+			c.name, c.param = s[:idx], s[idx+1:]
+		}
+
+		switch c.name {
+		case "required":
+		case "min", "max", "len", "oneof":
+			if c.param == "" {
+				return nil, fmt.Errorf("Constraint %s requires a param", c.name)
+			}
+		default:
+			return nil, fmt.Errorf("Unknown constraint: %s", c.name)
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func isContainer(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	}
+	return false
+}
+
+func isNumber(t reflect.Type) bool {
+	k := t.Kind()
+	return (k >= reflect.Int && k <= reflect.Uintptr) || k == reflect.Float32 || k == reflect.Float64
+}
+
+// measure returns what min, max and len compare, the length of a container or the value of a number.
+func measure(v reflect.Value) float64 {
+	switch {
+	case isContainer(v.Type()):
+		return float64(v.Len())
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return float64(v.Int())
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// bound parses the param of min, max and len, a time.Duration can be written like 1s.
+func (c constraint) bound(t reflect.Type) (float64, error) {
+	if t == durationType {
+		d, err := time.ParseDuration(c.param)
+		return float64(d), err
+	}
+	return strconv.ParseFloat(c.param, 64)
+}
+
+// applies checks that the constraint can apply to a field of type t.
+func (c constraint) applies(t reflect.Type) error {
+	var ok bool
+	switch c.name {
+	case "required":
+		ok = true
+	case "min", "max":
+		ok = isContainer(t) || isNumber(t)
+	case "len":
+		ok = isContainer(t)
+	case "oneof":
+		ok = t.Kind() == reflect.String || isNumber(t)
+	}
+	if !ok {
+		return fmt.Errorf("Constraint %s cannot apply to type: %v", c.name, t)
+	}
+
+	if c.name == "min" || c.name == "max" || c.name == "len" {
+		if _, err := c.bound(t); err != nil {
+			return fmt.Errorf("Invalid param of constraint %s: %s", c.name, err)
+		}
+	}
+	return nil
+}
+
+// check validates v, the caller must have checked that the constraint applies.
+func (c constraint) check(v reflect.Value) error {
+	switch c.name {
+	case "required":
+		if v.IsZero() {
+			return fmt.Errorf("Value is required")
+		}
+
+	case "min", "max", "len":
+		n := measure(v)
+		bound, _ := c.bound(v.Type())
+		if (c.name == "min" && n < bound) || (c.name == "max" && n > bound) || (c.name == "len" && n != bound) {
+			return fmt.Errorf("Value %v does not satisfy %s=%s", v.Interface(), c.name, c.param)
+		}
+
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, item := range strings.Fields(c.param) {
+			if s == item {
+				return nil
+			}
+		}
+		return fmt.Errorf("Value %s is not one of: %s", s, c.param)
+	}
+	return nil
+}
+
+func constraintsOf(structField reflect.StructField) ([]constraint, error) {
+	tag, ok := structField.Tag.Lookup(ValidateTagKey)
+	if !ok {
+		return nil, nil
+	}
+
+	constraints, err := parseConstraints(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range constraints {
+		if err := c.applies(structField.Type); err != nil {
+			return nil, err
+		}
+	}
+	return constraints, nil
+}
+
+func validateField(structField reflect.StructField, v reflect.Value) error {
+	constraints, err := constraintsOf(structField)
+	if err != nil {
+		return err
+	}
+	for _, c := range constraints {
+		if err := c.check(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseDefault converts the default option to the type of a scalar field,
+// an invalid default is reported by CheckField before the processor runs.
+func ParseDefault(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+
+	var err error
+	switch {
+	case t == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(s); err == nil {
+			v.SetInt(int64(d))
+		}
+	case t.Kind() == reflect.String:
+		v.SetString(s)
+	case t.Kind() == reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, t.Bits()); err == nil {
+			v.SetInt(n)
+		}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, t.Bits()); err == nil {
+			v.SetUint(n)
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		var n float64
+		if n, err = strconv.ParseFloat(s, t.Bits()); err == nil {
+			v.SetFloat(n)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("Default value is not supported for type: %v", t)
+	}
+
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("Invalid default value %q of type %v: %s", s, t, err)
+	}
+	return v, nil
+}
+
+// CheckField checks the inject options and the constraints of an inject field without its value,
+// e.g. a default value of the wrong type or a constraint that cannot apply to the field.
+func CheckField(structField reflect.StructField) error {
+	ia := GetInjectAnnotation(structField)
+
+	if ia.Options.Contains(InjectTagOptionsAll) {
+		if err := checkAllType(structField.Type); err != nil {
+			return err
+		}
+	}
+
+	if _, err := constraintsOf(structField); err != nil {
+		return err
+	}
+
+	// 默认值在check时就能确定, 其他值要到运行时才能校验
+	if def, ok := ia.Default(); ok {
+		v, err := ParseDefault(structField.Type, def)
+		if err != nil {
+			return err
+		}
+		return validateField(structField, v)
+	}
+	return nil
+}
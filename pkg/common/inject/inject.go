@@ -4,6 +4,7 @@ package inject
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -33,7 +34,8 @@ type Applicator interface {
 // Invoker represents an interface for calling functions via reflection.
 type Invoker interface {
 	// Invoke attempts to call the interface{} provided as a function,
-	// providing dependencies for function arguments based on Type. Returns
+	// providing dependencies for function arguments based on Type, see
+	// InvokeWithNames for injecting them by name. Returns
 	// a slice of reflect.Value representing the returned values of the function.
 	// Returns an error if the injection fails.
 	Invoke(interface{}) ([]reflect.Value, error)
@@ -52,16 +54,40 @@ type TypeMapper interface {
 	// with reflect like unidirectional channels.
 	Set(typ reflect.Type, name string, value reflect.Value) TypeMapper
 	// Returns the Value that is mapped to the current type. Returns a zeroed Value if
-	// the Type has not been mapped.
+	// the Type has not been mapped, or if more than one implementor of the interface
+	// has the name and none of them is primary.
 	Get(typ reflect.Type, name string) reflect.Value
+	// Returns the only Value assignable to the type regardless of its name,
+	// a concrete type takes precedence over the implementors of an interface.
+	// Returns an error if there is none, or more than one without a primary in the nearest injector.
+	GetByType(typ reflect.Type) (reflect.Value, error)
+	// Maps the first return type of constructor to a value created lazily by calling it,
+	// the arguments of constructor are injected like Invoke.
+	// See Scope for when the constructor is called.
+	Provide(constructor interface{}, name string, scope Scope) error
+	// Returns nil if Get would find a value, without calling the providers.
+	// Returns NotFoundError or AmbiguousError otherwise.
+	Lookup(typ reflect.Type, name string) error
+	// Marks the value of type and name as the one chosen when more than one
+	// value is assignable to an interface.
+	SetPrimary(typ reflect.Type, name string) TypeMapper
+	// Returns the name GetByType would resolve, without calling the providers.
+	NameByType(typ reflect.Type) (string, error)
+	// Returns every value assignable to the type in this injector and its parents,
+	// a value shadows the one of the same type and name in the parents.
+	GetAll(typ reflect.Type) ([]Binding, error)
+	// Returns the names GetAll would find, without calling the providers.
+	LookupAll(typ reflect.Type) []string
 
 	MapValues(vals ...reflect.Value) error
 }
 
 type injector struct {
-	lock   sync.RWMutex
-	values map[reflect.Type]map[string]reflect.Value
-	parent Injector
+	lock      sync.RWMutex
+	values    map[reflect.Type]map[string]reflect.Value
+	providers map[reflect.Type]map[string]*provider
+	primaries map[reflect.Type]map[string]bool
+	parent    Injector
 }
 
 // InterfaceOf dereferences a pointer to an Interface type.
@@ -81,35 +107,46 @@ func InterfaceOf(value interface{}) reflect.Type {
 }
 
 // New returns a new Injector.
+// 每次调度都会创建injector, map在第一次写入时才分配
 func New() Injector {
-	return &injector{
-		values: make(map[reflect.Type]map[string]reflect.Value),
-	}
+	return &injector{}
 }
 
 // Invoke attempts to call the interface{} provided as a function,
 // providing dependencies for function arguments based on Type.
+// Arguments that are structs with inject tags are filled field by field,
+// other arguments are resolved as a whole by type, see InvokeWithNames.
 // Returns a slice of reflect.Value representing the returned values of the function.
 // Returns an error if the injection fails.
 // It panics if f is not a function
 func (inj *injector) Invoke(f interface{}) ([]reflect.Value, error) {
+	return inj.invoke(f, nil, nil)
+}
+
+// invoke is InvokeWithNames as a step of the resolution r, e.g. the call of a provider, r is nil at the top.
+func (inj *injector) invoke(f interface{}, names []string, r *resolution) ([]reflect.Value, error) {
 	t := reflect.TypeOf(f)
 
 	var in = make([]reflect.Value, t.NumIn()) //Panic if t is not kind of Func
 	for i := 0; i < t.NumIn(); i++ {
 		argType := t.In(i)
 
-		for argType.Kind() == reflect.Ptr {
-			argType = argType.Elem()
+		if !IsInjectStruct(argType) {
+			val, err := inj.resolve(argType, ParamName(names, i), inj, r)
+			if err != nil {
+				return nil, fmt.Errorf("Argument %d: %s", i, err)
+			}
+			in[i] = val
+			continue
 		}
 
-		if argType.Kind() != reflect.Struct {
-			return nil, fmt.Errorf("Cannot support types other than structures %v", argType)
+		for argType.Kind() == reflect.Ptr {
+			argType = argType.Elem()
 		}
 
 		val := reflect.New(argType)
 
-		if err := inj.Apply(val.Interface()); err != nil {
+		if err := inj.apply(val.Interface(), r); err != nil {
 			return nil, err
 		}
 
@@ -127,6 +164,10 @@ func (inj *injector) Invoke(f interface{}) ([]reflect.Value, error) {
 // that is tagged with 'inject'.
 // Returns an error if the injection fails.
 func (inj *injector) Apply(val interface{}) error {
+	return inj.apply(val, nil)
+}
+
+func (inj *injector) apply(val interface{}, r *resolution) error {
 	v := reflect.ValueOf(val)
 
 	for v.Kind() == reflect.Ptr {
@@ -137,27 +178,68 @@ func (inj *injector) Apply(val interface{}) error {
 		return nil // Should not panic here ?
 	}
 
-	t := v.Type()
-
 	for i := 0; i < v.NumField(); i++ {
-		f := v.Field(i)
-		structField := t.Field(i)
-		ia := GetInjectAnnotation(structField)
-		if f.CanSet() && ia.Exists {
-			ft := f.Type()
-			v := inj.Get(ft, ia.Name)
-
-			if v.IsValid() {
-				f.Set(v)
-			} else {
-				if !ia.Options.Contains(InjectTagOptionsOptional) {
-					return fmt.Errorf("Value not found for type: %v name: %v", ft, ia.Name)
-				}
+		if err := inj.applyField(v, i, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyField injects the i-th field of the addressable struct v like Apply,
+// the adapters generated for typed processors call it for the fields with options.
+// Other implementations of Injector apply the whole struct.
+func ApplyField(inj Injector, v reflect.Value, i int) error {
+	if ij, ok := inj.(*injector); ok {
+		return ij.applyField(v, i, nil)
+	}
+	return inj.Apply(v.Addr().Interface())
+}
+
+func (inj *injector) applyField(v reflect.Value, i int, r *resolution) error {
+	f := v.Field(i)
+	structField := v.Type().Field(i)
+	ia := GetInjectAnnotation(structField)
+	if !f.CanSet() || !ia.Exists {
+		return nil
+	}
+
+	ft := f.Type()
+
+	var (
+		val reflect.Value
+		err error
+	)
+	if ia.Options.Contains(InjectTagOptionsAll) {
+		val, err = inj.collectAll(ft, r)
+	} else {
+		val, err = inj.getValue(ft, ia.Name, inj, r)
+	}
+	if err != nil {
+		return fmt.Errorf("Field %s: %s", structField.Name, err)
+	}
+
+	if !val.IsValid() {
+		if def, ok := ia.Default(); ok {
+			if val, err = ParseDefault(ft, def); err != nil {
+				return fmt.Errorf("Field %s: %s", structField.Name, err)
 			}
+		} else if !ia.Options.Contains(InjectTagOptionsOptional) {
+			return fmt.Errorf("Value not found for type: %v name: %v", ft, ia.Name)
+		} else {
+			// 没有提供也没有默认值的optional字段保持零值, 不做约束检查
+			return nil
 		}
+	}
 
+	if val.IsValid() {
+		f.Set(val)
 	}
 
+	if err := validateField(structField, f); err != nil {
+		return fmt.Errorf("Field %s: %s", structField.Name, err)
+	}
 	return nil
 }
 
@@ -177,21 +259,14 @@ func (i *injector) Set(typ reflect.Type, name string, val reflect.Value) TypeMap
 	return i.set(typ, name, val)
 }
 
-func (i *injector) get(typ reflect.Type, name string) (val reflect.Value) {
-	i.lock.RLock()
-	defer i.lock.RUnlock()
-
-	if m, ok := i.values[typ]; ok {
-		val = m[name]
-		return
-	}
-	return
-}
-
 func (i *injector) set(typ reflect.Type, name string, val reflect.Value) TypeMapper {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
+	if i.values == nil {
+		i.values = map[reflect.Type]map[string]reflect.Value{}
+	}
+
 	var m map[string]reflect.Value
 	var ok bool
 	if m, ok = i.values[typ]; !ok {
@@ -203,39 +278,444 @@ func (i *injector) set(typ reflect.Type, name string, val reflect.Value) TypeMap
 	return i
 }
 
-func (i *injector) Get(t reflect.Type, name string) reflect.Value {
-	val := i.get(t, name)
-	if val.IsValid() {
-		return val
+// entry is a value or a provider mapped in the injector.
+type entry struct {
+	typ      reflect.Type
+	name     string
+	value    reflect.Value
+	provider *provider
+	primary  bool
+}
+
+func (e entry) valid() bool {
+	return e.provider != nil || e.value.IsValid()
+}
+
+func (e entry) get(origin Injector, r *resolution) (reflect.Value, error) {
+	if e.provider != nil {
+		return e.provider.get(origin, r)
+	}
+	return e.value, nil
+}
+
+func (e entry) String() string {
+	return fmt.Sprintf("%s(%s)", e.typ, e.name)
+}
+
+// choose returns the only entry of the list, or the only primary one if there are more,
+// the list is sorted so that the candidates of AmbiguousError are stable.
+func choose(t reflect.Type, list []entry) (entry, error) {
+	switch len(list) {
+	case 0:
+		return entry{}, NotFoundError{Type: t}
+	case 1:
+		return list[0], nil
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].String() < list[j].String()
+	})
+
+	var (
+		primaries  []entry
+		candidates []string
+	)
+	for _, e := range list {
+		if e.primary {
+			primaries = append(primaries, e)
+		}
+		candidates = append(candidates, e.String())
+	}
+	if len(primaries) == 1 {
+		return primaries[0], nil
+	}
+	return entry{}, AmbiguousError{Type: t, Candidates: candidates}
+}
+
+// collect returns the valid entries of type k accepted by match, the caller must hold the lock.
+func (i *injector) collect(k reflect.Type, match func(name string) bool) []entry {
+	var list []entry
+	for n, v := range i.values[k] {
+		if v.IsValid() && match(n) {
+			list = append(list, entry{typ: k, name: n, value: v, primary: i.primaries[k][n]})
+		}
+	}
+	for n, p := range i.providers[k] {
+		if match(n) {
+			list = append(list, entry{typ: k, name: n, provider: p, primary: i.primaries[k][n]})
+		}
+	}
+	return list
+}
+
+// types returns the mapped types, the caller must hold the lock.
+func (i *injector) types() []reflect.Type {
+	var list []reflect.Type
+	for k := range i.values {
+		list = append(list, k)
+	}
+	for k := range i.providers {
+		if _, ok := i.values[k]; !ok {
+			list = append(list, k)
+		}
 	}
+	return list
+}
+
+// lookup finds the entry of type and name in this injector only,
+// a concrete type takes precedence over the implementors of an interface.
+// If more than one implementor has the name, the primary one is chosen.
+func (i *injector) lookup(t reflect.Type, name string) (entry, error) {
+	e, ok, err := i.find(t, name)
+	if err == nil && !ok {
+		err = NotFoundError{Type: t, Name: name}
+	}
+	return e, err
+}
+
+// find is lookup without allocating an error if nothing is found,
+// getValue calls it on every injector up to the one mapping the value.
+func (i *injector) find(t reflect.Type, name string) (entry, bool, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	// 同类型同名最多只有一个值和一个provider, 值优先
+	if v, ok := i.values[t][name]; ok && v.IsValid() {
+		return entry{typ: t, name: name, value: v, primary: i.primaries[t][name]}, true, nil
+	}
+	if p, ok := i.providers[t][name]; ok {
+		return entry{typ: t, name: name, provider: p, primary: i.primaries[t][name]}, true, nil
+	}
+
+	byName := func(n string) bool { return n == name }
 
 	// no concrete types found, try to find implementors
 	// if t is an interface
+	var list []entry
 	if t.Kind() == reflect.Interface {
-		i.lock.RLock()
-		for k, m := range i.values {
-			for n, v := range m {
-				if n == name && k.Implements(t) {
-					val = v
-					break
-				}
+		for _, k := range i.types() {
+			if k != t && k.Implements(t) {
+				list = append(list, i.collect(k, byName)...)
 			}
 		}
-		i.lock.RUnlock()
 	}
 
-	// Still no type found, try to look it up on the parent
-	if !val.IsValid() && i.parent != nil {
-		val = i.parent.Get(t, name)
+	if len(list) == 0 {
+		return entry{}, false, nil
 	}
 
+	e, err := choose(t, list)
+	return e, err == nil, err
+}
+
+// entryByType returns the only entry assignable to t in this injector,
+// or the primary one if there are more.
+func (i *injector) entryByType(t reflect.Type) (entry, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	all := func(string) bool { return true }
+	list := i.collect(t, all)
+
+	if len(list) == 0 && t.Kind() == reflect.Interface {
+		for _, k := range i.types() {
+			if k != t && k.Implements(t) {
+				list = append(list, i.collect(k, all)...)
+			}
+		}
+	}
+	return choose(t, list)
+}
+
+func (i *injector) Get(t reflect.Type, name string) reflect.Value {
+	val, _ := i.getValue(t, name, i, nil)
 	return val
 }
 
+// getValue returns the value of type and name, the transient providers inject their arguments from origin.
+// It returns an invalid value without error if nothing is found.
+func (i *injector) getValue(t reflect.Type, name string, origin Injector, r *resolution) (reflect.Value, error) {
+	e, ok, err := i.find(t, name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if ok {
+		return e.get(origin, r)
+	}
+
+	// Still no type found, try to look it up on the parent
+	if i.parent == nil {
+		return reflect.Value{}, nil
+	}
+	if p, ok := i.parent.(*injector); ok {
+		return p.getValue(t, name, origin, r)
+	}
+	return i.parent.Get(t, name), nil
+}
+
+func (i *injector) Lookup(t reflect.Type, name string) error {
+	_, err := i.lookup(t, name)
+	if _, ok := err.(NotFoundError); ok && i.parent != nil {
+		return i.parent.Lookup(t, name)
+	}
+	return err
+}
+
+func (i *injector) GetByType(t reflect.Type) (reflect.Value, error) {
+	return i.getByType(t, i, nil)
+}
+
+func (i *injector) getByType(t reflect.Type, origin Injector, r *resolution) (reflect.Value, error) {
+	e, err := i.entryByType(t)
+	if err == nil {
+		return e.get(origin, r)
+	}
+
+	if _, ok := err.(AmbiguousError); ok || i.parent == nil {
+		return reflect.Value{}, err
+	}
+	if p, ok := i.parent.(*injector); ok {
+		return p.getByType(t, origin, r)
+	}
+	return i.parent.GetByType(t)
+}
+
+func (i *injector) NameByType(t reflect.Type) (string, error) {
+	e, err := i.entryByType(t)
+	if err == nil {
+		return e.name, nil
+	}
+
+	if _, ok := err.(AmbiguousError); ok || i.parent == nil {
+		return "", err
+	}
+	return i.parent.NameByType(t)
+}
+
+// Binding is a value found by GetAll.
+type Binding struct {
+	Type  reflect.Type // the mapped type
+	Name  string
+	Value reflect.Value
+}
+
+// entriesOf returns all the entries assignable to t in this injector, sorted like choose.
+func (i *injector) entriesOf(t reflect.Type) []entry {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	all := func(string) bool { return true }
+	list := i.collect(t, all)
+	if t.Kind() == reflect.Interface {
+		for _, k := range i.types() {
+			if k != t && k.Implements(t) {
+				list = append(list, i.collect(k, all)...)
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].String() < list[j].String()
+	})
+	return list
+}
+
+func (i *injector) GetAll(t reflect.Type) ([]Binding, error) {
+	return i.getAll(t, i, map[string]bool{}, nil)
+}
+
+// getAll skips the entries in seen, which are shadowed by a child injector.
+func (i *injector) getAll(t reflect.Type, origin Injector, seen map[string]bool, r *resolution) ([]Binding, error) {
+	var list []Binding
+	for _, e := range i.entriesOf(t) {
+		if seen[e.String()] {
+			continue
+		}
+		seen[e.String()] = true
+
+		v, err := e.get(origin, r)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Binding{Type: e.typ, Name: e.name, Value: v})
+	}
+
+	if i.parent == nil {
+		return list, nil
+	}
+
+	var (
+		parent []Binding
+		err    error
+	)
+	if p, ok := i.parent.(*injector); ok {
+		parent, err = p.getAll(t, origin, seen, r)
+	} else {
+		parent, err = i.parent.GetAll(t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(list, parent...), nil
+}
+
+func (i *injector) LookupAll(t reflect.Type) []string {
+	return i.lookupAll(t, map[string]bool{})
+}
+
+func (i *injector) lookupAll(t reflect.Type, seen map[string]bool) []string {
+	var names []string
+	for _, e := range i.entriesOf(t) {
+		if !seen[e.String()] {
+			seen[e.String()] = true
+			names = append(names, e.name)
+		}
+	}
+
+	if p, ok := i.parent.(*injector); ok {
+		return append(names, p.lookupAll(t, seen)...)
+	}
+	if i.parent != nil {
+		names = append(names, i.parent.LookupAll(t)...)
+	}
+	return names
+}
+
+// collectAll returns a slice or a map keyed by name of all the values assignable to the element type of t,
+// It returns an invalid value without error if nothing is found.
+func (i *injector) collectAll(t reflect.Type, r *resolution) (reflect.Value, error) {
+	if err := checkAllType(t); err != nil {
+		return reflect.Value{}, err
+	}
+
+	list, err := i.getAll(t.Elem(), i, map[string]bool{}, r)
+	if err != nil || len(list) == 0 {
+		return reflect.Value{}, err
+	}
+
+	if t.Kind() == reflect.Slice {
+		v := reflect.MakeSlice(t, 0, len(list))
+		for _, b := range list {
+			v = reflect.Append(v, b.Value)
+		}
+		return v, nil
+	}
+
+	v := reflect.MakeMapWithSize(t, len(list))
+	for _, b := range list {
+		key := reflect.ValueOf(b.Name).Convert(t.Key())
+		if v.MapIndex(key).IsValid() {
+			return reflect.Value{}, fmt.Errorf("Duplicate name: %s of type: %v", b.Name, t.Elem())
+		}
+		v.SetMapIndex(key, b.Value)
+	}
+	return v, nil
+}
+
+func checkAllType(t reflect.Type) error {
+	switch {
+	case t.Kind() == reflect.Slice:
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+	default:
+		return fmt.Errorf("Option %s requires a slice or a map with string keys, got %v", InjectTagOptionsAll, t)
+	}
+	return nil
+}
+
+// resolve returns the value of an argument, by type and name if name is not empty, otherwise by type only.
+func (i *injector) resolve(t reflect.Type, name string, origin Injector, r *resolution) (reflect.Value, error) {
+	if name == "" {
+		return i.getByType(t, origin, r)
+	}
+
+	v, err := i.getValue(t, name, origin, r)
+	if err != nil {
+		return v, err
+	}
+	if !v.IsValid() {
+		return v, NotFoundError{Type: t, Name: name}
+	}
+	return v, nil
+}
+
+// entryOf returns the entry resolve gets the value from, looking it up on the parents.
+func (i *injector) entryOf(t reflect.Type, name string) (entry, error) {
+	var (
+		e   entry
+		err error
+	)
+	if name == "" {
+		e, err = i.entryByType(t)
+	} else {
+		e, err = i.lookup(t, name)
+	}
+	if err == nil {
+		return e, nil
+	}
+
+	if _, ok := err.(AmbiguousError); ok {
+		return e, err
+	}
+	if p, ok := i.parent.(*injector); ok {
+		return p.entryOf(t, name)
+	}
+	return e, err
+}
+
+func (i *injector) SetPrimary(t reflect.Type, name string) TypeMapper {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.primaries == nil {
+		i.primaries = map[reflect.Type]map[string]bool{}
+	}
+
+	m, ok := i.primaries[t]
+	if !ok {
+		m = map[string]bool{}
+		i.primaries[t] = m
+	}
+	m[name] = true
+	return i
+}
+
 func (i *injector) SetParent(parent Injector) {
 	i.parent = parent
 }
 
+// NotFoundError is returned if no value is mapped to the type and name.
+type NotFoundError struct {
+	Type reflect.Type
+	Name string
+}
+
+func (e NotFoundError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("Value not found for type: %v", e.Type)
+	}
+	return fmt.Sprintf("Value not found for type: %v name: %v", e.Type, e.Name)
+}
+
+// AmbiguousError is returned if more than one value is assignable to the type and none of them is primary.
+type AmbiguousError struct {
+	Type       reflect.Type
+	Candidates []string // type(name)
+}
+
+func (e AmbiguousError) Error() string {
+	return fmt.Sprintf("Ambiguous values for type: %v, candidates: %s, choose one by name or mark it as primary",
+		e.Type, strings.Join(e.Candidates, ", "))
+}
+
+// CycleError is returned if a provider depends on itself through the arguments of other providers.
+type CycleError struct {
+	Path []string // type(name), 首尾是同一个provider
+}
+
+func (e CycleError) Error() string {
+	return fmt.Sprintf("Provider cycle: %s", strings.Join(e.Path, " -> "))
+}
+
 func (i *injector) MapValues(vals ...reflect.Value) error {
 	for _, val := range vals {
 		// 处理返回值中带error的情况
@@ -274,6 +754,10 @@ func (i *injector) MapValues(vals ...reflect.Value) error {
 const (
 	InjectTagKey             = "inject"
 	InjectTagOptionsOptional = "optional"
+	// 注入所有可赋值给元素类型的值, 字段必须是slice或者key为string的map, e.g. `inject:",all"`
+	InjectTagOptionsAll = "all"
+	// 找不到值时使用的默认值, 只支持基础类型和time.Duration, e.g. `inject:"timeout,default=5s"`
+	InjectTagOptionsDefault = "default="
 )
 
 type InjectAnnotation struct {
@@ -284,8 +768,8 @@ type InjectAnnotation struct {
 
 func GetInjectAnnotation(structField reflect.StructField) InjectAnnotation {
 	tag := structField.Tag
-	tagVal := tag.Get(InjectTagKey)
-	if tag == InjectTagKey || tagVal != "" {
+	tagVal, ok := tag.Lookup(InjectTagKey)
+	if tag == InjectTagKey || ok {
 		var name string
 		var options TagOptions
 		if tagVal == "" {
@@ -303,6 +787,11 @@ func GetInjectAnnotation(structField reflect.StructField) InjectAnnotation {
 	return InjectAnnotation{}
 }
 
+// Default returns the default value of the field, a field with a default value is optional.
+func (ia InjectAnnotation) Default() (string, bool) {
+	return ia.Options.Value(InjectTagOptionsDefault)
+}
+
 type TagOptions string
 
 func ParseTag(tag string) (string, TagOptions) {
@@ -312,6 +801,16 @@ func ParseTag(tag string) (string, TagOptions) {
 	return tag, TagOptions("")
 }
 
+// Value returns the value of an option like "default=5s" by its prefix "default=".
+func (o TagOptions) Value(prefix string) (string, bool) {
+	for _, opt := range strings.Split(string(o), ",") {
+		if strings.HasPrefix(opt, prefix) {
+			return opt[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
 func (o TagOptions) Contains(optionName string) bool {
 	if len(o) == 0 {
 		return false
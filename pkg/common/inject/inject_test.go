@@ -2,9 +2,12 @@ package inject
 
 import (
 	"fmt"
-
+	"io"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type SpecialString interface {
@@ -203,3 +206,312 @@ func TestInjectImplementors(t *testing.T) {
 	expect(t, g.Name, injector.Get(InterfaceOf((*fmt.Stringer)(nil)), "g").Interface().(*Greeter).Name)
 	expect(t, g2.Name, injector.Get(InterfaceOf((*fmt.Stringer)(nil)), "g2").Interface().(*Greeter).Name)
 }
+
+func Test_InjectorInvokeArgs(t *testing.T) {
+	injector := New()
+	injector.MapTo("another dep", "D2", (*SpecialString)(nil))
+	injector.Map(&Greeter{"Jeremy"}, "g")
+	injector.Map(&Greeter{"Foo"}, "g2")
+	injector.Map(11, "i")
+
+	// 按类型注入
+	_, err := injector.Invoke(func(i int, s SpecialString) {
+		expect(t, i, 11)
+		expect(t, s, "another dep")
+	})
+	expect(t, err, nil)
+
+	// 同一类型有多个值时需要参数名
+	f := func(g *Greeter, i *InvokeStruct3) {
+		expect(t, g.Name, "Foo")
+		expect(t, i.D2, "another dep")
+	}
+	_, err = injector.Invoke(f)
+	refute(t, err, nil)
+	expect(t, strings.Contains(err.Error(), "*inject.Greeter(g), *inject.Greeter(g2)"), true)
+
+	injector.Map("some dependency", "D1")
+	_, err = InvokeWithNames(injector, f, []string{"g2"})
+	expect(t, err, nil)
+
+	_, err = injector.GetByType(InterfaceOf((*fmt.Stringer)(nil)))
+	_, ok := err.(AmbiguousError)
+	expect(t, ok, true)
+
+	refute(t, ValidateParamNames(f, []string{"a", "b", "c"}), nil)
+
+	// 同一个函数字面量创建的闭包使用各自的参数名
+	greet := func(expected string) func(*Greeter) {
+		return func(g *Greeter) { expect(t, g.Name, expected) }
+	}
+	_, err = InvokeWithNames(injector, greet("Jeremy"), []string{"g"})
+	expect(t, err, nil)
+	_, err = InvokeWithNames(injector, greet("Foo"), []string{"g2"})
+	expect(t, err, nil)
+
+	// 没有inject标签的结构体和以前一样传零值
+	type plain struct{ Name string }
+	injector.Map(plain{"mapped"}, "p")
+	_, err = injector.Invoke(func(p plain) { expect(t, p.Name, "") })
+	expect(t, err, nil)
+}
+
+func Test_InjectorProvide(t *testing.T) {
+	injector := New()
+	injector.Map(1, "step")
+
+	var singletons, transients int
+	expect(t, injector.Provide(func(step int) *Greeter {
+		singletons += step
+		return &Greeter{"singleton"}
+	}, "s", Singleton), nil)
+	expect(t, injector.Provide(func(i *InvokeStruct2) (fmt.Stringer, error) {
+		transients++
+		if i.D1 == "fail" {
+			return nil, fmt.Errorf("failed")
+		}
+		return &Greeter{i.D1}, nil
+	}, "t", Transient), nil)
+
+	// 没有Get之前不会调用
+	expect(t, injector.Lookup(reflect.TypeOf(&Greeter{}), "s"), nil)
+	name, err := injector.NameByType(InterfaceOf((*fmt.Stringer)(nil)))
+	expect(t, err, nil)
+	expect(t, name, "t")
+	expect(t, singletons+transients, 0)
+
+	// transient的参数从调用Get的injector中注入
+	child := New()
+	child.SetParent(injector)
+	child.Map("child", "D1")
+
+	_, err = child.Invoke(func(g *Greeter, s fmt.Stringer, i *InvokeStruct3) {
+		expect(t, g.Name, "singleton")
+		expect(t, s.(*Greeter).Name, "child")
+	})
+	refute(t, err, nil) // InvokeStruct3.D2 is missing
+	child.MapTo("d2", "D2", (*SpecialString)(nil))
+
+	for k := 0; k < 2; k++ {
+		_, err = child.Invoke(func(g *Greeter, s fmt.Stringer) {
+			expect(t, g.Name, "singleton")
+			expect(t, s.(*Greeter).Name, "child")
+		})
+		expect(t, err, nil)
+	}
+	expect(t, singletons, 1)
+	expect(t, transients, 3)
+
+	failed := New()
+	failed.SetParent(injector)
+	failed.Map("fail", "D1")
+	_, err = failed.Invoke(func(s fmt.Stringer) {})
+	expect(t, strings.Contains(err.Error(), "failed"), true)
+
+	refute(t, injector.Provide("not a func", "x", Singleton), nil)
+	refute(t, injector.Provide(func() {}, "x", Singleton), nil)
+	refute(t, injector.Provide(func() (int, int) { return 0, 0 }, "x", Singleton), nil)
+	refute(t, injector.Provide(func(int) int { return 0 }, "x", Singleton), nil)
+}
+
+type cycleA struct{}
+type cycleB struct{}
+
+type cycleRequest struct {
+	B *cycleB `inject:"b"`
+}
+
+func Test_InjectorProvideCycle(t *testing.T) {
+	for _, scope := range []Scope{Singleton, Transient} {
+		injector := New()
+		expect(t, injector.Provide(func(req cycleRequest) *cycleA { return &cycleA{} }, "a", scope), nil)
+		expect(t, injector.Provide(func(*cycleA) *cycleB { return &cycleB{} }, "b", scope), nil)
+
+		// 互相依赖的singleton不会死锁, transient不会无限递归
+		_, err := injector.GetByType(reflect.TypeOf(&cycleA{}))
+		expected := "Provider cycle: *inject.cycleA(a) -> *inject.cycleB(b) -> *inject.cycleA(a)"
+		refute(t, err, nil)
+		expect(t, strings.HasSuffix(err.Error(), expected), true)
+
+		// 两个goroutine分别从环的两端开始也不会互相等待
+		done := make(chan error, 2)
+		for _, typ := range []reflect.Type{reflect.TypeOf(&cycleA{}), reflect.TypeOf(&cycleB{})} {
+			go func(typ reflect.Type) {
+				_, err := injector.GetByType(typ)
+				done <- err
+			}(typ)
+		}
+		for k := 0; k < 2; k++ {
+			refute(t, <-done, nil)
+		}
+
+		err = CanResolve(injector, reflect.TypeOf(&cycleA{}), "a")
+		expect(t, fmt.Sprint(err), expected)
+		_, ok := err.(CycleError)
+		expect(t, ok, true)
+	}
+
+	injector := New()
+	expect(t, injector.Provide(func(*cycleA) *cycleB { return &cycleB{} }, "b", Singleton), nil)
+	err := CanResolve(injector, reflect.TypeOf(&cycleB{}), "b")
+	expect(t, fmt.Sprint(err), "Provider func(*inject.cycleA) *inject.cycleB: Argument 0: Value not found for type: *inject.cycleA")
+
+	injector.Map(&cycleA{}, "a")
+	expect(t, CanResolve(injector, reflect.TypeOf(&cycleB{}), "b"), nil)
+}
+
+func Test_InjectorProvideConcurrent(t *testing.T) {
+	injector := New()
+
+	// 不相关的singleton不会等待其他singleton的构造函数
+	started, release := make(chan struct{}), make(chan struct{})
+	expect(t, injector.Provide(func() *cycleA {
+		close(started)
+		<-release
+		return &cycleA{}
+	}, "a", Singleton), nil)
+	expect(t, injector.Provide(func() *cycleB { return &cycleB{} }, "b", Singleton), nil)
+
+	var calls int
+	expect(t, injector.Provide(func(b *cycleB) *Greeter {
+		calls++
+		return &Greeter{"g"}
+	}, "g", Singleton), nil)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := injector.GetByType(reflect.TypeOf(&cycleA{}))
+		done <- err
+	}()
+	<-started
+
+	// 同一个singleton并发Get时只调用一次构造函数
+	var wg sync.WaitGroup
+	for k := 0; k < 4; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := injector.GetByType(reflect.TypeOf(&Greeter{}))
+			expect(t, err, nil)
+		}()
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the singletons to be created while another constructor is running")
+	}
+	expect(t, calls, 1)
+
+	close(release)
+	expect(t, <-done, nil)
+}
+
+type Farewell struct {
+	Name string
+}
+
+func (f Farewell) String() string {
+	return "Bye, " + f.Name
+}
+
+func Test_InjectorPrimary(t *testing.T) {
+	stringer := InterfaceOf((*fmt.Stringer)(nil))
+
+	injector := New()
+	injector.Map(&Greeter{"Jeremy"}, "g")
+	injector.Map(Farewell{"Foo"}, "g")
+
+	err := injector.Lookup(stringer, "g")
+	ambiguous, ok := err.(AmbiguousError)
+	if !ok {
+		t.Fatalf("Expected AmbiguousError - Got %v", err)
+	}
+	expect(t, strings.Join(ambiguous.Candidates, ","), "*inject.Greeter(g),inject.Farewell(g)")
+	expect(t, injector.Get(stringer, "g").IsValid(), false)
+
+	_, err = injector.NameByType(stringer)
+	if _, ok := err.(AmbiguousError); !ok {
+		t.Fatalf("Expected AmbiguousError - Got %v", err)
+	}
+
+	injector.SetPrimary(reflect.TypeOf(Farewell{}), "g")
+	expect(t, injector.Lookup(stringer, "g"), nil)
+	expect(t, injector.Get(stringer, "g").Interface().(Farewell).Name, "Foo")
+	v, err := injector.GetByType(stringer)
+	expect(t, err, nil)
+	expect(t, v.Interface().(Farewell).Name, "Foo")
+
+	name, err := injector.NameByType(stringer)
+	expect(t, err, nil)
+	expect(t, name, "g")
+
+	// 具体类型优先于primary
+	expect(t, injector.Get(reflect.TypeOf(&Greeter{}), "g").Interface().(*Greeter).Name, "Jeremy")
+}
+
+func Test_InjectorFieldOptions(t *testing.T) {
+	type Options struct {
+		Timeout time.Duration       `inject:"timeout,default=5s"`
+		Workers int                 `inject:"workers,default=4" validate:"min=1,max=8"`
+		Mode    string              `inject:"mode,optional" validate:"oneof=sync async"`
+		All     []fmt.Stringer      `inject:",all"`
+		ByName  map[string]*Greeter `inject:",all"`
+		None    []io.Writer         `inject:",all,optional"`
+	}
+
+	parent := New()
+	parent.Map(&Greeter{"Parent"}, "g")
+	parent.Map(&Greeter{"Bar"}, "bar")
+
+	injector := New()
+	injector.SetParent(parent)
+	injector.Map(&Greeter{"Jeremy"}, "g")
+	injector.Map(Farewell{"Foo"}, "f")
+	injector.Map("async", "mode")
+
+	var o Options
+	expect(t, injector.Apply(&o), nil)
+	expect(t, o.Timeout, 5*time.Second)
+	expect(t, o.Workers, 4)
+	expect(t, o.Mode, "async")
+	expect(t, len(o.None), 0)
+
+	// 子injector中同类型同名的值覆盖父injector中的值
+	var names []string
+	for _, s := range o.All {
+		names = append(names, s.String())
+	}
+	expect(t, strings.Join(names, ";"), "Hello, My name isJeremy;Bye, Foo;Hello, My name isBar")
+	expect(t, len(o.ByName), 2)
+	expect(t, o.ByName["g"].Name, "Jeremy")
+	expect(t, len(injector.LookupAll(InterfaceOf((*fmt.Stringer)(nil)))), 3)
+
+	injector.Map(16, "workers")
+	err := injector.Apply(&o)
+	refute(t, err, nil)
+	expect(t, strings.Contains(err.Error(), "Field Workers"), true)
+
+	injector.Map(2, "workers")
+	injector.Map("batch", "mode")
+	err = injector.Apply(&o)
+	refute(t, err, nil)
+	expect(t, strings.Contains(err.Error(), "not one of: sync async"), true)
+
+	type Required struct {
+		Writers []io.Writer `inject:",all"`
+	}
+	refute(t, injector.Apply(&Required{}), nil)
+
+	// 未提供的optional字段保持零值, 不检查约束
+	type Optional struct {
+		Mode string `inject:"mode,optional" validate:"oneof=sync async"`
+	}
+	var opt Optional
+	expect(t, New().Apply(&opt), nil)
+	expect(t, opt.Mode, "")
+}
@@ -0,0 +1,125 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ValidateParamNames checks that f is a func with at least as many params as names.
+func ValidateParamNames(f interface{}, names []string) error {
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("Cannot use param names with %v, it is not a func", t)
+	}
+	if len(names) > t.NumIn() {
+		return fmt.Errorf("Cannot use %d param names with %v, it has %d params", len(names), t, t.NumIn())
+	}
+	return nil
+}
+
+// ParamName returns the name of the i-th argument, an empty name or a missing trailing name
+// resolves the argument only by its type.
+func ParamName(names []string, i int) string {
+	if i < len(names) {
+		return names[i]
+	}
+	return ""
+}
+
+// InvokeWithNames is Invoke resolving the arguments which are not inject structs by the names at their position.
+// go的反射拿不到函数的参数名, 名字由调用方保存, 同一个函数字面量创建的多个闭包(e.g. 工厂创建的处理器)可以使用不同的名字.
+func InvokeWithNames(inj Injector, f interface{}, names []string) ([]reflect.Value, error) {
+	if i, ok := inj.(*injector); ok {
+		return i.invoke(f, names, nil)
+	}
+	if len(names) > 0 {
+		return nil, fmt.Errorf("Cannot invoke with param names by %T", inj)
+	}
+	return inj.Invoke(f)
+}
+
+// IsInjectStruct reports whether an argument of type t is filled field by field,
+// that is t is a struct, which is passed zeroed if none of its fields is tagged inject,
+// or a pointer to a struct with at least one field tagged inject.
+// Other arguments, e.g. *sql.DB or io.Reader, are injected as a whole.
+func IsInjectStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Struct {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if GetInjectAnnotation(t.Field(i)).Exists {
+			return true
+		}
+	}
+	return false
+}
+
+// CanResolve checks that an argument can be injected like Invoke does, without calling the providers.
+// The arguments of the providers are checked recursively, a cycle between them is reported as CycleError.
+func CanResolve(m TypeMapper, typ reflect.Type, name string) error {
+	if i, ok := m.(*injector); ok {
+		return i.canResolve(typ, name, nil)
+	}
+	if name == "" {
+		_, err := m.NameByType(typ)
+		return err
+	}
+	return m.Lookup(typ, name)
+}
+
+// Resolve returns the value of an argument like Invoke does, by type and name if name is not empty,
+// otherwise by type only. It returns NotFoundError or AmbiguousError if there is no value to inject.
+func Resolve(inj Injector, typ reflect.Type, name string) (reflect.Value, error) {
+	if i, ok := inj.(*injector); ok {
+		return i.resolve(typ, name, i, nil)
+	}
+
+	if name == "" {
+		return inj.GetByType(typ)
+	}
+	if v := inj.Get(typ, name); v.IsValid() {
+		return v, nil
+	}
+	if err := inj.Lookup(typ, name); err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.Value{}, NotFoundError{Type: typ, Name: name}
+}
+
+// Find is Resolve by type and name which returns an invalid value instead of NotFoundError,
+// so a missing optional value does not allocate an error on every call.
+func Find(inj Injector, typ reflect.Type, name string) (reflect.Value, error) {
+	if i, ok := inj.(*injector); ok {
+		return i.getValue(typ, name, i, nil)
+	}
+
+	v, err := Resolve(inj, typ, name)
+	if _, ok := err.(NotFoundError); ok {
+		return reflect.Value{}, nil
+	}
+	return v, err
+}
+
+// Fixed returns the value Resolve would return if it is a value mapped in inj or its parents,
+// so the caller can resolve it once and reuse it. It returns false if the value is made by a provider,
+// e.g. a transient one is made on every call, or if it cannot be resolved.
+func Fixed(inj Injector, typ reflect.Type, name string) (reflect.Value, bool) {
+	i, ok := inj.(*injector)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	e, err := i.entryOf(typ, name)
+	if err != nil || e.provider != nil {
+		return reflect.Value{}, false
+	}
+	return e.value, true
+}
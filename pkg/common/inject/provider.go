@@ -0,0 +1,274 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Scope decides when a provider calls its constructor.
+type Scope int
+
+const (
+	// Singleton calls the constructor on the first Get and reuses the value,
+	// its arguments are injected from the injector the provider is registered in.
+	Singleton Scope = iota
+	// Transient calls the constructor on every Get,
+	// its arguments are injected from the injector Get is called on, e.g. the per-run Context.
+	Transient
+)
+
+func (s Scope) String() string {
+	switch s {
+	case Singleton:
+		return "singleton"
+	case Transient:
+		return "transient"
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+type provider struct {
+	typ         reflect.Type
+	name        string
+	constructor interface{}
+	scope       Scope
+	owner       *injector
+
+	lock      sync.Mutex
+	construct sync.Mutex // singleton调用构造函数期间持有, 其他goroutine等待它的结果
+	done      bool
+	value     reflect.Value
+}
+
+// resolution is the state of getting one value, passed down to the arguments of the providers it calls.
+type resolution struct {
+	stack []*provider // 正在调用的provider
+}
+
+// enter pushes p on the stack, it returns CycleError if p is already being called.
+func (r *resolution) enter(p *provider) error {
+	for k, q := range r.stack {
+		if q == p {
+			return cycleOf(append(r.stack[k:], p))
+		}
+	}
+	r.stack = append(r.stack, p)
+	return nil
+}
+
+func (r *resolution) leave() {
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+func cycleOf(stack []*provider) CycleError {
+	path := make([]string, len(stack))
+	for k, p := range stack {
+		path[k] = p.String()
+	}
+	return CycleError{Path: path}
+}
+
+func (p *provider) String() string {
+	return fmt.Sprintf("%s(%s)", p.typ, p.name)
+}
+
+func (p *provider) built() (reflect.Value, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.value, p.done
+}
+
+// get calls the constructor, a failed singleton is called again by the next get.
+// r is nil if the value is not an argument of another provider.
+func (p *provider) get(origin Injector, r *resolution) (reflect.Value, error) {
+	if p.scope == Singleton {
+		if v, ok := p.built(); ok {
+			return v, nil
+		}
+	}
+
+	if r == nil {
+		r = &resolution{}
+	}
+	if p.scope == Singleton {
+		// 两个goroutine从环的两端开始会互相等待对方的construct, 加锁之前先检查依赖中的环,
+		// 没有环时锁总是按依赖的顺序获取, 不会死锁. 参数缺失等其他错误由call报告
+		if err := p.check(p.owner, r.stack); err != nil {
+			if _, ok := err.(CycleError); ok {
+				return reflect.Value{}, err
+			}
+		}
+	}
+	if err := r.enter(p); err != nil {
+		return reflect.Value{}, err
+	}
+	defer r.leave()
+
+	if p.scope == Transient {
+		return p.call(origin, r)
+	}
+
+	p.construct.Lock()
+	defer p.construct.Unlock()
+
+	// 等待construct期间可能已经被其他goroutine创建
+	if v, ok := p.built(); ok {
+		return v, nil
+	}
+
+	v, err := p.call(p.owner, r)
+	if err != nil {
+		return v, err
+	}
+
+	p.lock.Lock()
+	p.value, p.done = v, true
+	p.lock.Unlock()
+	return v, nil
+}
+
+func (p *provider) call(inj Injector, r *resolution) (reflect.Value, error) {
+	var (
+		vals []reflect.Value
+		err  error
+	)
+	if i, ok := inj.(*injector); ok {
+		vals, err = i.invoke(p.constructor, nil, r)
+	} else {
+		vals, err = inj.Invoke(p.constructor)
+	}
+	if err != nil {
+		if _, ok := err.(CycleError); ok {
+			return reflect.Value{}, err
+		}
+		return reflect.Value{}, fmt.Errorf("Provider %v: %s", reflect.TypeOf(p.constructor), err)
+	}
+
+	if len(vals) == 2 && !vals[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("Provider %v: %s", reflect.TypeOf(p.constructor), vals[1].Interface())
+	}
+	return vals[0], nil
+}
+
+// check checks that the arguments of the constructor can be resolved like get would, without calling it.
+// The arguments of a transient provider are resolved from origin.
+func (p *provider) check(origin *injector, stack []*provider) error {
+	if p.scope == Singleton {
+		if _, ok := p.built(); ok {
+			return nil
+		}
+		origin = p.owner
+	}
+
+	r := &resolution{stack: stack}
+	if err := r.enter(p); err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(p.constructor)
+	for k := 0; k < t.NumIn(); k++ {
+		argType := t.In(k)
+		if !IsInjectStruct(argType) {
+			if err := origin.canResolve(argType, "", r.stack); err != nil {
+				return p.wrap(err, fmt.Sprintf("Argument %d", k))
+			}
+			continue
+		}
+
+		for argType.Kind() == reflect.Ptr {
+			argType = argType.Elem()
+		}
+		for n := 0; n < argType.NumField(); n++ {
+			structField := argType.Field(n)
+			ia := GetInjectAnnotation(structField)
+			if !ia.Exists || structField.PkgPath != "" || ia.Options.Contains(InjectTagOptionsAll) {
+				continue
+			}
+
+			err := origin.canResolve(structField.Type, ia.Name, r.stack)
+			if _, ok := err.(NotFoundError); ok {
+				if _, def := ia.Default(); def || ia.Options.Contains(InjectTagOptionsOptional) {
+					continue
+				}
+			}
+			if err != nil {
+				return p.wrap(err, "Field "+structField.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// wrap keeps CycleError as it is, a missing argument of the provider is not a NotFoundError of its value.
+func (p *provider) wrap(err error, where string) error {
+	if _, ok := err.(CycleError); ok {
+		return err
+	}
+	return fmt.Errorf("Provider %v: %s: %s", reflect.TypeOf(p.constructor), where, err)
+}
+
+// canResolve is CanResolve which also checks the arguments of the providers,
+// stack is the providers whose arguments are being checked.
+func (i *injector) canResolve(t reflect.Type, name string, stack []*provider) error {
+	var err error
+	if name == "" {
+		_, err = i.NameByType(t)
+	} else {
+		err = i.Lookup(t, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 值在其他实现的Injector中时无法继续检查
+	e, err := i.entryOf(t, name)
+	if err != nil || e.provider == nil {
+		return nil
+	}
+	return e.provider.check(i, stack)
+}
+
+// Provide maps the first return type of constructor, which must return a value and optionally an error.
+func (i *injector) Provide(constructor interface{}, name string, scope Scope) error {
+	t := reflect.TypeOf(constructor)
+	if t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("Provider must be a func, got %v", t)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 ||
+		t.Out(0) == errorInterface ||
+		(t.NumOut() == 2 && t.Out(1) != errorInterface) {
+		return fmt.Errorf("Provider %v must return a value and optionally an error", t)
+	}
+	if scope != Singleton && scope != Transient {
+		return fmt.Errorf("Provider %v has unknown scope: %v", t, scope)
+	}
+
+	typ := t.Out(0)
+	for k := 0; k < t.NumIn(); k++ {
+		if t.In(k) == typ {
+			return fmt.Errorf("Provider %v depends on its own type", t)
+		}
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.providers == nil {
+		i.providers = map[reflect.Type]map[string]*provider{}
+	}
+
+	m, ok := i.providers[typ]
+	if !ok {
+		m = map[string]*provider{}
+		i.providers[typ] = m
+	}
+	m[name] = &provider{
+		typ:         typ,
+		name:        name,
+		constructor: constructor,
+		scope:       scope,
+		owner:       i,
+	}
+	return nil
+}
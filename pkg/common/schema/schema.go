@@ -0,0 +1,310 @@
+// Package schema derives the JSON Schema of a config from its sample struct,
+// the raw configs are yaml, so the properties are named like gopkg.in/yaml.v2 does:
+// the name of the yaml tag or the lowercased field name, ",inline" fields are merged into their parent.
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// DurationPattern matches what time.ParseDuration accepts, e.g. 1m30s.
+const DurationPattern = `^[-+]?([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Schema is the subset of JSON Schema draft 7 that a config struct can be described by.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"` // 为空时可以是任意值
+	Format      string `json:"format,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// 结构体为false, 不允许未知的字段; map为元素的Schema
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+
+	Default interface{} `json:"default,omitempty"`
+}
+
+// Of returns the schema of the sample config, its non-zero values are the defaults.
+// It returns nil if the sample is not a struct, e.g. a sample config written as text.
+func Of(sample interface{}) *Schema {
+	v := reflect.ValueOf(sample)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.Zero(v.Type().Elem())
+			continue
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	s := valueOf(v.Type(), v)
+	s.Schema = Draft
+	return s
+}
+
+func valueOf(t reflect.Type, v reflect.Value) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsValid() {
+			if v.IsNil() {
+				v = reflect.Value{}
+			} else {
+				v = v.Elem()
+			}
+		}
+	}
+
+	s := &Schema{}
+	switch {
+	case t == durationType:
+		// yaml.v2把time.Duration写成1m30s
+		s.Type, s.Format, s.Pattern = "string", "duration", DurationPattern
+		if v.IsValid() && v.Int() != 0 {
+			s.Default = time.Duration(v.Int()).String()
+		}
+		return s
+
+	case t.Kind() == reflect.Bool:
+		s.Type = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s.Type = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s.Type = "number"
+	case t.Kind() == reflect.String:
+		s.Type = "string"
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s.Type = "string"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s.Type = "array"
+		s.Items = valueOf(t.Elem(), reflect.Value{})
+	case t.Kind() == reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = valueOf(t.Elem(), reflect.Value{})
+	case t.Kind() == reflect.Struct:
+		s.Type = "object"
+		s.Properties = map[string]*Schema{}
+		s.AdditionalProperties = false
+		addFields(s, t, v)
+		return s
+	}
+
+	if v.IsValid() && !v.IsZero() && s.Type != "" {
+		s.Default = v.Interface()
+	}
+	return s
+}
+
+// addFields adds the fields of struct t to the properties of s, v is the sample value or invalid.
+func addFields(s *Schema, t reflect.Type, v reflect.Value) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		var fv reflect.Value
+		if v.IsValid() {
+			fv = v.Field(i)
+		}
+
+		if inline(opts) {
+			switch f.Type.Kind() {
+			case reflect.Struct:
+				addFields(s, f.Type, fv)
+			case reflect.Map:
+				s.AdditionalProperties = valueOf(f.Type.Elem(), reflect.Value{})
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		switch f.Type.Kind() {
+		case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+			continue
+		}
+		s.Properties[name] = valueOf(f.Type, fv)
+	}
+}
+
+func inline(opts string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == "inline" {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the yaml config against the schema,
+// it reports all the unknown fields and the values of wrong types, e.g.
+//
+//	addrs[0]: expected string, got map
+//	sasl.usr: unknown field
+//
+// A null value is accepted as the zero value like yaml.v2 does.
+func (s *Schema) Validate(rawConfig string) error {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(rawConfig), &v); err != nil {
+		return err
+	}
+
+	var errs []string
+	s.validate("", v, &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid config: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]string) {
+	if v == nil {
+		return
+	}
+
+	fail := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		*errs = append(*errs, msg)
+	}
+
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[interface{}]interface{})
+		if !ok {
+			fail("expected object, got %s", typeName(v))
+			return
+		}
+
+		keys := make([]string, 0, len(m))
+		values := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			key := fmt.Sprint(k)
+			keys = append(keys, key)
+			values[key] = val
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := join(path, key)
+			if p, ok := s.Properties[key]; ok {
+				p.validate(child, values[key], errs)
+				continue
+			}
+			switch ap := s.AdditionalProperties.(type) {
+			case *Schema:
+				ap.validate(child, values[key], errs)
+			case bool:
+				if !ap {
+					*errs = append(*errs, child+": unknown field")
+				}
+			}
+		}
+
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			fail("expected array, got %s", typeName(v))
+			return
+		}
+		for i, item := range list {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if s.Format == "duration" {
+			// yaml.v2也接受整数的纳秒
+			if isInteger(v) {
+				return
+			}
+			if ok {
+				if _, err := time.ParseDuration(str); err != nil {
+					fail("invalid duration %q", str)
+				}
+				return
+			}
+		}
+		if !ok {
+			fail("expected string, got %s", typeName(v))
+		}
+
+	case "integer":
+		if !isInteger(v) {
+			fail("expected integer, got %s", typeName(v))
+		}
+
+	case "number":
+		if _, ok := v.(float64); !ok && !isInteger(v) {
+			fail("expected number, got %s", typeName(v))
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected boolean, got %s", typeName(v))
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func isInteger(v interface{}) bool {
+	switch v.(type) {
+	case int, int64, uint64:
+		return true
+	}
+	return false
+}
+
+// typeName returns the name of a yaml value like the schema types.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[interface{}]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	if isInteger(v) {
+		return "integer"
+	}
+	return fmt.Sprintf("%T", v)
+}
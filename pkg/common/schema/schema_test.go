@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type tlsConfig struct {
+	Enable bool `yaml:"enable"`
+}
+
+type clientConfig struct {
+	ClientID string    `yaml:"client_id"`
+	TLS      tlsConfig `yaml:"tls"`
+}
+
+type sampleConfig struct {
+	Name         string `yaml:"name"`
+	Addrs        []string
+	clientConfig `yaml:",inline"`
+	Timeout      time.Duration     `yaml:"timeout"`
+	Labels       map[string]int    `yaml:"labels"`
+	Ignored      string            `yaml:"-"`
+	Rate         float64           `yaml:"rate,omitempty"`
+	Extra        map[string]string `yaml:",inline"`
+}
+
+func TestSchema(t *testing.T) {
+	s := Of(sampleConfig{Name: "client", Timeout: 3 * time.Second})
+
+	data, err := json.Marshal(s.Properties["timeout"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern, _ := json.Marshal(DurationPattern)
+	expected := `{"type":"string","format":"duration","pattern":` + string(pattern) + `,"default":"3s"}`
+	if string(data) != expected {
+		t.Errorf("Expected %s - Got %s", expected, data)
+	}
+
+	for _, name := range []string{"name", "addrs", "client_id", "tls", "timeout", "labels", "rate"} {
+		if _, ok := s.Properties[name]; !ok {
+			t.Errorf("Expected property %s", name)
+		}
+	}
+	if _, ok := s.Properties["ignored"]; ok {
+		t.Errorf("Unexpected property ignored")
+	}
+
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{"", ""},
+		{"name: a\naddrs: [a, b]\ntimeout: 1m\ntls: {enable: true}\nrate: 1\nother: x", ""},
+		{"timeout: 1000", ""},
+		{"name: [a]\naddrs: a", "Invalid config: addrs: expected array, got string, name: expected string, got array"},
+		{"timeout: 1x\nlabels: {a: b}", "Invalid config: labels.a: expected integer, got string, timeout: invalid duration \"1x\""},
+		{"tls: {enabled: true}", "Invalid config: tls.enabled: unknown field"},
+	}
+	for _, test := range tests {
+		err := s.Validate(test.config)
+		var msg string
+		if err != nil {
+			msg = err.Error()
+		}
+		if msg != test.err {
+			t.Errorf("Config %q: expected error %q - Got %q", test.config, test.err, msg)
+		}
+	}
+
+	if Of("name: a") != nil {
+		t.Errorf("Expected no schema of a text sample config")
+	}
+}
@@ -1,6 +1,12 @@
 package component
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+)
 
 type Component interface {
 	// 获取组件实例对象
@@ -13,6 +19,42 @@ type Component interface {
 	Stop() error
 }
 
+// 需要上报监控指标的组件可以实现该接口, 执行器在组件启动前设置其监控
+type MonitorSetter interface {
+	SetMonitor(m monitor.Monitor)
+}
+
+// 依赖外部服务的组件可以实现该接口, 执行器会定期调用, 返回nil表示组件健康
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// 需要按需创建注入值的组件可以实现该接口, 执行器在依赖检查前调用,
+// 例如开销较大的对象可以注册为provider, 只有processor需要时才创建
+type ProviderRegistrar interface {
+	RegisterProviders(m inject.TypeMapper) error
+}
+
+// 推送式的数据源组件可以实现该接口, 例如: http_source
+// 执行器在组件启动前设置Emitter, 组件产生的每个输入都会驱动一次根stream,
+// 没有配置schedule的执行器只由Source推送的输入驱动
+type Source interface {
+	SetEmitter(emit Emitter)
+
+	// 每个输入会注入的值, 只用于依赖检查, Value可以是零值
+	InputTypes() []Instance
+}
+
+// Emitter 把输入推送给执行器, ctx结束或者执行器没有运行时返回错误
+type Emitter func(ctx context.Context, in Input) error
+
+// Input 推送给执行器的一次输入
+type Input struct {
+	Values   []Instance  // 注入到本次输入的值
+	OnCommit func()      // 所有stream都处理成功后回调
+	OnAbort  func(error) // 所有分支结束并且有stream处理失败后回调
+}
+
 type Instance interface {
 	Name() string
 	// 组件的Go Type
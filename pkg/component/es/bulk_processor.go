@@ -0,0 +1,178 @@
+package es
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	METRICS_KEY_BULK_SUCCESS_COUNT = "_bulk_success_count"
+	METRICS_KEY_BULK_FAILED_COUNT  = "_bulk_failed_count"
+	METRICS_KEY_BULK_ERROR         = "_bulk_error"
+)
+
+var (
+	bulkProcessorFactory       component.Factory       = NewBulkProcessorFactory()
+	_                          component.Component     = &BulkProcessor{}
+	_                          component.MonitorSetter = &BulkProcessor{}
+	_                          component.HealthChecker = &BulkProcessor{}
+	defaultBulkProcessorConfig                         = BulkProcessorConfig{
+		Name:          "es_bulk_processor",
+		ClientConfig:  defaultClientConfig,
+		Workers:       1,
+		BulkActions:   1000,
+		BulkSize:      5 << 20,
+		FlushInterval: time.Second,
+	}
+	bulkProcessorDescription = "es bulk processor factory, requests added to *elastic.BulkProcessor are flushed when the component stopped"
+)
+
+func init() {
+	if err := component.Register("es_bulk_processor", bulkProcessorFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewBulkProcessorFactory() component.Factory {
+	return component.NewFactory(
+		defaultBulkProcessorConfig,
+		bulkProcessorDescription,
+		reflect.TypeOf(&elastic.BulkProcessor{}),
+		func(c string) (component.Component, error) {
+			return NewBulkProcessor(c)
+		})
+}
+
+type BulkProcessorConfig struct {
+	Name          string `yaml:"name"`
+	ClientConfig  `yaml:",inline"`
+	Workers       int           `yaml:"workers"`        // 并发提交的worker数量
+	BulkActions   int           `yaml:"bulk_actions"`   // 达到请求条数后提交, -1表示不限制
+	BulkSize      int           `yaml:"bulk_size"`      // 达到请求字节数后提交, -1表示不限制
+	FlushInterval time.Duration `yaml:"flush_interval"` // 定时提交的时间间隔, 0表示不定时提交
+}
+
+func (c BulkProcessorConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type BulkProcessor struct {
+	config    BulkProcessorConfig
+	client    *elastic.Client
+	processor *elastic.BulkProcessor
+	instance  component.Instance
+	monitor   monitor.Monitor
+	stopOnce  sync.Once
+	stopErr   error
+}
+
+func NewBulkProcessor(rawConfig string) (*BulkProcessor, error) {
+	conf := defaultBulkProcessorConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("ES bulk processor config: %+v", conf)
+
+	if conf.Workers <= 0 {
+		return nil, errors.New("Component:es_bulk_processor workers must be positive")
+	}
+
+	client, err := conf.newClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "es_bulk_processor")
+	}
+
+	p := &BulkProcessor{
+		config:  conf,
+		client:  client,
+		monitor: monitor.NewMonitor(conf.Name),
+	}
+
+	// Do会直接启动worker, 在Add之前不会产生请求
+	p.processor, err = client.BulkProcessor().
+		Name(conf.Name).
+		Workers(conf.Workers).
+		BulkActions(conf.BulkActions).
+		BulkSize(conf.BulkSize).
+		FlushInterval(conf.FlushInterval).
+		Backoff(conf.Retry.backoff()).
+		After(p.after).
+		Do(context.Background())
+	if err != nil {
+		client.Stop()
+		return nil, errors.Wrap(err, "es_bulk_processor")
+	}
+
+	p.instance = component.NewInstance(
+		conf.Name,
+		reflect.TypeOf(p.processor),
+		reflect.ValueOf(p.processor),
+		p.processor,
+	)
+	return p, nil
+}
+
+func (p *BulkProcessor) after(executionId int64, requests []elastic.BulkableRequest, resp *elastic.BulkResponse, err error) {
+	if err != nil {
+		p.monitor.Add(METRICS_KEY_BULK_FAILED_COUNT, int64(len(requests)))
+		p.monitor.Set(METRICS_KEY_BULK_ERROR, monitor.String(err.Error()))
+		log.Error("ES bulk processor: %s, Requests: %d, Error: %s", p.config.Name, len(requests), err)
+		return
+	}
+
+	if resp == nil {
+		return
+	}
+
+	failed := resp.Failed()
+	p.monitor.Add(METRICS_KEY_BULK_SUCCESS_COUNT, int64(len(resp.Succeeded())))
+	if len(failed) > 0 {
+		p.monitor.Add(METRICS_KEY_BULK_FAILED_COUNT, int64(len(failed)))
+		if failed[0].Error != nil {
+			p.monitor.Set(METRICS_KEY_BULK_ERROR, monitor.String(failed[0].Error.Reason))
+			log.Error("ES bulk processor: %s, Failed: %d, Index: %s, Error: %s",
+				p.config.Name, len(failed), failed[0].Index, failed[0].Error.Reason)
+		}
+	}
+}
+
+func (p *BulkProcessor) Instance() component.Instance {
+	return p.instance
+}
+
+func (p *BulkProcessor) SetMonitor(m monitor.Monitor) {
+	p.monitor = m
+}
+
+func (p *BulkProcessor) HealthCheck(ctx context.Context) error {
+	return ping(ctx, p.client, p.config.urls())
+}
+
+func (p *BulkProcessor) Start() error {
+	return nil
+}
+
+// Stop commits the outstanding requests before the processor and the client are stopped.
+func (p *BulkProcessor) Stop() error {
+	p.stopOnce.Do(func() {
+		if err := p.processor.Flush(); err != nil {
+			p.stopErr = err
+		}
+		if err := p.processor.Close(); err != nil && p.stopErr == nil {
+			p.stopErr = err
+		}
+		p.client.Stop()
+	})
+	return p.stopErr
+}
@@ -1,9 +1,10 @@
 package es
 
 import (
+	"context"
 	"reflect"
-	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/component"
 	"gopkg.in/yaml.v2"
@@ -12,11 +13,12 @@ import (
 )
 
 var (
-	factory       component.Factory   = NewFactory()
-	_             component.Component = &Client{}
-	defaultConfig                     = Config{
-		Name: "es_client",
-		Addr: "127.0.0.1:9200",
+	factory       component.Factory       = NewFactory()
+	_             component.Component     = &Client{}
+	_             component.HealthChecker = &Client{}
+	defaultConfig                         = Config{
+		Name:         "es_client",
+		ClientConfig: defaultClientConfig,
 	}
 	description = "es client factory"
 )
@@ -38,8 +40,8 @@ func NewFactory() component.Factory {
 }
 
 type Config struct {
-	Name string `yaml:"name"`
-	Addr string `yaml:"addr"`
+	Name         string `yaml:"name"`
+	ClientConfig `yaml:",inline"`
 }
 
 func (c Config) Marshal() ([]byte, error) {
@@ -47,6 +49,7 @@ func (c Config) Marshal() ([]byte, error) {
 }
 
 type Client struct {
+	config   Config
 	c        *elastic.Client
 	instance component.Instance
 }
@@ -60,23 +63,14 @@ func NewClient(rawConfig string) (*Client, error) {
 
 	log.Info("ES config: %+v", conf)
 
-	var options []elastic.ClientOptionFunc
-	if conf.Addr != "" {
-		url := conf.Addr
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-			url = "http://" + url
-		}
-
-		options = append(options, elastic.SetURL(url))
-	}
-
-	c, err := elastic.NewClient(options...)
+	c, err := conf.newClient()
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "es_client")
 	}
 
 	return &Client{
-		c: c,
+		config: conf,
+		c:      c,
 		instance: component.NewInstance(
 			conf.Name,
 			reflect.TypeOf(c),
@@ -94,6 +88,11 @@ func (c *Client) Start() error {
 	return nil
 }
 
+func (c *Client) HealthCheck(ctx context.Context) error {
+	return ping(ctx, c.c, c.config.urls())
+}
+
 func (c *Client) Stop() error {
+	c.c.Stop()
 	return nil
 }
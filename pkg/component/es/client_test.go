@@ -0,0 +1,154 @@
+package es
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+)
+
+type mockES struct {
+	sync.Mutex
+	server         *httptest.Server
+	requireAuth    bool
+	authorizations []string
+	bulkDocs       []string
+}
+
+func newMockES() *mockES {
+	m := &mockES{}
+	m.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		m.authorizations = append(m.authorizations, r.Header.Get("Authorization"))
+		if m.requireAuth && r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/_bulk" {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+
+		// 每两行是一个请求, 第一行是action, 第二行是文档
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 == 1 {
+				m.bulkDocs = append(m.bulkDocs, scanner.Text())
+				items = append(items, `{"index":{"_index":"test","status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+	}))
+	return m
+}
+
+// lastAuthorization sends a request by the client and returns its Authorization header.
+func (m *mockES) lastAuthorization(t *testing.T, c *Client) string {
+	if _, err := c.c.ClusterHealth().Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	return m.authorizations[len(m.authorizations)-1]
+}
+
+func TestClientAuth(t *testing.T) {
+	m := newMockES()
+	m.requireAuth = true
+	defer m.server.Close()
+
+	c, err := NewClient(fmt.Sprintf("addrs: [%s]\nusername: elastic\npassword: changeme", m.server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	auth := m.lastAuthorization(t, c)
+	if !strings.HasPrefix(auth, "Basic ") {
+		t.Fatalf("Expected basic auth, got: %s", auth)
+	}
+
+	c2, err := NewClient(fmt.Sprintf("addrs: [%s]\napi_key: id:key", m.server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Stop()
+
+	auth = m.lastAuthorization(t, c2)
+	if auth != "ApiKey aWQ6a2V5" {
+		t.Fatalf("Expected api key auth, got: %s", auth)
+	}
+
+	_, err = NewClient(fmt.Sprintf("addrs: [%s]\nusername: elastic\napi_key: id:key", m.server.URL))
+	if err == nil {
+		t.Fatal("Expected an error when username and api_key are set together")
+	}
+}
+
+func TestBulkProcessorFlushOnStop(t *testing.T) {
+	m := newMockES()
+	defer m.server.Close()
+
+	p, err := NewBulkProcessor(fmt.Sprintf(`
+addrs: [%s]
+bulk_actions: 1000
+flush_interval: 0s
+`, m.server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	bp := p.Instance().Value().Interface().(*elastic.BulkProcessor)
+	for i := 0; i < 3; i++ {
+		bp.Add(elastic.NewBulkIndexRequest().Index("test").Doc(map[string]int{"i": i}))
+	}
+
+	m.Lock()
+	n := len(m.bulkDocs)
+	m.Unlock()
+	if n != 0 {
+		t.Fatalf("Expected no bulk requests before stop, got: %d", n)
+	}
+
+	if err = p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if len(m.bulkDocs) != 3 {
+		t.Fatalf("Expected 3 documents flushed on stop, got: %v", m.bulkDocs)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	b := RetryConfig{MaxRetries: 2, InitialInterval: 1, MaxInterval: 10}.backoff()
+	for retry := 1; retry <= 2; retry++ {
+		if _, ok := b.Next(retry); !ok {
+			t.Fatalf("Expected retry %d to be allowed", retry)
+		}
+	}
+	if _, ok := b.Next(3); ok {
+		t.Fatal("Expected retry 3 to be stopped")
+	}
+
+	if _, ok := (RetryConfig{}).backoff().Next(1); ok {
+		t.Fatal("Expected no retries when max_retries is 0")
+	}
+}
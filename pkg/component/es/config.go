@@ -0,0 +1,213 @@
+package es
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/shima-park/lotus/pkg/common/log"
+
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
+)
+
+// ClientConfig is shared by the es_client and es_bulk_processor components.
+type ClientConfig struct {
+	Addrs    []string       `yaml:"addrs"`
+	Addr     string         `yaml:"addr,omitempty"` // 兼容旧配置, 设置后会替换addrs
+	Username string         `yaml:"username,omitempty"`
+	Password secret         `yaml:"password,omitempty"`
+	APIKey   secret         `yaml:"api_key,omitempty"` // id:api_key 或者base64编码后的值
+	TLS      utiltls.Config `yaml:"tls"`
+
+	// 在负载均衡后面时需要关闭sniff, 否则会使用节点的内网地址
+	Sniff               bool          `yaml:"sniff"`
+	SnifferInterval     time.Duration `yaml:"sniffer_interval"`
+	Healthcheck         bool          `yaml:"healthcheck"`
+	HealthcheckInterval time.Duration `yaml:"healthcheck_interval"`
+	HealthcheckTimeout  time.Duration `yaml:"healthcheck_timeout"`
+
+	Retry RetryConfig `yaml:"retry"`
+}
+
+type RetryConfig struct {
+	MaxRetries      int           `yaml:"max_retries"`      // 0表示不重试
+	InitialInterval time.Duration `yaml:"initial_interval"` // 第一次重试的等待时间, 之后指数增长
+	MaxInterval     time.Duration `yaml:"max_interval"`     // 重试等待时间的上限
+}
+
+// secret hides the credentials when the config is logged.
+type secret string
+
+func (s secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "******"
+}
+
+var defaultClientConfig = ClientConfig{
+	Addrs:               []string{"127.0.0.1:9200"},
+	Sniff:               false,
+	SnifferInterval:     elastic.DefaultSnifferInterval,
+	Healthcheck:         true,
+	HealthcheckInterval: elastic.DefaultHealthcheckInterval,
+	HealthcheckTimeout:  elastic.DefaultHealthcheckTimeout,
+	Retry: RetryConfig{
+		MaxRetries:      3,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+	},
+}
+
+func (c ClientConfig) urls() []string {
+	addrs := c.Addrs
+	if c.Addr != "" {
+		addrs = []string{c.Addr}
+	}
+
+	scheme := "http://"
+	if c.TLS.Enable {
+		scheme = "https://"
+	}
+
+	var urls []string
+	for _, addr := range addrs {
+		if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+			addr = scheme + addr
+		}
+		urls = append(urls, addr)
+	}
+	return urls
+}
+
+func (c ClientConfig) options() ([]elastic.ClientOptionFunc, error) {
+	urls := c.urls()
+	if len(urls) == 0 {
+		return nil, errors.New("The addrs of es can not be empty")
+	}
+
+	options := []elastic.ClientOptionFunc{
+		elastic.SetURL(urls...),
+		elastic.SetSniff(c.Sniff),
+		elastic.SetHealthcheck(c.Healthcheck),
+		elastic.SetErrorLog(errorLogger{}),
+		elastic.SetRetrier(elastic.NewBackoffRetrier(c.Retry.backoff())),
+	}
+	if c.SnifferInterval > 0 {
+		options = append(options, elastic.SetSnifferInterval(c.SnifferInterval))
+	}
+	if c.HealthcheckInterval > 0 {
+		options = append(options, elastic.SetHealthcheckInterval(c.HealthcheckInterval))
+	}
+	if c.HealthcheckTimeout > 0 {
+		options = append(options,
+			elastic.SetHealthcheckTimeout(c.HealthcheckTimeout),
+			elastic.SetHealthcheckTimeoutStartup(c.HealthcheckTimeout),
+		)
+	}
+
+	if c.Username != "" && c.APIKey != "" {
+		return nil, errors.New("The username/password and api_key of es can not be set together")
+	}
+	if c.Username != "" {
+		options = append(options, elastic.SetBasicAuth(c.Username, string(c.Password)))
+	}
+
+	tlsConf, err := c.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf != nil {
+		// sniff到的节点地址没有scheme
+		options = append(options, elastic.SetScheme("https"))
+	}
+
+	if tlsConf != nil || c.APIKey != "" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConf
+
+		var rt http.RoundTripper = transport
+		if c.APIKey != "" {
+			apiKey := string(c.APIKey)
+			if strings.Contains(apiKey, ":") {
+				apiKey = base64.StdEncoding.EncodeToString([]byte(apiKey))
+			}
+			// healthcheck和sniff的请求不会带上SetHeaders设置的header, 所以在transport中设置
+			rt = &apiKeyTransport{apiKey: apiKey, next: transport}
+		}
+		options = append(options, elastic.SetHttpClient(&http.Client{Transport: rt}))
+	}
+
+	return options, nil
+}
+
+func (c ClientConfig) newClient() (*elastic.Client, error) {
+	options, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+	return elastic.NewClient(options...)
+}
+
+// ping succeeds when any of the nodes responds.
+func ping(ctx context.Context, client *elastic.Client, urls []string) error {
+	var err error
+	for _, url := range urls {
+		if _, _, err = client.Ping(url).Do(ctx); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// backoff retries at most max_retries times with exponential intervals.
+func (c RetryConfig) backoff() elastic.Backoff {
+	if c.MaxRetries <= 0 {
+		return elastic.StopBackoff{}
+	}
+	return &retryBackoff{
+		maxRetries:  c.MaxRetries,
+		maxInterval: c.MaxInterval,
+		backoff:     elastic.NewExponentialBackoff(c.InitialInterval, c.MaxInterval),
+	}
+}
+
+type retryBackoff struct {
+	maxRetries  int
+	maxInterval time.Duration
+	backoff     *elastic.ExponentialBackoff
+}
+
+func (b *retryBackoff) Next(retry int) (time.Duration, bool) {
+	if retry > b.maxRetries {
+		return 0, false
+	}
+
+	wait, ok := b.backoff.Next(retry)
+	if !ok {
+		// 超过max_interval后按照max_interval继续重试
+		return b.maxInterval, true
+	}
+	return wait, true
+}
+
+type apiKeyTransport struct {
+	apiKey string
+	next   http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "ApiKey "+t.apiKey)
+	return t.next.RoundTrip(req)
+}
+
+type errorLogger struct{}
+
+func (errorLogger) Printf(format string, v ...interface{}) {
+	log.Error(format, v...)
+}
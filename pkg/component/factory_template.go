@@ -3,6 +3,8 @@ package component
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/schema"
 )
 
 type FactoryTemplate struct {
@@ -10,10 +12,14 @@ type FactoryTemplate struct {
 	description  string
 	factoryFunc  FactoryFunc
 	exampleType  reflect.Type
+	schema       *schema.Schema
 }
 
 func NewFactory(sampleConfig interface{}, description string, _type reflect.Type, factoryFunc FactoryFunc) Factory {
-	var conf string
+	var (
+		conf string
+		s    *schema.Schema
+	)
 	if sampleConfig != nil {
 		t := reflect.TypeOf(sampleConfig)
 		if t.Kind() == reflect.String {
@@ -26,6 +32,12 @@ func NewFactory(sampleConfig interface{}, description string, _type reflect.Type
 				data, _ := m.Marshal()
 				conf = string(data)
 			}
+			// 文本的示例配置没有Schema
+			if s = schema.Of(sampleConfig); s != nil {
+				s.Description = description
+				// 所有组件的配置都可以写primary, 见executor.Component.Primary
+				s.Properties["primary"] = &schema.Schema{Type: "boolean"}
+			}
 		}
 	}
 	return FactoryTemplate{
@@ -33,6 +45,7 @@ func NewFactory(sampleConfig interface{}, description string, _type reflect.Type
 		description:  description,
 		factoryFunc:  factoryFunc,
 		exampleType:  _type,
+		schema:       s,
 	}
 }
 
@@ -57,3 +70,7 @@ func (f FactoryTemplate) New(config string) (Component, error) {
 func (f FactoryTemplate) ExampleType() reflect.Type {
 	return f.exampleType
 }
+
+func (f FactoryTemplate) Schema() *schema.Schema {
+	return f.schema
+}
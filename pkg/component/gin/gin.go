@@ -2,25 +2,27 @@ package gin
 
 import (
 	"context"
-	"errors"
-	"net/http"
 	"reflect"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/component"
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
 	"gopkg.in/yaml.v2"
 )
 
 var (
-	factory                    component.Factory   = NewFactory()
-	_                          component.Component = &Gin{}
-	defaultGracefulStopTimeout                     = time.Second * 30
-	defaultConfig                                  = Config{
+	factory                    component.Factory       = NewFactory()
+	_                          component.Component     = &Gin{}
+	_                          component.HealthChecker = &Gin{}
+	defaultGracefulStopTimeout                         = time.Second * 30
+	defaultConfig                                      = Config{
 		Name:                "gin_server",
 		Addr:                ":8080",
 		GracefulStopTimeout: defaultGracefulStopTimeout,
+		Middleware:          defaultMiddlewareConfig,
 	}
 	description = "http listen factory"
 )
@@ -42,9 +44,11 @@ func NewFactory() component.Factory {
 }
 
 type Config struct {
-	Name                string        `yaml:"name"`
-	Addr                string        `yaml:"addr"`
-	GracefulStopTimeout time.Duration `yaml:"graceful_stop_timeout"`
+	Name                string           `yaml:"name"`
+	Addr                string           `yaml:"addr"`
+	GracefulStopTimeout time.Duration    `yaml:"graceful_stop_timeout"` // 停止时等待处理中的请求的时间
+	TLS                 utiltls.Config   `yaml:"tls"`                   // 启用时cert_file和key_file必填, ca_file用于校验客户端证书
+	Middleware          MiddlewareConfig `yaml:"middleware"`
 }
 
 func (c Config) Marshal() ([]byte, error) {
@@ -53,7 +57,7 @@ func (c Config) Marshal() ([]byte, error) {
 
 type Gin struct {
 	conf     Config
-	srv      *http.Server
+	server   *server
 	instance component.Instance
 }
 
@@ -71,7 +75,7 @@ func NewGin(rawConfig string) (*Gin, error) {
 	}
 
 	if conf.Addr == "" {
-		return nil, errors.New("Component:gin_server name cannot be empty")
+		return nil, errors.New("Component:gin_server addr cannot be empty")
 	}
 
 	if conf.GracefulStopTimeout < time.Second {
@@ -79,14 +83,16 @@ func NewGin(rawConfig string) (*Gin, error) {
 	}
 
 	g := gin.New()
+	conf.Middleware.apply(g)
 
-	srv := &http.Server{
-		Addr:    conf.Addr,
-		Handler: g,
+	srv, err := newServer(conf.Addr, g, conf.TLS, conf.GracefulStopTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "gin_server")
 	}
 
 	return &Gin{
-		srv: srv,
+		conf:   conf,
+		server: srv,
 		instance: component.NewInstance(
 			conf.Name,
 			reflect.TypeOf(g),
@@ -101,27 +107,14 @@ func (g *Gin) Instance() component.Instance {
 }
 
 func (g *Gin) Start() error {
-	var errCh = make(chan error)
-	go func() {
-		// service connections
-		if err := g.srv.ListenAndServe(); err != nil {
-			errCh <- err
-		}
-	}()
-
-	select {
-	case err := <-errCh:
-		return err
-	case <-time.After(time.Second):
-		return nil
-	}
+	return errors.Wrap(g.server.start(), "gin_server")
+}
+
+// HealthCheck fails once the listener stopped serving.
+func (g *Gin) HealthCheck(ctx context.Context) error {
+	return g.server.healthCheck()
 }
 
 func (g *Gin) Stop() error {
-	ctx, cancel := context.WithTimeout(context.Background(), g.conf.GracefulStopTimeout)
-	defer cancel()
-	if err := g.srv.Shutdown(ctx); err != nil {
-		return err
-	}
-	return nil
+	return g.server.stop()
 }
@@ -1,18 +1,38 @@
 package gin
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestGin(t *testing.T) {
 	g, err := NewGin(`
       name: "GinServer"
-      addr: ":8080"
+      addr: "127.0.0.1:0"
+      graceful_stop_timeout: 5s
       routers:
         GET: /send/article`)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if g.conf.GracefulStopTimeout != 5*time.Second {
+		t.Fatalf("Expected the graceful stop timeout to be stored, got: %s", g.conf.GracefulStopTimeout)
+	}
 	err = g.Start()
 	if err != nil {
 		t.Fatal(err)
@@ -22,3 +42,168 @@ func TestGin(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGinBindError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	g, err := NewGin("addr: " + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Start(); err == nil {
+		t.Fatal("Expected the bind error to be returned by Start")
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	addr := freeAddr(t)
+	g, err := NewGin(`
+addr: ` + addr + `
+middleware:
+  recovery: true
+  logger: true
+  pprof: true
+  cors:
+    enable: true
+    allow_origins: [http://example.com]
+    allow_methods: [GET]
+    allow_headers: [Content-Type]
+    max_age: 1m`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := g.Instance().Interface().(*gin.Engine)
+	engine.GET("/panic", func(c *gin.Context) { panic("oops") })
+
+	if err = g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	url := "http://" + addr
+	resp := do(t, http.MethodGet, url+"/panic", nil)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 after recovery, got: %d", resp.StatusCode)
+	}
+
+	resp = do(t, http.MethodGet, url+"/debug/pprof/cmdline", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected pprof to be registered, got: %d", resp.StatusCode)
+	}
+
+	resp = do(t, http.MethodOptions, url+"/panic", map[string]string{
+		"Origin":                        "http://example.com",
+		"Access-Control-Request-Method": "GET",
+	})
+	if resp.StatusCode != http.StatusNoContent ||
+		resp.Header.Get("Access-Control-Allow-Origin") != "http://example.com" ||
+		resp.Header.Get("Access-Control-Allow-Methods") != "GET" ||
+		resp.Header.Get("Access-Control-Max-Age") != "60" {
+		t.Fatalf("Unexpected preflight response: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp = do(t, http.MethodOptions, url+"/panic", map[string]string{
+		"Origin":                        "http://evil.com",
+		"Access-Control-Request-Method": "GET",
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected the preflight from unknown origin to be rejected, got: %d", resp.StatusCode)
+	}
+}
+
+func TestGinTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gin_tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeCert(t, dir)
+	if _, err = NewGin("tls:\n  enable: true"); err == nil {
+		t.Fatal("Expected an error without cert_file and key_file")
+	}
+
+	addr := freeAddr(t)
+	g, err := NewGin("addr: " + addr + "\ntls:\n  enable: true\n  cert_file: " + certFile + "\n  key_file: " + keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := g.Instance().Interface().(*gin.Engine)
+	engine.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	if err = g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Get("https://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 over tls, got: %d", resp.StatusCode)
+	}
+}
+
+func do(t *testing.T, method, url string, header map[string]string) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// writeCert writes a self-signed certificate for 127.0.0.1.
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"lotus"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
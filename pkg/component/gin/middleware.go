@@ -0,0 +1,148 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shima-park/lotus/pkg/common/log"
+)
+
+type MiddlewareConfig struct {
+	Recovery bool       `yaml:"recovery"` // panic时返回500而不是断开连接
+	Logger   bool       `yaml:"logger"`   // 记录每个请求的访问日志
+	Pprof    bool       `yaml:"pprof"`    // 注册/debug/pprof路由
+	CORS     CORSConfig `yaml:"cors"`
+}
+
+type CORSConfig struct {
+	Enable           bool          `yaml:"enable"`
+	AllowOrigins     []string      `yaml:"allow_origins"` // *表示允许所有来源
+	AllowMethods     []string      `yaml:"allow_methods"`
+	AllowHeaders     []string      `yaml:"allow_headers"`
+	ExposeHeaders    []string      `yaml:"expose_headers,omitempty"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"` // 预检请求的缓存时间
+}
+
+var defaultMiddlewareConfig = MiddlewareConfig{
+	Recovery: true,
+	CORS: CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{
+			http.MethodGet, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodHead,
+		},
+		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		MaxAge:       12 * time.Hour,
+	},
+}
+
+func (c MiddlewareConfig) apply(g *gin.Engine) {
+	if c.Recovery {
+		g.Use(gin.Recovery())
+	}
+
+	if c.Logger {
+		g.Use(logger())
+	}
+
+	if c.CORS.Enable {
+		g.Use(cors(c.CORS))
+	}
+
+	if c.Pprof {
+		registerPprof(g)
+	}
+}
+
+func logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + c.Request.URL.RawQuery
+		}
+
+		c.Next()
+
+		log.Info("gin: %s %s %d %s %s",
+			c.Request.Method, path, c.Writer.Status(), time.Since(start), c.ClientIP())
+	}
+}
+
+func cors(conf CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	origins := map[string]bool{}
+	for _, o := range conf.AllowOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+		origins[o] = true
+	}
+
+	methods := strings.Join(conf.AllowMethods, ", ")
+	headers := strings.Join(conf.AllowHeaders, ", ")
+	expose := strings.Join(conf.ExposeHeaders, ", ")
+	maxAge := strconv.FormatInt(int64(conf.MaxAge/time.Second), 10)
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !allowAll && !origins[origin] {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		// 允许携带cookie时不能返回*
+		if allowAll && !conf.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+		}
+		if conf.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if expose != "" {
+			h.Set("Access-Control-Expose-Headers", expose)
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			if conf.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func registerPprof(g *gin.Engine) {
+	r := g.Group("/debug/pprof")
+	r.GET("/", gin.WrapF(pprof.Index))
+	r.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	r.GET("/profile", gin.WrapF(pprof.Profile))
+	r.POST("/symbol", gin.WrapF(pprof.Symbol))
+	r.GET("/symbol", gin.WrapF(pprof.Symbol))
+	r.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		r.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+}
@@ -0,0 +1,89 @@
+package gin
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
+)
+
+// server listens before serving, so that bind errors are returned by start synchronously.
+type server struct {
+	srv                 *http.Server
+	tlsConf             *tls.Config
+	gracefulStopTimeout time.Duration
+
+	lock     sync.Mutex
+	serveErr error
+	done     chan struct{}
+}
+
+func newServer(addr string, handler http.Handler, tlsConf utiltls.Config, gracefulStopTimeout time.Duration) (*server, error) {
+	conf, err := tlsConf.ServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &server{
+		srv: &http.Server{
+			Addr:      addr,
+			Handler:   handler,
+			TLSConfig: conf,
+		},
+		tlsConf:             conf,
+		gracefulStopTimeout: gracefulStopTimeout,
+	}, nil
+}
+
+func (s *server) start() error {
+	l, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	if s.tlsConf != nil {
+		l = tls.NewListener(l, s.tlsConf)
+	}
+
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		// Shutdown后Serve返回ErrServerClosed, 不算作错误
+		if err := s.srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			s.lock.Lock()
+			s.serveErr = err
+			s.lock.Unlock()
+		}
+	}()
+	return nil
+}
+
+// healthCheck fails once the listener stopped serving.
+func (s *server) healthCheck() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.serveErr
+}
+
+// stop waits for the active connections until the graceful stop timeout,
+// then closes the remaining connections.
+func (s *server) stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.gracefulStopTimeout)
+	defer cancel()
+
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		// 超时后强制关闭剩余的连接
+		_ = s.srv.Close()
+	}
+
+	if s.done != nil {
+		<-s.done
+	}
+	return err
+}
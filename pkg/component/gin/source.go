@@ -0,0 +1,264 @@
+package gin
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/component"
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	sourceFactory       component.Factory   = NewSourceFactory()
+	_                   component.Component = &Source{}
+	_                   component.Source    = &Source{}
+	defaultSourceConfig                     = SourceConfig{
+		Name:                "http_source",
+		Addr:                ":8081",
+		Paths:               []string{"/webhook"},
+		Methods:             []string{http.MethodPost},
+		Wait:                true,
+		Timeout:             30 * time.Second,
+		MaxBodySize:         10 << 20,
+		GracefulStopTimeout: defaultGracefulStopTimeout,
+		Middleware:          defaultMiddlewareConfig,
+	}
+	sourceDescription = "http source, every request becomes an input of the root stream"
+)
+
+func init() {
+	if err := component.Register("http_source", sourceFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewSourceFactory() component.Factory {
+	return component.NewFactory(
+		defaultSourceConfig,
+		sourceDescription,
+		reflect.TypeOf(&Source{}),
+		func(c string) (component.Component, error) {
+			return NewSource(c)
+		})
+}
+
+type SourceConfig struct {
+	Name                string           `yaml:"name"`                  // 请求和响应注入时使用的名字
+	Addr                string           `yaml:"addr"`                  // 监听地址
+	Paths               []string         `yaml:"paths"`                 // 接收请求的路径
+	Methods             []string         `yaml:"methods"`               // 接收请求的方法
+	Wait                bool             `yaml:"wait"`                  // 是否等待pipeline处理完成后再响应, 否则入队后立即返回202
+	Timeout             time.Duration    `yaml:"timeout"`               // 入队和等待处理结果的超时时间
+	MaxBodySize         int64            `yaml:"max_body_size"`         // 请求body的最大字节数
+	GracefulStopTimeout time.Duration    `yaml:"graceful_stop_timeout"` // 停止时等待处理中的请求的时间
+	TLS                 utiltls.Config   `yaml:"tls"`
+	Middleware          MiddlewareConfig `yaml:"middleware"`
+}
+
+func (c SourceConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// Request 注入到根stream的请求
+type Request struct {
+	Method     string
+	Path       string
+	RemoteAddr string
+	Header     http.Header
+	Query      url.Values
+	Body       []byte
+}
+
+// Response 注入到根stream的响应, wait为true时stream设置的内容会返回给客户端
+type Response struct {
+	lock       sync.Mutex
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+func newResponse() *Response {
+	return &Response{
+		statusCode: http.StatusOK,
+		header:     http.Header{},
+	}
+}
+
+func (r *Response) SetStatus(code int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.statusCode = code
+}
+
+func (r *Response) SetHeader(key, value string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.header.Set(key, value)
+}
+
+// Write appends data to the response body.
+func (r *Response) Write(data []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.body = append(r.body, data...)
+	return len(data), nil
+}
+
+func (r *Response) writeTo(w http.ResponseWriter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for k, vs := range r.header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(r.statusCode)
+	_, _ = w.Write(r.body)
+}
+
+type Source struct {
+	conf     SourceConfig
+	server   *server
+	instance component.Instance
+
+	lock sync.RWMutex
+	emit component.Emitter
+}
+
+func NewSource(rawConfig string) (*Source, error) {
+	conf := defaultSourceConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Name == "" {
+		return nil, errors.New("Component:http_source name cannot be empty")
+	}
+	if conf.Addr == "" {
+		return nil, errors.New("Component:http_source addr cannot be empty")
+	}
+	if len(conf.Paths) == 0 {
+		return nil, errors.New("Component:http_source paths cannot be empty")
+	}
+	if conf.GracefulStopTimeout < time.Second {
+		conf.GracefulStopTimeout = defaultGracefulStopTimeout
+	}
+
+	s := &Source{conf: conf}
+
+	g := gin.New()
+	conf.Middleware.apply(g)
+	for _, path := range conf.Paths {
+		for _, method := range conf.Methods {
+			g.Handle(method, path, s.handle)
+		}
+	}
+
+	s.server, err = newServer(conf.Addr, g, conf.TLS, conf.GracefulStopTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "http_source")
+	}
+	s.instance = component.NewInstance(conf.Name, reflect.TypeOf(s), reflect.ValueOf(s), s)
+	return s, nil
+}
+
+func (s *Source) Instance() component.Instance {
+	return s.instance
+}
+
+func (s *Source) SetEmitter(emit component.Emitter) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.emit = emit
+}
+
+func (s *Source) InputTypes() []component.Instance {
+	return []component.Instance{
+		component.NewInstance(s.conf.Name, reflect.TypeOf(&Request{}), reflect.ValueOf(&Request{}), nil),
+		component.NewInstance(s.conf.Name, reflect.TypeOf(&Response{}), reflect.ValueOf(newResponse()), nil),
+	}
+}
+
+func (s *Source) handle(c *gin.Context) {
+	s.lock.RLock()
+	emit := s.emit
+	s.lock.RUnlock()
+
+	if emit == nil {
+		c.String(http.StatusServiceUnavailable, "http_source is not attached to a pipeline")
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, s.conf.MaxBodySize))
+	if err != nil {
+		c.String(http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+
+	req := &Request{
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		RemoteAddr: c.Request.RemoteAddr,
+		Header:     c.Request.Header,
+		Query:      c.Request.URL.Query(),
+		Body:       body,
+	}
+	resp := newResponse()
+
+	done := make(chan error, 1)
+	in := component.Input{
+		Values: []component.Instance{
+			component.NewInstance(s.conf.Name, reflect.TypeOf(req), reflect.ValueOf(req), req),
+			component.NewInstance(s.conf.Name, reflect.TypeOf(resp), reflect.ValueOf(resp), resp),
+		},
+		OnCommit: func() { done <- nil },
+		OnAbort:  func(err error) { done <- err },
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), s.conf.Timeout)
+	defer cancel()
+
+	if err = emit(ctx, in); err != nil {
+		c.String(http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	if !s.conf.Wait {
+		c.Status(http.StatusAccepted)
+		return
+	}
+
+	select {
+	case err = <-done:
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp.writeTo(c.Writer)
+	case <-ctx.Done():
+		c.String(http.StatusGatewayTimeout, ctx.Err().Error())
+	}
+}
+
+func (s *Source) Start() error {
+	return errors.Wrap(s.server.start(), "http_source")
+}
+
+// HealthCheck fails once the listener stopped serving.
+func (s *Source) HealthCheck(ctx context.Context) error {
+	return s.server.healthCheck()
+}
+
+func (s *Source) Stop() error {
+	return s.server.stop()
+}
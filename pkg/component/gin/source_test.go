@@ -0,0 +1,110 @@
+package gin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/component"
+)
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func post(t *testing.T, url, body string) (int, string) {
+	resp, err := http.Post(url, "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestSource(t *testing.T) {
+	addr := freeAddr(t)
+	s, err := NewSource("name: hook\naddr: " + addr + "\npaths: [/hook]\ntimeout: 200ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	url := "http://" + addr + "/hook"
+	code, _ := post(t, url, "")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without emitter, got: %d", code)
+	}
+
+	// 模拟pipeline: echo请求的body, body为fail时处理失败, 为slow时不返回结果
+	s.SetEmitter(func(ctx context.Context, in component.Input) error {
+		req := in.Values[0].Interface().(*Request)
+		resp := in.Values[1].Interface().(*Response)
+		if in.Values[0].Name() != "hook" {
+			t.Errorf("Expected inject name hook, got: %s", in.Values[0].Name())
+		}
+
+		go func() {
+			switch string(req.Body) {
+			case "fail":
+				in.OnAbort(errors.New("failed"))
+			case "slow":
+			default:
+				resp.SetStatus(http.StatusCreated)
+				resp.Write(req.Body)
+				in.OnCommit()
+			}
+		}()
+		return nil
+	})
+
+	code, body := post(t, url, "hello")
+	if code != http.StatusCreated || body != "hello" {
+		t.Fatalf("Expected 201 hello, got: %d %s", code, body)
+	}
+
+	code, body = post(t, url, "fail")
+	if code != http.StatusInternalServerError || body != "failed" {
+		t.Fatalf("Expected 500 failed, got: %d %s", code, body)
+	}
+
+	code, _ = post(t, url, "slow")
+	if code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected 504, got: %d", code)
+	}
+
+	s.conf.Wait = false
+	code, _ = post(t, url, "slow")
+	if code != http.StatusAccepted {
+		t.Fatalf("Expected 202 when not waiting, got: %d", code)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected GET to be rejected, got: %d", resp.StatusCode)
+	}
+
+	if err = s.HealthCheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
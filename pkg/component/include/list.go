@@ -5,5 +5,8 @@ import (
 	_ "github.com/shima-park/lotus/pkg/component/gin"
 	_ "github.com/shima-park/lotus/pkg/component/io"
 	_ "github.com/shima-park/lotus/pkg/component/kafka"
+	_ "github.com/shima-park/lotus/pkg/component/pubsub"
 	_ "github.com/shima-park/lotus/pkg/component/redis"
+	_ "github.com/shima-park/lotus/pkg/component/sql"
+	_ "github.com/shima-park/lotus/pkg/component/ticker"
 )
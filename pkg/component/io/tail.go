@@ -0,0 +1,610 @@
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	TailOutputLines  = "lines"  // 注入<-chan Line
+	TailOutputReader = "reader" // 注入io.Reader, 按行写入
+
+	TailStartBeginning = "beginning"
+	TailStartEnd       = "end"
+)
+
+var (
+	tailFactory       component.Factory       = NewTailFactory()
+	_                 component.Component     = &Tail{}
+	_                 component.HealthChecker = &Tail{}
+	defaultTailConfig                         = TailConfig{
+		Name:          "io_tail",
+		Paths:         []string{"/var/log/*.log"},
+		Output:        TailOutputLines,
+		StartPosition: TailStartBeginning,
+		BufferSize:    1024,
+		PollInterval:  250 * time.Millisecond,
+		ScanInterval:  10 * time.Second,
+		SyncInterval:  time.Second,
+		MaxPending:    65536,
+	}
+	tailDescription = "follow files like tail -F, e.g.: /var/log/nginx/*.log"
+)
+
+func init() {
+	if err := component.Register("io_tail", tailFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewTailFactory() component.Factory {
+	return component.NewFactory(
+		defaultTailConfig,
+		tailDescription,
+		reflect.TypeOf((<-chan Line)(nil)),
+		func(c string) (component.Component, error) {
+			return NewTail(c)
+		})
+}
+
+type TailConfig struct {
+	Name          string        `yaml:"name"`
+	Paths         []string      `yaml:"paths"`          // 文件路径, 支持glob, 例如: /var/log/*.log
+	Output        string        `yaml:"output"`         // lines: 注入<-chan Line, reader: 注入io.Reader
+	StartPosition string        `yaml:"start_position"` // 没有记录offset的文件从哪里开始读取, beginning或者end
+	MetaPath      string        `yaml:"meta_path"`      // offset保存在meta_path/io_tail/name.yaml中, 为空时使用服务的元数据目录(--meta)
+	BufferSize    int           `yaml:"buffer_size"`    // lines的channel缓冲区大小
+	PollInterval  time.Duration `yaml:"poll_interval"`  // 读到文件末尾后检查新数据的间隔
+	ScanInterval  time.Duration `yaml:"scan_interval"`  // 重新匹配glob发现新文件的间隔
+	SyncInterval  time.Duration `yaml:"sync_interval"`  // offset落盘的间隔
+	MaxPending    int           `yaml:"max_pending"`    // lines输出时每个文件等待Ack的行数上限, 超过后offset在重启前不再前进
+}
+
+func (c TailConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// Line 文件中的一行, 不包含换行符
+type Line struct {
+	Path   string
+	Text   string
+	Offset int64 // 这一行结束后在文件中的偏移量
+
+	done func(error)
+}
+
+// Ack records the offset of the line after every stream reached by it has succeeded.
+// Offsets are recorded in the order of the file, so every line received from the channel must be acked,
+// more than max_pending unacked lines of a file stall its offset like a failed line.
+// If any stream fails the offset of the file stops at the line, which is read again after a restart,
+// the health check of the component fails until then.
+// Without a checkpoint the offset is recorded immediately.
+func (l Line) Ack(cp checkpoint.Checkpoint) {
+	if l.done == nil {
+		return
+	}
+	if cp == nil {
+		l.done(nil)
+		return
+	}
+
+	cp.OnCommit(func() { l.done(nil) })
+	cp.OnAbort(func(err error) {
+		log.Warn("io_tail: line of %s at offset %d is not acked: %s", l.Path, l.Offset, err)
+		l.done(err)
+	})
+}
+
+type Tail struct {
+	config     TailConfig
+	instance   component.Instance
+	offsetPath string
+
+	lines chan Line
+	pr    *io.PipeReader
+	pw    *io.PipeWriter
+
+	lock     sync.Mutex
+	offsets  map[string]int64 // key: path
+	tailing  map[string]bool
+	trackers map[string]*lineTracker // key: path, 只有lines输出时使用
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	started  bool
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func NewTail(rawConfig string) (*Tail, error) {
+	conf := defaultTailConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conf.Paths) == 0 {
+		return nil, errors.Wrap(errors.New("paths cannot be empty"), "io_tail")
+	}
+	for _, pattern := range conf.Paths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "io_tail: %s", pattern)
+		}
+	}
+	switch conf.StartPosition {
+	case TailStartBeginning, TailStartEnd:
+	default:
+		return nil, errors.Wrap(fmt.Errorf("Unknown start position: %s", conf.StartPosition), "io_tail")
+	}
+	if conf.MaxPending <= 0 {
+		return nil, errors.Wrap(errors.New("max_pending must be greater than 0"), "io_tail")
+	}
+
+	metaPath := conf.MetaPath
+	if metaPath == "" {
+		metaPath = component.MetadataPath()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Tail{
+		config:     conf,
+		offsetPath: filepath.Join(metaPath, "io_tail", conf.Name+".yaml"),
+		offsets:    map[string]int64{},
+		tailing:    map[string]bool{},
+		trackers:   map[string]*lineTracker{},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	switch conf.Output {
+	case TailOutputLines:
+		t.lines = make(chan Line, conf.BufferSize)
+		var lines <-chan Line = t.lines
+		t.instance = component.NewInstance(
+			conf.Name,
+			reflect.TypeOf(lines),
+			reflect.ValueOf(lines),
+			lines,
+		)
+	case TailOutputReader:
+		t.pr, t.pw = io.Pipe()
+		t.instance = component.NewInstance(
+			conf.Name,
+			inject.InterfaceOf((*io.Reader)(nil)),
+			reflect.ValueOf(t.pr),
+			t.pr,
+		)
+	default:
+		cancel()
+		return nil, errors.Wrap(fmt.Errorf("Unknown output: %s", conf.Output), "io_tail")
+	}
+
+	if err = t.loadOffsets(); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "io_tail")
+	}
+
+	return t, nil
+}
+
+func (t *Tail) Instance() component.Instance {
+	return t.instance
+}
+
+func (t *Tail) Start() error {
+	if err := os.MkdirAll(filepath.Dir(t.offsetPath), 0750); err != nil {
+		return errors.Wrap(err, "io_tail")
+	}
+
+	t.started = true
+	t.scan(true)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		scan := time.NewTicker(t.config.ScanInterval)
+		defer scan.Stop()
+		flush := time.NewTicker(t.config.SyncInterval)
+		defer flush.Stop()
+
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-scan.C:
+				t.scan(false)
+			case <-flush.C:
+				if err := t.saveOffsets(); err != nil {
+					log.Error("io_tail: failed to save offsets: %s", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (t *Tail) Stop() error {
+	var err error
+	t.stopOnce.Do(func() {
+		t.cancel()
+		if t.pw != nil {
+			// 解除阻塞在管道写入上的goroutine, 读取方会收到EOF
+			t.pw.Close()
+		}
+		t.wg.Wait()
+		if t.lines != nil {
+			close(t.lines)
+		}
+
+		if t.started {
+			err = t.saveOffsets()
+		}
+	})
+	return err
+}
+
+// scan matches the patterns and tails the new files.
+func (t *Tail) scan(initial bool) {
+	var paths []string
+	for _, pattern := range t.config.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Error("io_tail: failed to match %s: %s", pattern, err)
+			continue
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, path := range paths {
+		if t.tailing[path] {
+			continue
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+
+		offset, ok := t.offsets[path]
+		if !ok && initial && t.config.StartPosition == TailStartEnd {
+			// 启动后新出现的文件总是从头开始读取
+			offset = fi.Size()
+		}
+		if offset > fi.Size() {
+			// 停止期间文件被截断了
+			offset = 0
+		}
+		t.offsets[path] = offset
+		t.tailing[path] = true
+
+		tl := &tailer{tail: t, path: path, offset: offset}
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			tl.run()
+		}()
+	}
+}
+
+func (t *Tail) setOffset(path string, offset int64) {
+	t.lock.Lock()
+	t.offsets[path] = offset
+	t.lock.Unlock()
+}
+
+// resetOffset restarts the file from the beginning after it was rotated or truncated,
+// the acks of the lines read before are ignored.
+func (t *Tail) resetOffset(path string) {
+	t.lock.Lock()
+	t.offsets[path] = 0
+	delete(t.trackers, path)
+	t.lock.Unlock()
+}
+
+// track registers the line as in flight and returns the func to call when it has been processed.
+func (t *Tail) track(path string, offset int64) func(error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lt, ok := t.trackers[path]
+	if !ok {
+		lt = &lineTracker{}
+		t.trackers[path] = lt
+	}
+	if lt.err == nil && len(lt.pendings) >= t.config.MaxPending {
+		// 没有Ack的行太多, 和失败的行一样停在最早的行, 之后的行不再登记
+		lt.failed = lt.pendings[0]
+		lt.err = fmt.Errorf("%d lines are not acked, every line must be acked by Line.Ack", len(lt.pendings))
+	}
+	if lt.err != nil {
+		// 失败的行之后的offset在重启前都不会被记录, 不再登记
+		lt.held++
+		return func(error) {}
+	}
+
+	pl := &pendingLine{offset: offset}
+	lt.pendings = append(lt.pendings, pl)
+
+	return func(err error) {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		if err != nil {
+			lt.fail(pl, err)
+			return
+		}
+		pl.done = true
+
+		var last *pendingLine
+		for len(lt.pendings) > 0 && lt.pendings[0].done {
+			last = lt.pendings[0]
+			lt.pendings = lt.pendings[1:]
+		}
+		// 文件被切割或者截断后, 旧的tracker已经被替换了
+		if last != nil && t.trackers[path] == lt {
+			t.offsets[path] = last.offset
+		}
+	}
+}
+
+// HealthCheck fails while the offset of a file is stalled by a failed line or too many unacked lines, see Line.Ack.
+func (t *Tail) HealthCheck(ctx context.Context) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var list []string
+	for path, lt := range t.trackers {
+		if lt.err == nil {
+			continue
+		}
+		list = append(list, fmt.Sprintf("path: %s, offset: %d, pending: %d, error: %s",
+			path, lt.failed.offset, len(lt.pendings)+lt.held, lt.err))
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Strings(list)
+	return fmt.Errorf("io_tail: offsets stalled by failed or unacked lines, restart to read them again: %s",
+		strings.Join(list, "; "))
+}
+
+func (t *Tail) emit(line Line) bool {
+	if t.lines != nil {
+		select {
+		case t.lines <- line:
+			return true
+		case <-t.ctx.Done():
+			return false
+		}
+	}
+
+	_, err := t.pw.Write([]byte(line.Text + "\n"))
+	return err == nil
+}
+
+func (t *Tail) loadOffsets() error {
+	data, err := ioutil.ReadFile(t.offsetPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, &t.offsets)
+}
+
+func (t *Tail) saveOffsets() error {
+	t.lock.Lock()
+	data, err := yaml.Marshal(t.offsets)
+	t.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再rename, 防止进程退出时写坏offset文件
+	tmp := t.offsetPath + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.offsetPath)
+}
+
+type pendingLine struct {
+	offset int64
+	done   bool
+}
+
+// lineTracker keeps the lines in flight of a file, the offset of a line is only
+// recorded after all lines before it have been processed.
+type lineTracker struct {
+	pendings []*pendingLine
+	failed   *pendingLine
+	err      error
+	held     int // failed之后没有登记的行数
+}
+
+// fail stalls the file at pl, the lines after pl are dropped because their
+// offsets can no longer be recorded, the caller must hold the lock.
+func (lt *lineTracker) fail(pl *pendingLine, err error) {
+	for i, pending := range lt.pendings {
+		if pending == pl {
+			lt.held += len(lt.pendings) - i - 1
+			lt.pendings = lt.pendings[:i+1]
+			lt.failed, lt.err = pl, err
+			return
+		}
+	}
+}
+
+type tailer struct {
+	tail   *Tail
+	path   string
+	offset int64 // 已经发送的完整行的偏移量
+
+	file    *os.File
+	fi      os.FileInfo
+	reader  *bufio.Reader
+	partial []byte // 还没有读到换行符的数据
+	rotated bool   // 文件已经被切割, 读完旧文件后切换到新文件
+}
+
+func (tl *tailer) run() {
+	defer tl.close()
+
+	ctx := tl.tail.ctx
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if tl.file == nil {
+			if err := tl.open(tl.offset); err != nil {
+				if !tl.wait() {
+					return
+				}
+				continue
+			}
+		}
+
+		data, err := tl.reader.ReadBytes('\n')
+		if len(data) > 0 {
+			if data[len(data)-1] != '\n' {
+				tl.partial = append(tl.partial, data...)
+			} else {
+				text := append(tl.partial, data...)
+				tl.partial = nil
+				if !tl.rotated {
+					tl.offset += int64(len(text))
+				}
+
+				line := Line{
+					Path:   tl.path,
+					Text:   string(bytes.TrimRight(text, "\r\n")),
+					Offset: tl.offset,
+				}
+				// lines输出的offset在处理完成后由Line.Ack记录, reader输出在写入管道后记录
+				if !tl.rotated && tl.tail.lines != nil {
+					line.done = tl.tail.track(tl.path, tl.offset)
+				}
+				if !tl.tail.emit(line) {
+					return
+				}
+				if !tl.rotated && tl.tail.lines == nil {
+					tl.tail.setOffset(tl.path, tl.offset)
+				}
+			}
+		}
+
+		if err == nil {
+			continue
+		}
+		if err != io.EOF {
+			log.Error("io_tail: failed to read %s: %s", tl.path, err)
+			tl.close()
+			if !tl.wait() {
+				return
+			}
+			continue
+		}
+
+		if tl.rotated {
+			// 旧文件已经读完, 切换到新文件
+			if len(tl.partial) > 0 {
+				if !tl.tail.emit(Line{Path: tl.path, Text: string(tl.partial)}) {
+					return
+				}
+			}
+			tl.close()
+			tl.rotated = false
+			tl.offset = 0
+			tl.tail.resetOffset(tl.path)
+			continue
+		}
+
+		fi, err := os.Stat(tl.path)
+		switch {
+		case err != nil:
+			// 文件被移走了, 还没有创建新文件
+		case !os.SameFile(tl.fi, fi):
+			// 文件被切割了, 再读一次旧文件防止丢失切割前写入的数据
+			tl.rotated = true
+			continue
+		case fi.Size() < tl.offset+int64(len(tl.partial)):
+			// 文件被截断了
+			log.Info("io_tail: %s was truncated", tl.path)
+			tl.close()
+			tl.offset = 0
+			tl.tail.resetOffset(tl.path)
+			continue
+		}
+
+		if !tl.wait() {
+			return
+		}
+	}
+}
+
+func (tl *tailer) open(offset int64) error {
+	f, err := os.Open(tl.path)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	tl.file = f
+	tl.fi = fi
+	tl.reader = bufio.NewReader(f)
+	tl.partial = nil
+	return nil
+}
+
+func (tl *tailer) close() {
+	if tl.file != nil {
+		tl.file.Close()
+		tl.file = nil
+	}
+}
+
+func (tl *tailer) wait() bool {
+	select {
+	case <-tl.tail.ctx.Done():
+		return false
+	case <-time.After(tl.tail.config.PollInterval):
+		return true
+	}
+}
@@ -0,0 +1,254 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
+	"github.com/shima-park/lotus/pkg/component"
+)
+
+func newTestTail(t *testing.T, dir, conf string) *Tail {
+	tail, err := NewTail("paths: [" + filepath.Join(dir, "*.log") + "]\nmeta_path: " + filepath.Join(dir, "meta") +
+		"\npoll_interval: 5ms\nscan_interval: 10ms\nsync_interval: 10ms\n" + conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tail.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tail.Stop() })
+	return tail
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "io_tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func appendFile(t *testing.T, path, data string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// receiveLine waits for the next line without acking it.
+func receiveLine(t *testing.T, lines <-chan Line, path, text string) Line {
+	t.Helper()
+
+	select {
+	case l := <-lines:
+		if l.Path != path || l.Text != text {
+			t.Fatalf("Expected line %s: %q, got: %s: %q", path, text, l.Path, l.Text)
+		}
+		return l
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timeout waiting for line %s: %q", path, text)
+	}
+	return Line{}
+}
+
+func expectLine(t *testing.T, lines <-chan Line, path, text string) {
+	t.Helper()
+
+	receiveLine(t, lines, path, text).Ack(nil)
+}
+
+func TestTailFollow(t *testing.T) {
+	dir := tempDir(t)
+
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "a\n")
+
+	tail := newTestTail(t, dir, "")
+	lines := tail.Instance().Interface().(<-chan Line)
+	expectLine(t, lines, path, "a")
+
+	// 没有换行符的行等写完后再发送
+	appendFile(t, path, "b")
+	appendFile(t, path, "b\n")
+	expectLine(t, lines, path, "bb")
+
+	// 切割: 旧文件被移走, 新文件从头读取
+	appendFile(t, path, "c\n")
+	expectLine(t, lines, path, "c")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "d\n")
+	expectLine(t, lines, path, "d")
+	appendFile(t, path, "dd\n")
+	expectLine(t, lines, path, "dd")
+
+	// 截断
+	if err := ioutil.WriteFile(path, []byte("e\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectLine(t, lines, path, "e")
+
+	// glob匹配到的新文件
+	other := filepath.Join(dir, "other.log")
+	appendFile(t, other, "f\n")
+	expectLine(t, lines, other, "f")
+
+	if err := tail.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-lines; ok {
+		t.Fatal("Expected the lines channel to be closed after stop")
+	}
+
+	// 重启后从保存的offset继续读取
+	appendFile(t, path, "g\n")
+	tail = newTestTail(t, dir, "")
+
+	lines = tail.Instance().Interface().(<-chan Line)
+	expectLine(t, lines, path, "g")
+}
+
+func savedOffset(tail *Tail, path string) int64 {
+	tail.lock.Lock()
+	defer tail.lock.Unlock()
+	return tail.offsets[path]
+}
+
+func TestTailAck(t *testing.T) {
+	dir := tempDir(t)
+
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "a\nb\nc\nd\n")
+
+	tail := newTestTail(t, dir, "")
+	lines := tail.Instance().Interface().(<-chan Line)
+
+	trackers := make([]*checkpoint.Tracker, 4)
+	for i, text := range []string{"a", "b", "c", "d"} {
+		trackers[i] = checkpoint.New()
+		receiveLine(t, lines, path, text).Ack(trackers[i])
+	}
+
+	// b还没有处理完, c的offset不能被记录
+	trackers[0].Done(nil)
+	trackers[2].Done(nil)
+	if offset := savedOffset(tail, path); offset != 2 {
+		t.Fatalf("Expected offset 2 after a, got: %d", offset)
+	}
+	if err := tail.HealthCheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// b失败后offset停在b之前
+	trackers[1].Done(errors.New("failed"))
+	trackers[3].Done(nil)
+	if offset := savedOffset(tail, path); offset != 2 {
+		t.Fatalf("Expected offset 2 after b failed, got: %d", offset)
+	}
+	if err := tail.HealthCheck(context.Background()); err == nil {
+		t.Fatal("Expected the health check to fail after b failed")
+	}
+
+	if err := tail.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后从失败的行重新读取
+	tail = newTestTail(t, dir, "")
+	lines = tail.Instance().Interface().(<-chan Line)
+	expectLine(t, lines, path, "b")
+}
+
+func TestTailMaxPending(t *testing.T) {
+	dir := tempDir(t)
+
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "a\nb\nc\nd\n")
+
+	tail := newTestTail(t, dir, "max_pending: 2")
+	lines := tail.Instance().Interface().(<-chan Line)
+
+	// 超过max_pending的行不再登记, 不Ack的行不会让pendings无限增长
+	var received []Line
+	for _, text := range []string{"a", "b", "c", "d"} {
+		received = append(received, receiveLine(t, lines, path, text))
+	}
+	if err := tail.HealthCheck(context.Background()); err == nil {
+		t.Fatal("Expected the health check to fail with too many unacked lines")
+	}
+
+	// 已登记的行Ack后offset前进到最后一个登记的行
+	for _, l := range received {
+		l.Ack(nil)
+	}
+	if offset := savedOffset(tail, path); offset != 4 {
+		t.Fatalf("Expected offset 4 after b, got: %d", offset)
+	}
+	if err := tail.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后从没有登记的行重新读取
+	tail = newTestTail(t, dir, "")
+	lines = tail.Instance().Interface().(<-chan Line)
+	expectLine(t, lines, path, "c")
+
+	if _, err := NewTail("max_pending: 0"); err == nil {
+		t.Fatal("Expected an error for non-positive max_pending")
+	}
+}
+
+func TestTailDefaultMetaPath(t *testing.T) {
+	dir := tempDir(t)
+	component.SetMetadataPath(dir)
+	defer component.SetMetadataPath("")
+
+	tail, err := NewTail("name: app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "io_tail", "app.yaml"); tail.offsetPath != expected {
+		t.Fatalf("Expected offset path %s, got: %s", expected, tail.offsetPath)
+	}
+}
+
+func TestTailReader(t *testing.T) {
+	dir := tempDir(t)
+
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "old\n")
+
+	tail := newTestTail(t, dir, "output: reader\nstart_position: end")
+	r := bufio.NewReader(tail.Instance().Interface().(io.Reader))
+
+	appendFile(t, path, "new\n")
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "new\n" {
+		t.Fatalf("Expected to start from the end of file, got: %q", line)
+	}
+
+	if err = tail.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.ReadString('\n'); err != io.EOF {
+		t.Fatalf("Expected EOF after stop, got: %v", err)
+	}
+}
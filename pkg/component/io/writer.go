@@ -1,12 +1,20 @@
 package io
 
 import (
+	"bufio"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/component"
 	"gopkg.in/yaml.v2"
 
@@ -14,12 +22,24 @@ import (
 	"github.com/shima-park/lotus/pkg/common/inject"
 )
 
+const (
+	WriteModeAppend   = "append"   // 文件不存在时创建, 存在时追加写入
+	WriteModeCreate   = "create"   // 文件必须不存在, 存在时报错
+	WriteModeTruncate = "truncate" // 文件不存在时创建, 存在时清空
+
+	rotateTimeFormat = "20060102-150405.000"
+)
+
 var (
 	writerFactory       component.Factory   = NewWriterFactory()
 	_                   component.Component = &Writer{}
-	defaultWriterConfig                     = ReaderConfig{
-		Name: "io_writer",
-		Path: "stdout",
+	defaultWriterConfig                     = WriterConfig{
+		Name:          "io_writer",
+		Path:          "stdout",
+		Mode:          WriteModeAppend,
+		Perm:          "0644",
+		BufferSize:    4096,
+		FlushInterval: time.Second,
 	}
 	writerDescription = "file writer e.g.: stdout, stderr, /dev/null, /var/log/xxx.log"
 )
@@ -41,18 +61,73 @@ func NewWriterFactory() component.Factory {
 }
 
 type WriterConfig struct {
-	Name string
-	Path string
+	Name          string        `yaml:"name"`
+	Path          string        `yaml:"path"`
+	Mode          string        `yaml:"mode"`           // append, create, truncate
+	Perm          string        `yaml:"perm"`           // 创建文件时的权限, 八进制, 例如: 0644
+	BufferSize    int           `yaml:"buffer_size"`    // 写缓冲区大小, 为0时不使用缓冲区
+	FlushInterval time.Duration `yaml:"flush_interval"` // 缓冲区定时刷新的间隔
+	Rotate        RotateConfig  `yaml:"rotate"`         // 只对普通文件生效
+}
+
+type RotateConfig struct {
+	MaxSize    int64         `yaml:"max_size"`    // 单个文件最大字节数, 为0时不按大小切割
+	Interval   time.Duration `yaml:"interval"`    // 按时间切割的间隔, 为0时不按时间切割
+	MaxBackups int           `yaml:"max_backups"` // 保留的历史文件个数, 为0时不限制
+	MaxAge     time.Duration `yaml:"max_age"`     // 历史文件保留的时长, 为0时不限制
+}
+
+func (c RotateConfig) enabled() bool {
+	return c.MaxSize > 0 || c.Interval > 0
 }
 
 func (c WriterConfig) Marshal() ([]byte, error) {
 	return yaml.Marshal(c)
 }
 
+func (c WriterConfig) flag() (int, error) {
+	switch c.Mode {
+	case WriteModeAppend, "":
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND, nil
+	case WriteModeCreate:
+		return os.O_WRONLY | os.O_CREATE | os.O_EXCL, nil
+	case WriteModeTruncate:
+		return os.O_WRONLY | os.O_CREATE | os.O_TRUNC, nil
+	default:
+		return 0, fmt.Errorf("Unknown write mode: %s", c.Mode)
+	}
+}
+
+func (c WriterConfig) perm() (os.FileMode, error) {
+	if c.Perm == "" {
+		return 0644, nil
+	}
+	perm, err := strconv.ParseUint(c.Perm, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid file perm: %s", c.Perm)
+	}
+	return os.FileMode(perm), nil
+}
+
 type Writer struct {
-	wc       io.WriteCloser
-	instance component.Instance
+	config   WriterConfig
 	path     string
+	flag     int
+	perm     os.FileMode
+	instance component.Instance
+
+	lock     sync.Mutex
+	wc       io.WriteCloser
+	file     *os.File // 只有写入普通文件时不为空, 用于切割
+	buf      *bufio.Writer
+	size     int64
+	openTime time.Time
+	now      func() time.Time
+	rename   func(oldpath, newpath string) error
+
+	stopOnce sync.Once
+	done     chan struct{}
+	wg       sync.WaitGroup
 }
 
 type nopCloser struct {
@@ -72,32 +147,217 @@ func NewWriter(rawConfig string) (*Writer, error) {
 		return nil, err
 	}
 
-	var f io.WriteCloser
-	switch strings.TrimSpace(conf.Path) {
+	w := &Writer{
+		config: conf,
+		path:   strings.TrimSpace(conf.Path),
+		now:    time.Now,
+		rename: os.Rename,
+		done:   make(chan struct{}),
+	}
+
+	if w.flag, err = conf.flag(); err != nil {
+		return nil, errors.Wrap(err, "io_writer")
+	}
+	if w.perm, err = conf.perm(); err != nil {
+		return nil, errors.Wrap(err, "io_writer")
+	}
+
+	switch w.path {
 	case "/dev/null":
-		f = NopCloser(ioutil.Discard)
+		w.wc = NopCloser(ioutil.Discard)
 	case "stdout":
-		f = os.Stdout
+		w.wc = NopCloser(os.Stdout)
 	case "stderr":
-		f = os.Stderr
+		w.wc = NopCloser(os.Stderr)
 	default:
-		file, err := os.Open(conf.Path)
-		if err != nil {
+		if err = w.open(w.flag); err != nil {
 			return nil, errors.Wrap(err, "io_writer")
 		}
-		f = file
+	}
+	w.reset()
+
+	w.instance = component.NewInstance(
+		conf.Name,
+		inject.InterfaceOf((*io.Writer)(nil)),
+		reflect.ValueOf(w),
+		w,
+	)
+	return w, nil
+}
+
+func (w *Writer) open(flag int) error {
+	f, err := os.OpenFile(w.path, flag, w.perm)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.wc = f
+	w.size = fi.Size()
+	w.openTime = w.now()
+	return nil
+}
+
+func (w *Writer) reset() {
+	if w.config.BufferSize > 0 {
+		w.buf = bufio.NewWriterSize(w.wc, w.config.BufferSize)
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, errors.Wrap(err, "io_writer")
+		}
+	}
+
+	var n int
+	var err error
+	if w.buf != nil {
+		n, err = w.buf.Write(p)
+	} else {
+		n, err = w.wc.Write(p)
+	}
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush writes the buffered data to the underlying file.
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.flush()
+}
+
+func (w *Writer) flush() error {
+	if w.buf == nil {
+		return nil
+	}
+	return w.buf.Flush()
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.file == nil || !w.config.Rotate.enabled() {
+		return false
+	}
+
+	// 空文件即使写入的数据超过了MaxSize也不切割, 防止产生空的历史文件
+	if w.config.Rotate.MaxSize > 0 && w.size > 0 && w.size+n > w.config.Rotate.MaxSize {
+		return true
+	}
+
+	if w.config.Rotate.Interval > 0 && w.size > 0 && !w.now().Before(w.openTime.Add(w.config.Rotate.Interval)) {
+		return true
+	}
+	return false
+}
+
+func (w *Writer) rotate() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	if err := w.renew(); err != nil {
+		// 切割失败时重新以追加的方式打开原文件, 不能继续使用已经关闭的文件
+		if rerr := w.open(os.O_WRONLY | os.O_CREATE | os.O_APPEND); rerr != nil {
+			return errors.Wrapf(err, "reopen %s: %s", w.path, rerr)
+		}
+		w.reset()
+		return err
+	}
+	w.reset()
+
+	w.cleanup()
+	return nil
+}
+
+// renew closes the file, renames it to a backup and creates a new file at the path.
+func (w *Writer) renew() error {
+	if err := w.file.Close(); err != nil {
+		return err
 	}
 
-	return &Writer{
-		path: conf.Path,
-		wc:   f,
-		instance: component.NewInstance(
-			conf.Name,
-			inject.InterfaceOf((*io.Writer)(nil)),
-			reflect.ValueOf(f),
-			f,
-		),
-	}, nil
+	backup := w.backupName()
+	if err := w.rename(w.path, backup); err != nil {
+		return err
+	}
+
+	// 切割后的新文件总是重新创建
+	return w.open(os.O_WRONLY | os.O_CREATE | os.O_TRUNC)
+}
+
+func (w *Writer) backupName() string {
+	name := w.path + "." + w.now().Format(rotateTimeFormat)
+	backup := name
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backup); os.IsNotExist(err) {
+			return backup
+		}
+		backup = fmt.Sprintf("%s.%d", name, i)
+	}
+}
+
+// backups returns the rotated files, the newest first.
+func (w *Writer) backups() ([]string, error) {
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := w.path + "."
+	var backups []string
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, prefix)
+		if len(suffix) < len(rotateTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(rotateTimeFormat, suffix[:len(rotateTimeFormat)]); err != nil {
+			continue
+		}
+		backups = append(backups, m)
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+func (w *Writer) cleanup() {
+	rc := w.config.Rotate
+	if rc.MaxBackups <= 0 && rc.MaxAge <= 0 {
+		return
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		log.Warn("io_writer: failed to list backups of %s: %s", w.path, err)
+		return
+	}
+
+	for i, b := range backups {
+		remove := rc.MaxBackups > 0 && i >= rc.MaxBackups
+		if !remove && rc.MaxAge > 0 {
+			if fi, err := os.Stat(b); err == nil && w.now().Sub(fi.ModTime()) > rc.MaxAge {
+				remove = true
+			}
+		}
+		if !remove {
+			continue
+		}
+
+		if err := os.Remove(b); err != nil {
+			log.Warn("io_writer: failed to remove backup %s: %s", b, err)
+		}
+	}
 }
 
 func (w *Writer) Instance() component.Instance {
@@ -105,14 +365,63 @@ func (w *Writer) Instance() component.Instance {
 }
 
 func (w *Writer) Start() error {
+	if w.buf == nil && (w.file == nil || w.config.Rotate.Interval <= 0) {
+		return nil
+	}
+
+	interval := w.config.FlushInterval
+	if interval <= 0 {
+		interval = defaultWriterConfig.FlushInterval
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				w.tick()
+			}
+		}
+	}()
 	return nil
 }
 
-func (w *Writer) Stop() error {
-	switch strings.TrimSpace(w.path) {
-	case "/dev/null", "stdout", "stderr":
-		return nil
-	default:
-		return w.wc.Close()
+// tick flushes the buffer and rotates the file when it is expired.
+func (w *Writer) tick() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.flush(); err != nil {
+		log.Error("io_writer: failed to flush %s: %s", w.path, err)
 	}
+
+	if w.shouldRotate(0) {
+		if err := w.rotate(); err != nil {
+			log.Error("io_writer: failed to rotate %s: %s", w.path, err)
+		}
+	}
+}
+
+func (w *Writer) Stop() error {
+	var err error
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+
+		w.lock.Lock()
+		defer w.lock.Unlock()
+
+		err = w.flush()
+		if cerr := w.wc.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
 }
@@ -0,0 +1,213 @@
+package io
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterModes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "io_writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.log")
+	write := func(conf, data string) error {
+		w, err := NewWriter("path: " + path + "\n" + conf)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write([]byte(data)); err != nil {
+			return err
+		}
+		return w.Stop()
+	}
+
+	if err = write("mode: create\nperm: \"0600\"", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err = write("mode: create", "b"); err == nil {
+		t.Fatal("Expected an error when the file exists in create mode")
+	}
+	if err = write("mode: append", "b"); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, path, "ab")
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("Expected perm 0600, got: %v", fi.Mode().Perm())
+	}
+
+	if err = write("mode: truncate", "c"); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, path, "c")
+
+	if _, err = NewWriter("path: " + path + "\nmode: unknown"); err == nil {
+		t.Fatal("Expected an error for unknown mode")
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "io_writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.log")
+	w, err := NewWriter("path: " + path + "\nbuffer_size: 1024\nflush_interval: 10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, path, "")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, _ := ioutil.ReadFile(path)
+		if string(data) == "hello" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the buffer to be flushed periodically")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err = w.Write([]byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, path, "hello world")
+}
+
+func TestWriterRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "io_writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.log")
+	w, err := NewWriter("path: " + path + "\nbuffer_size: 0\nrotate:\n  max_size: 4\n  interval: 1h\n  max_backups: 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	w.now = func() time.Time { return now }
+	w.openTime = now
+
+	for _, s := range []string{"aaa", "bb", "cc", "dddd"} {
+		if _, err = w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// aaa, bbcc, dddd 共切割了2次
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got: %v", backups)
+	}
+	expectFile(t, backups[0], "bbcc")
+	expectFile(t, backups[1], "aaa")
+	expectFile(t, path, "dddd")
+
+	// 按时间切割并且只保留最新的2个历史文件
+	now = now.Add(time.Hour)
+	w.tick()
+
+	backups, err = w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got: %v", backups)
+	}
+	expectFile(t, backups[0], "dddd")
+	expectFile(t, backups[1], "bbcc")
+	expectFile(t, path, "")
+}
+
+func expectFile(t *testing.T, path, expected string) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Fatalf("Expected %s to be %q, got: %q", path, expected, string(data))
+	}
+}
+
+func TestWriterRotateError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "io_writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.log")
+	w, err := NewWriter("path: " + path + "\nbuffer_size: 0\nrotate:\n  max_size: 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	w.rename = func(string, string) error { return errors.New("rename failed") }
+
+	if _, err = w.Write([]byte("aaa")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("bb")); err == nil || !strings.Contains(err.Error(), "rename failed") {
+		t.Fatalf("Expected rename error, got: %v", err)
+	}
+
+	// 切割失败后原文件被重新打开, 仍然可以写入
+	w.config.Rotate.MaxSize = 0
+	if _, err = w.Write([]byte("bb")); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, path, "aaabb")
+
+	// 恢复后正常切割
+	w.rename = os.Rename
+	w.config.Rotate.MaxSize = 4
+	if _, err = w.Write([]byte("cc")); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got: %v", backups)
+	}
+	expectFile(t, backups[0], "aaabb")
+	expectFile(t, path, "cc")
+}
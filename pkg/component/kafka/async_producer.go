@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	METRICS_KEY_PRODUCER_SUCCESS_COUNT = "_producer_success_count"
+	METRICS_KEY_PRODUCER_ERROR_COUNT   = "_producer_error_count"
+	METRICS_KEY_PRODUCER_ERROR         = "_producer_error"
+)
+
+var (
+	asyncProducerFactory       component.Factory       = NewAsyncProducerFactory()
+	_                          component.Component     = &AsyncProducer{}
+	_                          component.MonitorSetter = &AsyncProducer{}
+	_                          component.HealthChecker = &AsyncProducer{}
+	defaultAsyncProducerConfig                         = AsyncProducerConfig{
+		ProducerConfig: func() ProducerConfig {
+			c := defaultProducerConfig
+			c.Name = "kafka_async_producer"
+			return c
+		}(),
+		FlushFrequency: 100 * time.Millisecond,
+		FlushMessages:  100,
+		CloseTimeout:   10 * time.Second,
+	}
+	asyncProducerDescription = "kafka async producer factory, delivery reports are reported to the executor monitor"
+)
+
+func init() {
+	if err := component.Register("kafka_async_producer", asyncProducerFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewAsyncProducerFactory() component.Factory {
+	return component.NewFactory(
+		defaultAsyncProducerConfig,
+		asyncProducerDescription,
+		inject.InterfaceOf((*sarama.AsyncProducer)(nil)),
+		func(c string) (component.Component, error) {
+			return NewAsyncProducer(c)
+		})
+}
+
+type AsyncProducerConfig struct {
+	ProducerConfig `yaml:",inline"`
+	FlushFrequency time.Duration `yaml:"flush_frequency"` // 批量发送的时间间隔
+	FlushMessages  int           `yaml:"flush_messages"`  // 批量发送的消息条数
+	FlushBytes     int           `yaml:"flush_bytes"`     // 批量发送的字节数
+	CloseTimeout   time.Duration `yaml:"close_timeout"`   // 停止时等待缓冲区消息发送完成的最长时间
+}
+
+func (c AsyncProducerConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type AsyncProducer struct {
+	config   AsyncProducerConfig
+	client   sarama.Client
+	producer sarama.AsyncProducer
+	instance component.Instance
+	monitor  monitor.Monitor
+	once     sync.Once
+	stopOnce sync.Once
+	stopErr  error
+	drained  chan struct{}
+}
+
+func NewAsyncProducer(rawConfig string) (*AsyncProducer, error) {
+	conf := defaultAsyncProducerConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Kafka async producer config: %+v", conf)
+
+	if conf.CloseTimeout <= 0 {
+		return nil, errors.New("Component:kafka_async_producer close_timeout must be positive")
+	}
+
+	kafkaConf, err := conf.newSaramaConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "kafka_async_producer")
+	}
+	kafkaConf.Producer.Flush.Frequency = conf.FlushFrequency
+	kafkaConf.Producer.Flush.Messages = conf.FlushMessages
+	kafkaConf.Producer.Flush.Bytes = conf.FlushBytes
+	if err := kafkaConf.Validate(); err != nil {
+		return nil, errors.Wrap(err, "kafka_async_producer")
+	}
+
+	client, err := sarama.NewClient(conf.Addrs, kafkaConf)
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return &AsyncProducer{
+		config:   conf,
+		client:   client,
+		producer: producer,
+		monitor:  monitor.NewMonitor(conf.Name),
+		drained:  make(chan struct{}),
+		instance: component.NewInstance(
+			conf.Name,
+			inject.InterfaceOf((*sarama.AsyncProducer)(nil)),
+			reflect.ValueOf(producer),
+			producer,
+		),
+	}, nil
+}
+
+func (p *AsyncProducer) Instance() component.Instance {
+	return p.instance
+}
+
+func (p *AsyncProducer) SetMonitor(m monitor.Monitor) {
+	p.monitor = m
+}
+
+func (p *AsyncProducer) HealthCheck(ctx context.Context) error {
+	return checkMetadata(ctx, p.client)
+}
+
+func (p *AsyncProducer) Start() error {
+	p.once.Do(func() {
+		go p.drain()
+	})
+	return nil
+}
+
+// drain consumes the delivery reports until the producer is closed,
+// otherwise the producer blocks once the report channels are full.
+func (p *AsyncProducer) drain() {
+	defer close(p.drained)
+
+	successes, errs := p.producer.Successes(), p.producer.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case _, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			p.monitor.Add(METRICS_KEY_PRODUCER_SUCCESS_COUNT, 1)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			p.monitor.Add(METRICS_KEY_PRODUCER_ERROR_COUNT, 1)
+			p.monitor.Set(METRICS_KEY_PRODUCER_ERROR, monitor.String(err.Error()))
+			log.Error("Kafka async producer: %s, Topic: %s, Error: %s",
+				p.config.Name, err.Msg.Topic, err.Err)
+		}
+	}
+}
+
+// Stop flushes the buffered messages and waits at most close_timeout for their delivery reports.
+func (p *AsyncProducer) Stop() error {
+	p.stopOnce.Do(func() {
+		p.stopErr = p.stop()
+		if err := p.client.Close(); p.stopErr == nil {
+			p.stopErr = err
+		}
+	})
+	return p.stopErr
+}
+
+func (p *AsyncProducer) stop() error {
+	// 没有启动时没有goroutine消费投递结果, 只能直接关闭
+	started := true
+	p.once.Do(func() {
+		started = false
+	})
+	if !started {
+		return p.producer.Close()
+	}
+
+	p.producer.AsyncClose()
+
+	select {
+	case <-p.drained:
+		return nil
+	case <-time.After(p.config.CloseTimeout):
+		return fmt.Errorf("Kafka async producer: %s flush timeout after %s",
+			p.config.Name, p.config.CloseTimeout)
+	}
+}
@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+)
+
+const testBadTopic = "my_bad_topic"
+
+func newAsyncProducerBroker(t *testing.T, produce bool) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 0)
+
+	handlers := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(testTopic, 0, broker.BrokerID()).
+			SetLeader(testBadTopic, 0, broker.BrokerID()),
+	}
+	// 没有ProduceRequest的响应时消息一直等待投递结果
+	if produce {
+		handlers["ProduceRequest"] = sarama.NewMockProduceResponse(t).SetVersion(3).
+			SetError(testBadTopic, 0, sarama.ErrInvalidMessage)
+	}
+	broker.SetHandlerByMap(handlers)
+
+	return broker
+}
+
+func newTestAsyncProducer(t *testing.T, broker *sarama.MockBroker, conf string) (*AsyncProducer, monitor.Monitor) {
+	p, err := NewAsyncProducer(fmt.Sprintf("addrs: [%s]\nretry_max: 0\nflush_frequency: 10ms\n%s", broker.Addr(), conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := monitor.NewMonitor("test_async_producer")
+	p.SetMonitor(m)
+	if err = p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return p, m
+}
+
+func metric(m monitor.Monitor, key string) string {
+	if v := m.Get(key); v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+func TestAsyncProducerDrain(t *testing.T) {
+	broker := newAsyncProducerBroker(t, true)
+	defer broker.Close()
+
+	p, m := newTestAsyncProducer(t, broker, "")
+
+	input := p.Instance().Interface().(sarama.AsyncProducer).Input()
+	input <- &sarama.ProducerMessage{Topic: testTopic, Value: sarama.StringEncoder("a")}
+	input <- &sarama.ProducerMessage{Topic: testTopic, Value: sarama.StringEncoder("b")}
+	input <- &sarama.ProducerMessage{Topic: testBadTopic, Value: sarama.StringEncoder("c")}
+
+	// Stop等待缓冲区中的消息投递完成, 投递结果都被消费并记录
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, metric(m, METRICS_KEY_PRODUCER_SUCCESS_COUNT), "2")
+	expect(t, metric(m, METRICS_KEY_PRODUCER_ERROR_COUNT), "1")
+	if err := metric(m, METRICS_KEY_PRODUCER_ERROR); !strings.Contains(err, sarama.ErrInvalidMessage.Error()) {
+		t.Fatalf("Expected the last error to be reported - Got %s", err)
+	}
+
+	// 重复Stop返回第一次的结果
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAsyncProducerCloseTimeout(t *testing.T) {
+	broker := newAsyncProducerBroker(t, false)
+	defer broker.Close()
+
+	p, m := newTestAsyncProducer(t, broker, "close_timeout: 100ms")
+
+	input := p.Instance().Interface().(sarama.AsyncProducer).Input()
+	input <- &sarama.ProducerMessage{Topic: testTopic, Value: sarama.StringEncoder("a")}
+
+	start := time.Now()
+	err := p.Stop()
+	if err == nil || !strings.Contains(err.Error(), "flush timeout after 100ms") {
+		t.Fatalf("Expected a flush timeout - Got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected Stop to return after close_timeout - Got %s", elapsed)
+	}
+	expect(t, metric(m, METRICS_KEY_PRODUCER_SUCCESS_COUNT), "")
+
+	if _, err = NewAsyncProducer("close_timeout: 0s"); err == nil {
+		t.Fatal("Expected an error for non-positive close_timeout")
+	}
+}
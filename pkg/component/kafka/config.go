@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg/scram"
+
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
+)
+
+// ClientConfig is shared by the kafka consumer and producer components.
+type ClientConfig struct {
+	ClientID string         `yaml:"client_id"`
+	Version  string         `yaml:"version"` // kafka协议版本, 例如: 2.1.0
+	SASL     SASLConfig     `yaml:"sasl"`
+	TLS      utiltls.Config `yaml:"tls"`
+}
+
+type SASLConfig struct {
+	Enable    bool   `yaml:"enable"`
+	Mechanism string `yaml:"mechanism"` // PLAIN, SCRAM-SHA-256, SCRAM-SHA-512
+	User      string `yaml:"user"`
+	Password  string `yaml:"password"`
+}
+
+// String hides the password when the config is logged.
+func (c SASLConfig) String() string {
+	return fmt.Sprintf("{Enable:%v Mechanism:%s User:%s Password:******}", c.Enable, c.Mechanism, c.User)
+}
+
+var defaultClientConfig = ClientConfig{
+	ClientID: "lotus",
+	Version:  sarama.V1_0_0_0.String(),
+	SASL: SASLConfig{
+		Mechanism: sarama.SASLTypePlaintext,
+	},
+}
+
+func (c ClientConfig) apply(conf *sarama.Config) error {
+	if c.ClientID != "" {
+		conf.ClientID = c.ClientID
+	}
+
+	if c.Version != "" {
+		version, err := sarama.ParseKafkaVersion(c.Version)
+		if err != nil {
+			return err
+		}
+		conf.Version = version
+	}
+
+	if c.SASL.Enable {
+		conf.Net.SASL.Enable = true
+		conf.Net.SASL.Handshake = true
+		conf.Net.SASL.User = c.SASL.User
+		conf.Net.SASL.Password = c.SASL.Password
+
+		switch mechanism := strings.ToUpper(c.SASL.Mechanism); mechanism {
+		case "", sarama.SASLTypePlaintext:
+			conf.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case sarama.SASLTypeSCRAMSHA256:
+			conf.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			conf.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{HashGeneratorFcn: sha256Generator}
+			}
+		case sarama.SASLTypeSCRAMSHA512:
+			conf.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			conf.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{HashGeneratorFcn: sha512Generator}
+			}
+		default:
+			return fmt.Errorf("Unsupported sasl mechanism: %s, supported mechanisms: %s, %s, %s",
+				c.SASL.Mechanism,
+				sarama.SASLTypePlaintext, sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512,
+			)
+		}
+	}
+
+	tlsConf, err := c.TLS.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConf != nil {
+		conf.Net.TLS.Enable = true
+		conf.Net.TLS.Config = tlsConf
+	}
+
+	return nil
+}
+
+// checkMetadata fetches the metadata of the topics, it fails when no broker is reachable.
+// All topics are fetched when topics is empty.
+func checkMetadata(ctx context.Context, client sarama.Client, topics ...string) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.RefreshMetadata(topics...)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func parseRequiredAcks(s string) (sarama.RequiredAcks, error) {
+	switch strings.ToLower(s) {
+	case "0", "none", "no_response":
+		return sarama.NoResponse, nil
+	case "1", "local", "wait_for_local", "":
+		return sarama.WaitForLocal, nil
+	case "-1", "all", "wait_for_all":
+		return sarama.WaitForAll, nil
+	}
+	return 0, fmt.Errorf("Unsupported required_acks: %s, supported values: none, local, all", s)
+}
+
+func parseCompression(s string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	}
+	return 0, fmt.Errorf("Unsupported compression: %s, supported values: none, gzip, snappy, lz4, zstd", s)
+}
+
+var (
+	sha256Generator scram.HashGeneratorFcn = func() hash.Hash { return sha256.New() }
+	sha512Generator scram.HashGeneratorFcn = func() hash.Hash { return sha512.New() }
+)
+
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (x *scramClient) Begin(userName, password, authzID string) (err error) {
+	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.ClientConversation = x.Client.NewConversation()
+	return nil
+}
+
+func (x *scramClient) Step(challenge string) (response string, err error) {
+	return x.ClientConversation.Step(challenge)
+}
+
+func (x *scramClient) Done() bool {
+	return x.ClientConversation.Done()
+}
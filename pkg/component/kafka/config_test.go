@@ -0,0 +1,198 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProducerConfig(t *testing.T) {
+	tests := []struct {
+		config string
+		check  func(t *testing.T, conf *sarama.Config)
+		err    string
+	}{
+		{
+			config: "",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.ClientID, "lotus")
+				expect(t, conf.Version, sarama.V1_0_0_0)
+				expect(t, conf.Net.SASL.Enable, false)
+				expect(t, conf.Net.TLS.Enable, false)
+				expect(t, conf.Producer.RequiredAcks, sarama.WaitForLocal)
+				expect(t, conf.Producer.Compression, sarama.CompressionNone)
+				expect(t, conf.Producer.Return.Successes, true)
+			},
+		},
+		{
+			config: "client_id: app\nversion: 2.1.0\nrequired_acks: all\ncompression: zstd\nmax_message_bytes: 100\nretry_max: 5",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.ClientID, "app")
+				expect(t, conf.Version, sarama.V2_1_0_0)
+				expect(t, conf.Producer.RequiredAcks, sarama.WaitForAll)
+				expect(t, conf.Producer.Compression, sarama.CompressionZSTD)
+				expect(t, conf.Producer.MaxMessageBytes, 100)
+				expect(t, conf.Producer.Retry.Max, 5)
+			},
+		},
+		{
+			config: "sasl: {enable: true, user: u, password: p}",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.Net.SASL.Enable, true)
+				expect(t, string(conf.Net.SASL.Mechanism), sarama.SASLTypePlaintext)
+				expect(t, conf.Net.SASL.User, "u")
+				expect(t, conf.Net.SASL.Password, "p")
+			},
+		},
+		{
+			config: "sasl: {enable: true, mechanism: scram-sha-256, user: u, password: p}",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, string(conf.Net.SASL.Mechanism), sarama.SASLTypeSCRAMSHA256)
+				client := conf.Net.SASL.SCRAMClientGeneratorFunc().(*scramClient)
+				expect(t, client.Begin("u", "p", ""), nil)
+			},
+		},
+		{
+			config: "sasl: {enable: true, mechanism: SCRAM-SHA-512, user: u, password: p}",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, string(conf.Net.SASL.Mechanism), sarama.SASLTypeSCRAMSHA512)
+				expect(t, conf.Net.SASL.SCRAMClientGeneratorFunc != nil, true)
+			},
+		},
+		{
+			config: "tls: {enable: true, server_name: kafka, insecure_skip_verify: true}",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.Net.TLS.Enable, true)
+				expect(t, conf.Net.TLS.Config.ServerName, "kafka")
+				expect(t, conf.Net.TLS.Config.InsecureSkipVerify, true)
+			},
+		},
+		{
+			config: "idempotent: true\nrequired_acks: all\nversion: 0.11.0.0",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.Producer.Idempotent, true)
+				expect(t, conf.Net.MaxOpenRequests, 1)
+			},
+		},
+		{config: "sasl: {enable: true, mechanism: GSSAPI}", err: "Unsupported sasl mechanism: GSSAPI"},
+		// 只校验enable时的配置
+		{config: "sasl: {enable: false, mechanism: GSSAPI}", check: func(*testing.T, *sarama.Config) {}},
+		{config: "sasl: {enable: true, mechanism: PLAIN}", err: "Net.SASL.User must not be empty"},
+		{config: "tls: {enable: true, ca_file: /not/exists}", err: "Failed to read tls ca_file"},
+		{config: "tls: {enable: true, cert_file: cert.pem}", err: "The tls cert_file and key_file must be set together"},
+		{config: "version: 1.x", err: "invalid version"},
+		{config: "required_acks: some", err: "Unsupported required_acks: some"},
+		{config: "compression: brotli", err: "Unsupported compression: brotli"},
+		{config: "idempotent: true", err: "Idempotent producer requires"},
+		{config: "max_message_bytes: 0", err: "Producer.MaxMessageBytes must be > 0"},
+	}
+
+	for _, test := range tests {
+		conf := defaultProducerConfig
+		if err := yaml.Unmarshal([]byte(test.config), &conf); err != nil {
+			t.Fatal(err)
+		}
+
+		kafkaConf, err := conf.newSaramaConfig()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: Expected error %s - Got %v", test.config, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.config, err)
+			continue
+		}
+		test.check(t, kafkaConf)
+	}
+}
+
+func TestConsumerConfig(t *testing.T) {
+	tests := []struct {
+		config string
+		check  func(t *testing.T, conf *sarama.Config)
+		err    string // kafka_consumer
+		group  string // kafka_consumer_group
+	}{
+		{
+			config: "",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.Consumer.Return.Errors, true)
+				expect(t, conf.Consumer.Offsets.Initial, sarama.OffsetNewest)
+				expect(t, conf.Consumer.Offsets.AutoCommit.Enable, true)
+				expect(t, conf.Consumer.Fetch.Min, int32(1))
+				expect(t, conf.Consumer.Fetch.Default, int32(1024*1024))
+				expect(t, conf.Consumer.Fetch.Max, int32(0))
+				expect(t, conf.Consumer.MaxWaitTime, 250*time.Millisecond)
+			},
+		},
+		{
+			config: "offsets_initial: -2\noffsets_auto_commit: false\nfetch_min_bytes: 10\nfetch_default_bytes: 100\nfetch_max_bytes: 1000\nmax_wait_time: 1s",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, conf.Consumer.Offsets.Initial, sarama.OffsetOldest)
+				expect(t, conf.Consumer.Offsets.AutoCommit.Enable, false)
+				expect(t, conf.Consumer.Fetch.Min, int32(10))
+				expect(t, conf.Consumer.Fetch.Default, int32(100))
+				expect(t, conf.Consumer.Fetch.Max, int32(1000))
+				expect(t, conf.Consumer.MaxWaitTime, time.Second)
+			},
+		},
+		{
+			config: "sasl: {enable: true, mechanism: scram-sha-512, user: u, password: p}\ntls: {enable: true}",
+			check: func(t *testing.T, conf *sarama.Config) {
+				expect(t, string(conf.Net.SASL.Mechanism), sarama.SASLTypeSCRAMSHA512)
+				expect(t, conf.Net.TLS.Enable, true)
+			},
+		},
+		{config: "rebalance_strategy: roundrobin", check: func(*testing.T, *sarama.Config) {}},
+		{config: "rebalance_strategy: sticky", err: "Unsupported rebalance_strategy: sticky"},
+		{config: "rebalance_strategy: random", err: "Unsupported rebalance_strategy: random", group: "Unsupported rebalance_strategy: random"},
+		{config: "sasl: {enable: true, mechanism: OAUTHBEARER}", err: "Unsupported sasl mechanism", group: "Unsupported sasl mechanism"},
+		{config: "version: 0.10.0.0", check: func(*testing.T, *sarama.Config) {}, group: "The kafka version must be at least 0.10.2.0"},
+		{config: "fetch_default_bytes: 0", err: "Consumer.Fetch.Default must be > 0", group: "Consumer.Fetch.Default must be > 0"},
+		{config: "max_wait_time: 0s", err: "Consumer.MaxWaitTime must be >= 1ms", group: "Consumer.MaxWaitTime must be >= 1ms"},
+		{config: "max_pending: 0", check: func(*testing.T, *sarama.Config) {}, group: "max_pending must be greater than 0"},
+	}
+
+	for _, test := range tests {
+		conf := defaultConsumerConfig
+		if err := yaml.Unmarshal([]byte(test.config), &conf); err != nil {
+			t.Fatal(err)
+		}
+
+		clusterConf, err := conf.newClusterConfig()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("kafka_consumer %q: Expected error %s - Got %v", test.config, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("kafka_consumer %q: %s", test.config, err)
+		} else {
+			test.check(t, &clusterConf.Config)
+		}
+
+		groupConf, err := conf.newConsumerGroupConfig()
+		switch {
+		case test.group != "":
+			if err == nil || !strings.Contains(err.Error(), test.group) {
+				t.Errorf("kafka_consumer_group %q: Expected error %s - Got %v", test.config, test.group, err)
+			}
+		case err != nil:
+			t.Errorf("kafka_consumer_group %q: %s", test.config, err)
+		case test.err == "":
+			test.check(t, groupConf)
+		}
+	}
+
+	conf := defaultConsumerConfig
+	conf.RebalanceStrategy = "sticky"
+	groupConf, err := conf.newConsumerGroupConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, groupConf.Consumer.Group.Rebalance.Strategy, sarama.BalanceStrategySticky)
+}
@@ -1,11 +1,17 @@
 package kafka
 
 import (
+	"context"
+	"expvar"
+	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
 	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/common/monitor"
 	"github.com/shima-park/lotus/pkg/component"
 	"gopkg.in/yaml.v2"
 
@@ -13,17 +19,25 @@ import (
 )
 
 var (
-	consumerFactory       component.Factory   = NewConsumerFactory()
-	_                     component.Component = &Consumer{}
-	defaultConsumerConfig                     = ConsumerConfig{
+	consumerFactory       component.Factory       = NewConsumerFactory()
+	_                     component.Component     = &Consumer{}
+	_                     component.HealthChecker = &Consumer{}
+	_                     component.MonitorSetter = &Consumer{}
+	defaultConsumerConfig                         = ConsumerConfig{
 		Name:              "kafka_consumer",
 		Addrs:             []string{"localhost:9092"},
 		ConsumerGroup:     "consumer_group",
 		Topics:            []string{"my_topics"},
 		OffsetsInitial:    sarama.OffsetNewest,
 		OffsetsAutoCommit: true,
+		ClientConfig:      defaultClientConfig,
+		RebalanceStrategy: "range",
+		FetchMinBytes:     1,
+		FetchDefaultBytes: 1024 * 1024,
+		MaxWaitTime:       250 * time.Millisecond,
+		MaxPending:        65536,
 	}
-	consumerDescription = "kafka consumer factory"
+	consumerDescription = "kafka consumer factory, processors should ack messages by kafka.Ack"
 )
 
 func init() {
@@ -48,7 +62,61 @@ type ConsumerConfig struct {
 	ConsumerGroup     string   `yaml:"consumer_group"`
 	Topics            []string `yaml:"topics"`
 	OffsetsInitial    int64    `yaml:"offsets_initial"`
-	OffsetsAutoCommit bool     `yaml:"offsets_auto_commit"`
+	OffsetsAutoCommit bool     `yaml:"offsets_auto_commit"` // 定时提交已被kafka.Ack标记的offset, 未标记的offset不会被提交
+
+	ClientConfig      `yaml:",inline"`
+	RebalanceStrategy string        `yaml:"rebalance_strategy"`  // range, roundrobin, kafka_consumer_group还支持sticky
+	FetchMinBytes     int32         `yaml:"fetch_min_bytes"`     // 单次fetch请求最少等待的字节数
+	FetchDefaultBytes int32         `yaml:"fetch_default_bytes"` // 单次fetch请求每个分区拉取的字节数
+	FetchMaxBytes     int32         `yaml:"fetch_max_bytes"`     // 单次fetch请求每个分区最多拉取的字节数, 0为不限制
+	MaxWaitTime       time.Duration `yaml:"max_wait_time"`       // broker等待满足fetch_min_bytes的最长时间
+	MaxPending        int           `yaml:"max_pending"`         // kafka_consumer_group每个分区等待Ack的消息数上限, 超过后offset在重启前不再前进
+}
+
+// apply sets the options shared by kafka_consumer and kafka_consumer_group.
+func (c ConsumerConfig) apply(conf *sarama.Config) error {
+	if err := c.ClientConfig.apply(conf); err != nil {
+		return err
+	}
+
+	conf.Consumer.Return.Errors = true
+	conf.Consumer.Offsets.Initial = c.OffsetsInitial
+
+	// sarama >= 1.26 https://github.com/Shopify/sarama/issues/1638
+	// fix it: panic: non-positive interval for NewTicker
+	conf.Consumer.Offsets.CommitInterval = time.Second
+	conf.Consumer.Offsets.AutoCommit.Enable = c.OffsetsAutoCommit
+
+	conf.Consumer.Fetch.Min = c.FetchMinBytes
+	conf.Consumer.Fetch.Default = c.FetchDefaultBytes
+	conf.Consumer.Fetch.Max = c.FetchMaxBytes
+	conf.Consumer.MaxWaitTime = c.MaxWaitTime
+	return nil
+}
+
+func (c ConsumerConfig) newClusterConfig() (*cluster.Config, error) {
+	kafkaConf := cluster.NewConfig()
+	if err := c.apply(&kafkaConf.Config); err != nil {
+		return nil, err
+	}
+
+	kafkaConf.Group.Return.Notifications = true
+
+	switch strings.ToLower(c.RebalanceStrategy) {
+	case "range", "":
+		kafkaConf.Group.PartitionStrategy = cluster.StrategyRange
+	case "roundrobin":
+		kafkaConf.Group.PartitionStrategy = cluster.StrategyRoundRobin
+	default:
+		return nil, fmt.Errorf("Unsupported rebalance_strategy: %s, supported values: range, roundrobin",
+			c.RebalanceStrategy)
+	}
+
+	if err := kafkaConf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return kafkaConf, nil
 }
 
 func (c ConsumerConfig) Marshal() ([]byte, error) {
@@ -57,6 +125,7 @@ func (c ConsumerConfig) Marshal() ([]byte, error) {
 
 type Consumer struct {
 	config   ConsumerConfig
+	client   *cluster.Client
 	consumer *cluster.Consumer
 	done     chan struct{}
 	instance component.Instance
@@ -71,24 +140,25 @@ func NewConsumer(rawConfig string) (*Consumer, error) {
 
 	log.Info("Kafka consumer config: %+v", conf)
 
-	kafkaConf := cluster.NewConfig()
-	kafkaConf.Consumer.Return.Errors = true
-	kafkaConf.Group.Return.Notifications = true
-	kafkaConf.Consumer.Offsets.Initial = conf.OffsetsInitial
+	kafkaConf, err := conf.newClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "kafka_consumer")
+	}
 
-	// sarama >= 1.26 https://github.com/Shopify/sarama/issues/1638
-	// fix it: panic: non-positive interval for NewTicker
-	kafkaConf.Consumer.Offsets.CommitInterval = time.Second
-	kafkaConf.Consumer.Offsets.AutoCommit.Enable = conf.OffsetsAutoCommit
+	client, err := cluster.NewClient(conf.Addrs, kafkaConf)
+	if err != nil {
+		return nil, err
+	}
 
-	consumer, err := cluster.NewConsumer(
-		conf.Addrs, conf.ConsumerGroup, conf.Topics, kafkaConf)
+	consumer, err := cluster.NewConsumerFromClient(client, conf.ConsumerGroup, conf.Topics)
 	if err != nil {
+		_ = client.Close()
 		return nil, err
 	}
 
 	return &Consumer{
 		config:   conf,
+		client:   client,
 		consumer: consumer,
 		done:     make(chan struct{}),
 		instance: component.NewInstance(
@@ -127,7 +197,36 @@ func (c *Consumer) Stop() error {
 		return nil
 	default:
 		close(c.done)
+		defer markers.Delete(c.consumer)
 
-		return c.consumer.Close()
+		if n := MarkerOf(c.consumer).Pending(); n > 0 {
+			log.Warn("Kafka consumer: %s stopped with %d unacked messages", c.config.Name, n)
+		}
+
+		err := c.consumer.Close()
+		if cerr := c.client.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+func (c *Consumer) SetMonitor(m monitor.Monitor) {
+	m.Set(METRICS_KEY_CONSUMER_PENDING_OFFSETS, expvar.Func(func() interface{} {
+		if marker, ok := markers.Load(c.consumer); ok {
+			return marker.(*OffsetMarker).Pending()
+		}
+		return 0
+	}))
+}
+
+// HealthCheck fails while a partition is stalled by a failed message, see kafka.Ack.
+func (c *Consumer) HealthCheck(ctx context.Context) error {
+	if err := checkMetadata(ctx, c.client, c.config.Topics...); err != nil {
+		return err
 	}
+	if marker, ok := markers.Load(c.consumer); ok {
+		return marker.(*OffsetMarker).Stalled()
+	}
+	return nil
 }
@@ -0,0 +1,300 @@
+package kafka
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	consumerGroupFactory       component.Factory       = NewConsumerGroupFactory()
+	_                          component.Component     = &ConsumerGroup{}
+	_                          component.HealthChecker = &ConsumerGroup{}
+	_                          component.MonitorSetter = &ConsumerGroup{}
+	defaultConsumerGroupConfig                         = func() ConsumerConfig {
+		c := defaultConsumerConfig
+		c.Name = "kafka_consumer_group"
+		return c
+	}()
+	consumerGroupDescription = "kafka consumer group factory based on sarama.ConsumerGroup, " +
+		"processors receive *kafka.Message from ConsumerGroup.Messages() and ack them by Message.Ack"
+)
+
+func init() {
+	if err := component.Register("kafka_consumer_group", consumerGroupFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewConsumerGroupFactory() component.Factory {
+	return component.NewFactory(
+		defaultConsumerGroupConfig,
+		consumerGroupDescription,
+		reflect.TypeOf(&ConsumerGroup{}),
+		func(c string) (component.Component, error) {
+			return NewConsumerGroup(c)
+		})
+}
+
+func (c ConsumerConfig) newConsumerGroupConfig() (*sarama.Config, error) {
+	kafkaConf := sarama.NewConfig()
+	if err := c.apply(kafkaConf); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(c.RebalanceStrategy) {
+	case "range", "":
+		kafkaConf.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRange
+	case "roundrobin":
+		kafkaConf.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
+	case "sticky":
+		kafkaConf.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategySticky
+	default:
+		return nil, fmt.Errorf("Unsupported rebalance_strategy: %s, supported values: range, roundrobin, sticky",
+			c.RebalanceStrategy)
+	}
+
+	if c.MaxPending <= 0 {
+		return nil, errors.New("max_pending must be greater than 0")
+	}
+
+	if !kafkaConf.Version.IsAtLeast(sarama.V0_10_2_0) {
+		return nil, fmt.Errorf("The kafka version must be at least %s, got: %s",
+			sarama.V0_10_2_0, kafkaConf.Version)
+	}
+
+	if err := kafkaConf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return kafkaConf, nil
+}
+
+// Message is a consumed message together with the session it was claimed in.
+type Message struct {
+	*sarama.ConsumerMessage
+
+	// Session is the consumer group session which claimed the partition of the message.
+	// It is canceled when the partitions are rebalanced.
+	Session sarama.ConsumerGroupSession
+
+	done func(error)
+}
+
+// Ack marks the offset of the message after every stream reached by it has succeeded,
+// see kafka.Ack. Offsets are marked in the order they were consumed within a partition,
+// so every message received from the channel must be acked,
+// more than max_pending unacked messages of a partition stall it like a failed message.
+// Without a checkpoint the offset is marked immediately.
+func (m *Message) Ack(cp checkpoint.Checkpoint) {
+	ack(cp, m.done, m.ConsumerMessage)
+}
+
+// ConsumerGroup is injected into processors by the kafka_consumer_group component.
+type ConsumerGroup struct {
+	config   ConsumerConfig
+	client   sarama.Client
+	group    sarama.ConsumerGroup
+	messages chan *Message
+	ctx      context.Context
+	cancel   context.CancelFunc
+	once     sync.Once
+	wg       sync.WaitGroup
+	instance component.Instance
+	markers  sync.Map // key: *OffsetMarker of the claims being consumed
+}
+
+func NewConsumerGroup(rawConfig string) (*ConsumerGroup, error) {
+	conf := defaultConsumerGroupConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Kafka consumer group config: %+v", conf)
+
+	kafkaConf, err := conf.newConsumerGroupConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "kafka_consumer_group")
+	}
+
+	client, err := sarama.NewClient(conf.Addrs, kafkaConf)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := sarama.NewConsumerGroupFromClient(conf.ConsumerGroup, client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &ConsumerGroup{
+		config:   conf,
+		client:   client,
+		group:    group,
+		messages: make(chan *Message, kafkaConf.ChannelBufferSize),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	c.instance = component.NewInstance(
+		conf.Name,
+		reflect.TypeOf(c),
+		reflect.ValueOf(c),
+		c,
+	)
+	return c, nil
+}
+
+// Messages returns the channel of consumed messages, it is closed after the component stopped.
+func (c *ConsumerGroup) Messages() <-chan *Message {
+	return c.messages
+}
+
+func (c *ConsumerGroup) Instance() component.Instance {
+	return c.instance
+}
+
+func (c *ConsumerGroup) Start() error {
+	c.once.Do(func() {
+		c.wg.Add(2)
+		go c.consume()
+		go c.handleErrors()
+	})
+	return nil
+}
+
+func (c *ConsumerGroup) consume() {
+	defer c.wg.Done()
+	defer close(c.messages)
+
+	handler := &consumerGroupHandler{consumer: c}
+	for {
+		// Consume会一直阻塞直到发生rebalance, 之后需要重新加入消费组
+		if err := c.group.Consume(c.ctx, c.config.Topics, handler); err != nil {
+			if err == sarama.ErrClosedConsumerGroup {
+				return
+			}
+			log.Error("Kafka consumer group: %s, Error: %s", c.config.Name, err)
+
+			select {
+			case <-c.ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+
+		if c.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (c *ConsumerGroup) handleErrors() {
+	defer c.wg.Done()
+
+	for err := range c.group.Errors() {
+		log.Error("Kafka consumer group: %s, Error: %s", c.config.Name, err)
+	}
+}
+
+func (c *ConsumerGroup) Stop() error {
+	select {
+	case <-c.ctx.Done():
+		return nil
+	default:
+	}
+
+	c.cancel()
+	// 关闭时会提交已经标记的offset
+	err := c.group.Close()
+	c.wg.Wait()
+	if cerr := c.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (c *ConsumerGroup) SetMonitor(m monitor.Monitor) {
+	m.Set(METRICS_KEY_CONSUMER_PENDING_OFFSETS, expvar.Func(func() interface{} {
+		var n int
+		c.markers.Range(func(k, _ interface{}) bool {
+			n += k.(*OffsetMarker).Pending()
+			return true
+		})
+		return n
+	}))
+}
+
+// HealthCheck fails while a claimed partition is stalled by a failed message,
+// the message is consumed again after a restart or a rebalance.
+func (c *ConsumerGroup) HealthCheck(ctx context.Context) error {
+	if err := checkMetadata(ctx, c.client, c.config.Topics...); err != nil {
+		return err
+	}
+
+	var err error
+	c.markers.Range(func(k, _ interface{}) bool {
+		err = k.(*OffsetMarker).Stalled()
+		return err == nil
+	})
+	return err
+}
+
+type consumerGroupHandler struct {
+	consumer *ConsumerGroup
+}
+
+func (h *consumerGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	log.Info("Kafka consumer group: %s, MemberID: %s, GenerationID: %d, Claims: %v",
+		h.consumer.config.Name, session.MemberID(), session.GenerationID(), session.Claims())
+	return nil
+}
+
+func (h *consumerGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// 每个分区在单独的goroutine中消费, 在这里登记offset才能保证与消费顺序一致
+	marker := NewOffsetMarker(func(msg *sarama.ConsumerMessage) {
+		session.MarkMessage(msg, "")
+	}, h.consumer.config.MaxPending)
+	h.consumer.markers.Store(marker, struct{}{})
+	defer h.consumer.markers.Delete(marker)
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			m := &Message{
+				ConsumerMessage: msg,
+				Session:         session,
+				done:            marker.Track(msg),
+			}
+
+			select {
+			case h.consumer.messages <- m:
+			case <-session.Context().Done():
+				return nil
+			}
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
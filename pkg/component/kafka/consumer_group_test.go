@@ -0,0 +1,310 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
+)
+
+const (
+	testTopic = "my_topic"
+	testGroup = "my_group"
+)
+
+func TestOffsetMarker(t *testing.T) {
+	var marked []int64
+	m := NewOffsetMarker(func(msg *sarama.ConsumerMessage) {
+		marked = append(marked, msg.Offset)
+	}, 0)
+
+	var dones []func(error)
+	for i := int64(0); i < 4; i++ {
+		dones = append(dones, m.Track(&sarama.ConsumerMessage{Topic: testTopic, Offset: i}))
+	}
+	other := m.Track(&sarama.ConsumerMessage{Topic: testTopic, Partition: 1, Offset: 10})
+
+	dones[2](nil)
+	dones[1](nil)
+	expect(t, fmt.Sprint(marked), "[]")
+
+	dones[0](nil)
+	expect(t, fmt.Sprint(marked), "[2]")
+
+	other(nil)
+	dones[3](nil)
+	expect(t, fmt.Sprint(marked), "[2 10 3]")
+	expect(t, m.Pending(), 0)
+	expect(t, m.Stalled(), nil)
+}
+
+func TestOffsetMarkerFailed(t *testing.T) {
+	var marked []int64
+	m := NewOffsetMarker(func(msg *sarama.ConsumerMessage) {
+		marked = append(marked, msg.Offset)
+	}, 0)
+
+	var dones []func(error)
+	for i := int64(0); i < 4; i++ {
+		dones = append(dones, m.Track(&sarama.ConsumerMessage{Topic: testTopic, Offset: i}))
+	}
+	other := m.Track(&sarama.ConsumerMessage{Topic: testTopic, Partition: 1, Offset: 10})
+
+	// 失败的offset之前的offset仍然可以被标记, 之后的都不会被标记
+	dones[3](nil)
+	dones[1](fmt.Errorf("failed"))
+	dones[2](nil)
+	dones[0](nil)
+	other(nil)
+	expect(t, fmt.Sprint(marked), "[0 10]")
+
+	// 失败之后消费的offset不再保存在内存中, 只计数
+	for i := int64(4); i < 100; i++ {
+		m.Track(&sarama.ConsumerMessage{Topic: testTopic, Offset: i})(nil)
+	}
+	expect(t, fmt.Sprint(marked), "[0 10]")
+	expect(t, len(m.partitions[topicPartition{testTopic, 0}].pendings), 1)
+	expect(t, m.Pending(), 99)
+
+	err := m.Stalled()
+	if err == nil || !strings.Contains(err.Error(), "partition: 0, offset: 1, pending: 99, error: failed") {
+		t.Fatalf("Expected partition 0 stalled at offset 1 - Got %v", err)
+	}
+}
+
+func TestOffsetMarkerMaxPending(t *testing.T) {
+	var marked []int64
+	m := NewOffsetMarker(func(msg *sarama.ConsumerMessage) {
+		marked = append(marked, msg.Offset)
+	}, 2)
+
+	// 超过max_pending的offset不再登记, 不Ack的消息不会让pendings无限增长
+	var dones []func(error)
+	for i := int64(0); i < 100; i++ {
+		dones = append(dones, m.Track(&sarama.ConsumerMessage{Topic: testTopic, Offset: i}))
+	}
+	expect(t, len(m.partitions[topicPartition{testTopic, 0}].pendings), 2)
+	expect(t, m.Pending(), 100)
+
+	err := m.Stalled()
+	if err == nil || !strings.Contains(err.Error(), "partition: 0, offset: 0, pending: 100, error: 2 messages are not acked") {
+		t.Fatalf("Expected partition 0 stalled at offset 0 - Got %v", err)
+	}
+
+	// 已登记的offset仍然可以被标记
+	for _, done := range dones {
+		done(nil)
+	}
+	expect(t, fmt.Sprint(marked), "[0 1]")
+}
+
+func TestConsumerGroupConfig(t *testing.T) {
+	_, err := NewConsumerGroup("rebalance_strategy: unknown")
+	if err == nil {
+		t.Fatal("Expected an error for unsupported rebalance_strategy")
+	}
+
+	_, err = NewConsumerGroup("version: 0.10.0.0")
+	if err == nil {
+		t.Fatal("Expected an error for version < 0.10.2.0")
+	}
+}
+
+func TestConsumerGroupAck(t *testing.T) {
+	broker := newMockBroker(t, "a", "b", "c")
+	defer broker.Close()
+
+	c, err := NewConsumerGroup(fmt.Sprintf(`
+addrs: [%s]
+consumer_group: %s
+topics: [%s]
+version: 0.10.2.0
+offsets_initial: -2
+`, broker.Addr(), testGroup, testTopic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []*Message
+	var trackers []*checkpoint.Tracker
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-c.Messages():
+			expect(t, msg.Offset, int64(i))
+			tracker := checkpoint.New()
+			msg.Ack(tracker)
+			messages = append(messages, msg)
+			trackers = append(trackers, tracker)
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timeout waiting for messages")
+		}
+	}
+	expect(t, string(messages[1].Value), "b")
+
+	// 乱序完成, 只有前面的offset都完成后才会被标记
+	trackers[2].Done(nil)
+	trackers[0].Done(nil)
+	trackers[1].Done(nil)
+
+	if err = c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-c.Messages(); ok {
+		t.Fatal("Expected the messages channel to be closed")
+	}
+
+	committed := committedOffsets(broker)
+	if len(committed) == 0 {
+		t.Fatal("Expected offsets to be committed")
+	}
+	for _, offset := range committed {
+		// MarkMessage提交的是下一条需要消费的offset
+		if offset != 1 && offset != 3 {
+			t.Fatalf("Unexpected committed offset %d in %v", offset, committed)
+		}
+	}
+	expect(t, committed[len(committed)-1], int64(3))
+}
+
+func TestConsumerGroupAbort(t *testing.T) {
+	broker := newMockBroker(t, "a", "b")
+	defer broker.Close()
+
+	c, err := NewConsumerGroup(fmt.Sprintf(`
+addrs: [%s]
+consumer_group: %s
+topics: [%s]
+version: 0.10.2.0
+offsets_initial: -2
+`, broker.Addr(), testGroup, testTopic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		msg := <-c.Messages()
+		tracker := checkpoint.New()
+		msg.Ack(tracker)
+		if i == 0 {
+			tracker.Done(fmt.Errorf("failed"))
+		} else {
+			tracker.Done(nil)
+		}
+	}
+
+	if err = c.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "offset: 0") {
+		t.Fatalf("Expected the partition to be stalled at offset 0 - Got %v", err)
+	}
+
+	if err = c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一条消息处理失败, 后续的offset也不能被提交
+	for _, offset := range committedOffsets(broker) {
+		t.Fatalf("Unexpected committed offset %d", offset)
+	}
+}
+
+func newMockBroker(t *testing.T, values ...string) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 0)
+
+	fetch := sarama.NewMockFetchResponse(t, 1).SetVersion(3)
+	for i, v := range values {
+		fetch.SetMessage(testTopic, 0, int64(i), sarama.StringEncoder(v))
+	}
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(testTopic, 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, testGroup, broker),
+		"JoinGroupRequest": sarama.NewMockWrapper(&sarama.JoinGroupResponse{
+			Version:      1,
+			GenerationId: 1,
+			LeaderId:     "leader",
+			MemberId:     "member",
+		}),
+		"SyncGroupRequest": sarama.NewMockWrapper(&sarama.SyncGroupResponse{
+			MemberAssignment: encodeAssignment(testTopic, 0),
+		}),
+		"HeartbeatRequest":  sarama.NewMockWrapper(&sarama.HeartbeatResponse{}),
+		"LeaveGroupRequest": sarama.NewMockWrapper(&sarama.LeaveGroupResponse{}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(testGroup, testTopic, 0, -1, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset(testTopic, 0, sarama.OffsetOldest, 0).
+			SetOffset(testTopic, 0, sarama.OffsetNewest, int64(len(values))),
+		"FetchRequest":        fetch,
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	return broker
+}
+
+// encodeAssignment encodes a ConsumerGroupMemberAssignment, sarama does not export its encoder.
+func encodeAssignment(topic string, partitions ...int32) []byte {
+	buf := new(bytes.Buffer)
+	write := func(v interface{}) {
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+
+	write(int16(0)) // version
+	write(int32(1)) // topics
+	write(int16(len(topic)))
+	buf.WriteString(topic)
+	write(int32(len(partitions)))
+	for _, p := range partitions {
+		write(p)
+	}
+	write(int32(-1)) // user data
+	return buf.Bytes()
+}
+
+// committedOffsets returns the offsets of testTopic/0 in the order they were committed.
+func committedOffsets(broker *sarama.MockBroker) []int64 {
+	var offsets []int64
+	for _, rr := range broker.History() {
+		req, ok := rr.Request.(*sarama.OffsetCommitRequest)
+		if !ok {
+			continue
+		}
+
+		// OffsetCommitRequest没有导出读取offset的方法, 通过反射读取
+		blocks := reflect.ValueOf(req).Elem().FieldByName("blocks")
+		partitions := blocks.MapIndex(reflect.ValueOf(testTopic))
+		if !partitions.IsValid() {
+			continue
+		}
+		block := partitions.MapIndex(reflect.ValueOf(int32(0)))
+		if !block.IsValid() {
+			continue
+		}
+		offsets = append(offsets, block.Elem().FieldByName("offset").Int())
+	}
+	return offsets
+}
+
+func expect(t *testing.T, a interface{}, b interface{}) {
+	t.Helper()
+	if a != b {
+		t.Errorf("Expected %v (type %v) - Got %v (type %v)", b, reflect.TypeOf(b), a, reflect.TypeOf(a))
+	}
+}
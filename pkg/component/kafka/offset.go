@@ -0,0 +1,196 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
+	"github.com/shima-park/lotus/pkg/common/log"
+
+	cluster "github.com/bsm/sarama-cluster"
+)
+
+const (
+	METRICS_KEY_CONSUMER_PENDING_OFFSETS = "_consumer_pending_offsets"
+)
+
+// MarkFunc marks msg as processed, e.g. (*cluster.Consumer).MarkOffset.
+type MarkFunc func(msg *sarama.ConsumerMessage)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type pendingOffset struct {
+	msg  *sarama.ConsumerMessage
+	done bool
+}
+
+type partitionOffsets struct {
+	pendings []*pendingOffset
+	// 处理失败的offset, 它和之后的offset在重启前都不会被标记, 不再登记之后的offset
+	failed *pendingOffset
+	err    error
+	held   int // failed之后没有登记的offset数
+}
+
+// OffsetMarker marks offsets in the order they were consumed.
+// Messages may finish out of order when a stream runs with several replicas,
+// an offset is only marked after all offsets before it in the same partition
+// have finished, so a restart never skips an unprocessed message.
+// A failed message stalls its partition until a restart, the offsets after it
+// are not kept in memory and the stall is reported by Stalled.
+// More than maxPending unfinished offsets of a partition stall it in the same way,
+// so messages which are never acked do not grow the pendings without bound.
+type OffsetMarker struct {
+	lock       sync.Mutex
+	mark       MarkFunc
+	maxPending int // 每个分区未完成的offset数上限, 小于等于0时不限制
+	partitions map[topicPartition]*partitionOffsets
+}
+
+func NewOffsetMarker(mark MarkFunc, maxPending int) *OffsetMarker {
+	return &OffsetMarker{
+		mark:       mark,
+		maxPending: maxPending,
+		partitions: map[topicPartition]*partitionOffsets{},
+	}
+}
+
+// Track registers msg as in flight and returns the func to call when it has been processed,
+// a non-nil error marks msg as failed.
+func (m *OffsetMarker) Track(msg *sarama.ConsumerMessage) func(error) {
+	tp := topicPartition{msg.Topic, msg.Partition}
+	po := &pendingOffset{msg: msg}
+
+	m.lock.Lock()
+	p, ok := m.partitions[tp]
+	if !ok {
+		p = &partitionOffsets{}
+		m.partitions[tp] = p
+	}
+	if p.failed == nil && m.maxPending > 0 && len(p.pendings) >= m.maxPending {
+		// 没有Ack的消息太多, 和失败的消息一样停在最早的offset
+		p.failed = p.pendings[0]
+		p.err = fmt.Errorf("%d messages are not acked", len(p.pendings))
+	}
+	if p.failed != nil {
+		p.held++
+		m.lock.Unlock()
+		return func(error) {}
+	}
+	p.pendings = append(p.pendings, po)
+	m.lock.Unlock()
+
+	return func(err error) {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		if err != nil {
+			m.fail(p, po, err)
+			return
+		}
+		po.done = true
+
+		pendings := p.pendings
+		var last *pendingOffset
+		for len(pendings) > 0 && pendings[0].done {
+			last = pendings[0]
+			pendings = pendings[1:]
+		}
+		p.pendings = pendings
+
+		if last != nil {
+			m.mark(last.msg)
+		}
+	}
+}
+
+// fail stalls the partition at po, the offsets after po are dropped because
+// they can no longer be marked, the caller must hold the lock.
+func (m *OffsetMarker) fail(p *partitionOffsets, po *pendingOffset, err error) {
+	for i, pending := range p.pendings {
+		if pending == po {
+			p.held += len(p.pendings) - i - 1
+			p.pendings = p.pendings[:i+1]
+			p.failed, p.err = po, err
+			return
+		}
+	}
+	// po已经在更早失败的offset之后被丢弃了
+}
+
+// Pending returns the number of consumed offsets which have not been marked yet.
+func (m *OffsetMarker) Pending() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var n int
+	for _, p := range m.partitions {
+		n += len(p.pendings) + p.held
+	}
+	return n
+}
+
+// Stalled returns an error describing the partitions which cannot advance
+// because a message failed, the failed message is consumed again after a restart.
+func (m *OffsetMarker) Stalled() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var list []string
+	for tp, p := range m.partitions {
+		if p.failed == nil {
+			continue
+		}
+		list = append(list, fmt.Sprintf("topic: %s, partition: %d, offset: %d, pending: %d, error: %s",
+			tp.topic, tp.partition, p.failed.msg.Offset, len(p.pendings)+p.held, p.err))
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Strings(list)
+	return fmt.Errorf("Kafka offsets stalled by failed or unacked messages, restart to consume them again: %s",
+		strings.Join(list, "; "))
+}
+
+var markers sync.Map // key: *cluster.Consumer value: *OffsetMarker
+
+// MarkerOf returns the OffsetMarker of a consumer created by the kafka_consumer component,
+// the offsets are only tracked by Ack, so the pendings are not bounded.
+func MarkerOf(consumer *cluster.Consumer) *OffsetMarker {
+	if m, ok := markers.Load(consumer); ok {
+		return m.(*OffsetMarker)
+	}
+	m, _ := markers.LoadOrStore(consumer, NewOffsetMarker(func(msg *sarama.ConsumerMessage) {
+		consumer.MarkOffset(msg, "")
+	}, 0))
+	return m.(*OffsetMarker)
+}
+
+// Ack marks the offset of msg after every stream reached by msg has succeeded.
+// If any stream fails the offset stays unmarked, which holds back the committed
+// offset of the partition so that the message is consumed again after a restart,
+// the health check of the consumer fails until then.
+// Without a checkpoint the offset is marked immediately.
+func Ack(cp checkpoint.Checkpoint, consumer *cluster.Consumer, msg *sarama.ConsumerMessage) {
+	ack(cp, MarkerOf(consumer).Track(msg), msg)
+}
+
+func ack(cp checkpoint.Checkpoint, done func(error), msg *sarama.ConsumerMessage) {
+	if cp == nil {
+		done(nil)
+		return
+	}
+
+	cp.OnCommit(func() { done(nil) })
+	cp.OnAbort(func(err error) {
+		log.Warn("Kafka message topic: %s, partition: %d, offset: %d is not acked: %s",
+			msg.Topic, msg.Partition, msg.Offset, err)
+		done(err)
+	})
+}
@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/shima-park/lotus/pkg/common/log"
@@ -8,15 +9,22 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
 	"github.com/shima-park/lotus/pkg/common/inject"
 )
 
 var (
-	producerFactory       component.Factory   = NewProducerFactory()
-	_                     component.Component = &Producer{}
-	defaultProducerConfig                     = ProducerConfig{
-		Name:  "kafka_producer",
-		Addrs: []string{"localhost:9092"},
+	producerFactory       component.Factory       = NewProducerFactory()
+	_                     component.Component     = &Producer{}
+	_                     component.HealthChecker = &Producer{}
+	defaultProducerConfig                         = ProducerConfig{
+		Name:            "kafka_producer",
+		Addrs:           []string{"localhost:9092"},
+		ClientConfig:    defaultClientConfig,
+		RequiredAcks:    "local",
+		Compression:     "none",
+		MaxMessageBytes: 1000000,
+		RetryMax:        3,
 	}
 	producerDescription = "kafka producer factory"
 )
@@ -40,6 +48,48 @@ func NewProducerFactory() component.Factory {
 type ProducerConfig struct {
 	Name  string   `yaml:"name"`
 	Addrs []string `yaml:"addrs"`
+
+	ClientConfig    `yaml:",inline"`
+	RequiredAcks    string `yaml:"required_acks"` // none, local, all
+	Compression     string `yaml:"compression"`   // none, gzip, snappy, lz4, zstd
+	Idempotent      bool   `yaml:"idempotent"`    // 需要required_acks: all, version >= 0.11.0
+	MaxMessageBytes int    `yaml:"max_message_bytes"`
+	RetryMax        int    `yaml:"retry_max"`
+}
+
+func (c ProducerConfig) newSaramaConfig() (*sarama.Config, error) {
+	kafkaConf := sarama.NewConfig()
+	if err := c.ClientConfig.apply(kafkaConf); err != nil {
+		return nil, err
+	}
+
+	kafkaConf.Producer.Return.Successes = true
+	kafkaConf.Producer.Return.Errors = true
+
+	var err error
+	kafkaConf.Producer.RequiredAcks, err = parseRequiredAcks(c.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	kafkaConf.Producer.Compression, err = parseCompression(c.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	kafkaConf.Producer.MaxMessageBytes = c.MaxMessageBytes
+	kafkaConf.Producer.Retry.Max = c.RetryMax
+	kafkaConf.Producer.Idempotent = c.Idempotent
+	if c.Idempotent {
+		// 幂等生产者要求同一连接上只能有一个进行中的请求
+		kafkaConf.Net.MaxOpenRequests = 1
+	}
+
+	if err := kafkaConf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return kafkaConf, nil
 }
 
 func (c ProducerConfig) Marshal() ([]byte, error) {
@@ -48,6 +98,7 @@ func (c ProducerConfig) Marshal() ([]byte, error) {
 
 type Producer struct {
 	config   ProducerConfig
+	client   sarama.Client
 	producer sarama.SyncProducer
 	instance component.Instance
 }
@@ -61,13 +112,25 @@ func NewProducer(rawConfig string) (*Producer, error) {
 
 	log.Info("Kafka producer config: %+v", conf)
 
-	producer, err := sarama.NewSyncProducer(conf.Addrs, nil)
+	kafkaConf, err := conf.newSaramaConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "kafka_producer")
+	}
+
+	client, err := sarama.NewClient(conf.Addrs, kafkaConf)
 	if err != nil {
 		return nil, err
 	}
 
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
 	return &Producer{
 		config:   conf,
+		client:   client,
 		producer: producer,
 		instance: component.NewInstance(
 			conf.Name,
@@ -86,6 +149,14 @@ func (c *Producer) Start() error {
 	return nil
 }
 
+func (c *Producer) HealthCheck(ctx context.Context) error {
+	return checkMetadata(ctx, c.client)
+}
+
 func (c *Producer) Stop() error {
-	return c.producer.Close()
+	err := c.producer.Close()
+	if cerr := c.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
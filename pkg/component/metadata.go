@@ -0,0 +1,38 @@
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 组件需要持久化的状态(例如io_tail的offset)默认保存在服务的元数据目录下
+var (
+	metadataPathLock sync.RWMutex
+	metadataPath     string
+)
+
+// SetMetadataPath is called by the server with its metadata directory (--meta).
+func SetMetadataPath(path string) {
+	metadataPathLock.Lock()
+	defer metadataPathLock.Unlock()
+
+	metadataPath = path
+}
+
+// MetadataPath returns the metadata directory of the server,
+// the meta directory under the working directory if the server has not set it.
+func MetadataPath() string {
+	metadataPathLock.RLock()
+	path := metadataPath
+	metadataPathLock.RUnlock()
+
+	if path != "" {
+		return path
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "meta"
+	}
+	return filepath.Join(pwd, "meta")
+}
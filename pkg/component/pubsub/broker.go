@@ -0,0 +1,154 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
+)
+
+// Message 订阅收到的消息
+type Message struct {
+	Subject string
+	Reply   string // 请求-响应模式下的回复subject, 可以为空
+	Data    []byte
+}
+
+// Publisher 注入到processor的发布接口, 与具体的broker无关
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// Subscriber 注入到processor的订阅接口, 与具体的broker无关
+type Subscriber interface {
+	Messages() <-chan *Message
+}
+
+// Handler 处理订阅到的消息, 同一个订阅的消息按顺序回调
+// memory broker同步回调, 阻塞时发布者也被阻塞; nats broker在每个订阅独立的goroutine中回调,
+// 阻塞时消息暂存在订阅的队列中, 超过pending_msgs/pending_bytes后丢弃
+type Handler func(msg *Message)
+
+type Subscription interface {
+	Unsubscribe() error
+	// Dropped 订阅的队列满了之后丢弃的消息数
+	Dropped() int
+}
+
+// Broker 消息中间件的连接, 新的中间件通过RegisterBroker按url的scheme注册
+type Broker interface {
+	Connect() error
+	Publish(ctx context.Context, subject string, data []byte) error
+	// queue不为空时, 同一个queue的订阅者中只有一个会收到消息
+	Subscribe(subject, queue string, handler Handler) (Subscription, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+type BrokerConfig struct {
+	URL            string         `yaml:"url"` // memory://name 或者 nats://host:4222, 多个nats地址用逗号分隔
+	Username       string         `yaml:"username,omitempty"`
+	Password       string         `yaml:"password,omitempty"`
+	Token          string         `yaml:"token,omitempty"`
+	ConnectTimeout time.Duration  `yaml:"connect_timeout"`
+	WriteTimeout   time.Duration  `yaml:"write_timeout"`
+	ReconnectWait  time.Duration  `yaml:"reconnect_wait"`
+	MaxReconnects  int            `yaml:"max_reconnects"` // 小于0时无限重连
+	PendingMsgs    int            `yaml:"pending_msgs"`   // 每个订阅未处理消息数的上限, 为0时使用nats的默认值65536
+	PendingBytes   int            `yaml:"pending_bytes"`  // 每个订阅未处理消息字节数的上限, 为0时使用nats的默认值64MB
+	SyncPublish    bool           `yaml:"sync_publish"`   // 为true时每次发布都等待服务端确认收到, 每条消息一次往返
+	TLS            utiltls.Config `yaml:"tls"`
+}
+
+// String hides the password and token when the config is logged.
+func (c BrokerConfig) String() string {
+	return fmt.Sprintf("{URL:%s Username:%s Password:****** Token:****** ConnectTimeout:%s MaxReconnects:%d}",
+		c.URL, c.Username, c.ConnectTimeout, c.MaxReconnects)
+}
+
+var defaultBrokerConfig = BrokerConfig{
+	URL:            "nats://127.0.0.1:4222",
+	ConnectTimeout: 2 * time.Second,
+	WriteTimeout:   2 * time.Second,
+	ReconnectWait:  2 * time.Second,
+	MaxReconnects:  60,
+}
+
+type BrokerFactory func(conf BrokerConfig) (Broker, error)
+
+var (
+	brokersLock sync.RWMutex
+	brokers     = map[string]BrokerFactory{}
+)
+
+func RegisterBroker(scheme string, factory BrokerFactory) error {
+	brokersLock.Lock()
+	defer brokersLock.Unlock()
+
+	if scheme == "" {
+		return fmt.Errorf("Error registering broker: scheme cannot be empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("Error registering broker '%v': factory cannot be empty", scheme)
+	}
+	if _, exists := brokers[scheme]; exists {
+		return fmt.Errorf("Error registering broker '%v': already registered", scheme)
+	}
+
+	brokers[scheme] = factory
+	return nil
+}
+
+func ListBrokers() []string {
+	brokersLock.RLock()
+	defer brokersLock.RUnlock()
+
+	var list []string
+	for scheme := range brokers {
+		list = append(list, scheme)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// NewBroker creates the broker registered for the scheme of conf.URL, it does not connect.
+func NewBroker(conf BrokerConfig) (Broker, error) {
+	first := strings.TrimSpace(strings.Split(conf.URL, ",")[0])
+	u, err := url.Parse(first)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid broker url: %s", err)
+	}
+
+	brokersLock.RLock()
+	factory, ok := brokers[u.Scheme]
+	brokersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unsupported broker: %s, supported brokers: %s",
+			u.Scheme, strings.Join(ListBrokers(), ", "))
+	}
+	return factory(conf)
+}
+
+// matchSubject 按nats的规则匹配subject, *匹配一个token, >匹配剩余的一个或多个token
+func matchSubject(pattern, subject string) bool {
+	pt := strings.Split(pattern, ".")
+	st := strings.Split(subject, ".")
+
+	for i, t := range pt {
+		if t == ">" {
+			return len(st) > i
+		}
+		if i >= len(st) {
+			return false
+		}
+		if t != "*" && t != st[i] {
+			return false
+		}
+	}
+	return len(pt) == len(st)
+}
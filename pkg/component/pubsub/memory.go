@@ -0,0 +1,190 @@
+package pubsub
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	if err := RegisterBroker("memory", newMemoryBroker); err != nil {
+		panic(err)
+	}
+}
+
+var (
+	ErrBrokerClosed = errors.New("Broker is closed")
+
+	busesLock sync.Mutex
+	buses     = map[string]*memoryBus{}
+)
+
+// memoryBus 进程内的消息总线, url相同的memory broker共享同一个总线
+type memoryBus struct {
+	lock   sync.RWMutex
+	subs   map[*memorySubscription]struct{}
+	queues map[string]uint64 // key: queue, 轮询投递的计数
+}
+
+func getMemoryBus(name string) *memoryBus {
+	busesLock.Lock()
+	defer busesLock.Unlock()
+
+	bus, ok := buses[name]
+	if !ok {
+		bus = &memoryBus{
+			subs:   map[*memorySubscription]struct{}{},
+			queues: map[string]uint64{},
+		}
+		buses[name] = bus
+	}
+	return bus
+}
+
+// targets returns the subscriptions receiving a message of subject,
+// one subscription of each queue is chosen round robin.
+func (b *memoryBus) targets(subject string) []*memorySubscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var (
+		list   []*memorySubscription
+		queues = map[string][]*memorySubscription{}
+	)
+	for sub := range b.subs {
+		if !matchSubject(sub.subject, subject) {
+			continue
+		}
+		if sub.queue == "" {
+			list = append(list, sub)
+			continue
+		}
+		queues[sub.queue] = append(queues[sub.queue], sub)
+	}
+
+	for queue, members := range queues {
+		// map的遍历顺序不固定, 按订阅的先后排序保证轮询是均匀的
+		sort.Slice(members, func(i, j int) bool { return members[i].seq < members[j].seq })
+		n := b.queues[queue]
+		b.queues[queue] = n + 1
+		list = append(list, members[n%uint64(len(members))])
+	}
+	return list
+}
+
+type memoryBroker struct {
+	bus *memoryBus
+
+	lock   sync.RWMutex
+	closed bool
+	subs   []*memorySubscription
+}
+
+var memorySeq uint64
+
+func newMemoryBroker(conf BrokerConfig) (Broker, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryBroker{bus: getMemoryBus(u.Host + u.Path)}, nil
+}
+
+func (b *memoryBroker) Connect() error {
+	return nil
+}
+
+// Publish delivers the message synchronously, a blocking handler blocks the publisher.
+func (b *memoryBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	b.lock.RLock()
+	closed := b.closed
+	b.lock.RUnlock()
+	if closed {
+		return ErrBrokerClosed
+	}
+
+	for _, sub := range b.bus.targets(subject) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 每个订阅者拿到独立的副本, 避免互相修改
+		msg := &Message{Subject: subject, Data: append([]byte(nil), data...)}
+		sub.handler(msg)
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(subject, queue string, handler Handler) (Subscription, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+
+	sub := &memorySubscription{
+		bus:     b.bus,
+		seq:     atomic.AddUint64(&memorySeq, 1),
+		subject: subject,
+		queue:   queue,
+		handler: handler,
+	}
+	b.bus.lock.Lock()
+	b.bus.subs[sub] = struct{}{}
+	b.bus.lock.Unlock()
+
+	b.subs = append(b.subs, sub)
+	return sub, nil
+}
+
+func (b *memoryBroker) Ping(ctx context.Context) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	return nil
+}
+
+func (b *memoryBroker) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	for _, sub := range b.subs {
+		_ = sub.Unsubscribe()
+	}
+	b.subs = nil
+	return nil
+}
+
+type memorySubscription struct {
+	bus     *memoryBus
+	seq     uint64
+	subject string
+	queue   string
+	handler Handler
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	delete(s.bus.subs, s)
+	return nil
+}
+
+// Dropped is always 0, the memory broker delivers synchronously.
+func (s *memorySubscription) Dropped() int {
+	return 0
+}
@@ -0,0 +1,246 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/log"
+)
+
+func init() {
+	for _, scheme := range []string{"nats", "tls"} {
+		if err := RegisterBroker(scheme, newNATSBroker); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var ErrNotConnected = errors.New("Broker is not connected")
+
+// natsBroker 基于官方的nats客户端, 每个订阅在独立的goroutine中回调handler,
+// 未处理的消息超过pending_msgs/pending_bytes时丢弃并计数, 不会阻塞连接的读循环和PING/PONG
+type natsBroker struct {
+	conf BrokerConfig
+	opts nats.Options
+
+	lock   sync.Mutex
+	conn   *nats.Conn
+	closed bool
+}
+
+func newNATSBroker(conf BrokerConfig) (Broker, error) {
+	var servers []string
+	for _, s := range strings.Split(conf.URL, ",") {
+		s = strings.TrimSpace(s)
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid nats url: %s", err)
+		}
+		if u.Scheme != "nats" && u.Scheme != "tls" {
+			return nil, fmt.Errorf("Invalid nats url: %s, scheme must be nats or tls", s)
+		}
+		servers = append(servers, s)
+	}
+
+	tlsConf, err := conf.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := nats.GetDefaultOptions()
+	opts.Servers = servers
+	opts.NoRandomize = true
+	opts.User = conf.Username
+	opts.Password = conf.Password
+	opts.Token = conf.Token
+	opts.Timeout = conf.ConnectTimeout
+	opts.FlusherTimeout = conf.WriteTimeout
+	opts.ReconnectWait = conf.ReconnectWait
+	opts.MaxReconnect = conf.MaxReconnects
+	if tlsConf != nil {
+		opts.Secure = true
+		opts.TLSConfig = tlsConf
+	}
+	opts.DisconnectedErrCB = func(nc *nats.Conn, err error) {
+		if err != nil {
+			log.Warn("nats: disconnected: %s", err)
+		}
+	}
+	opts.ReconnectedCB = func(nc *nats.Conn) {
+		log.Info("nats: reconnected to %s", nc.ConnectedUrl())
+	}
+	opts.AsyncErrorCB = func(nc *nats.Conn, sub *nats.Subscription, err error) {
+		// 订阅的handler处理不过来时, 超出pending限制的消息被丢弃
+		if sub != nil {
+			log.Warn("nats: subscription %s: %s", sub.Subject, err)
+			return
+		}
+		log.Warn("nats: %s", err)
+	}
+
+	return &natsBroker{conf: conf, opts: opts}, nil
+}
+
+func (b *natsBroker) Connect() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	if b.conn != nil {
+		return nil
+	}
+
+	conn, err := b.opts.Connect()
+	if err != nil {
+		return err
+	}
+	b.conn = conn
+	return nil
+}
+
+func (b *natsBroker) getConn() (*nats.Conn, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+	if b.conn == nil {
+		return nil, ErrNotConnected
+	}
+	return b.conn, nil
+}
+
+// flush waits for a PING/PONG round trip, ctx without deadline is bounded by write_timeout.
+func (b *natsBroker) flush(ctx context.Context, conn *nats.Conn) error {
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := b.conf.WriteTimeout
+		if timeout <= 0 {
+			timeout = defaultBrokerConfig.WriteTimeout
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return b.convertError(conn.FlushWithContext(ctx))
+}
+
+// Publish writes the message to the buffer of the connection, which is flushed in the background.
+// With sync_publish it returns after the server received the message or ctx is done.
+// Messages published while reconnecting are buffered and sent after the reconnect.
+func (b *natsBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("Invalid subject: %q", subject)
+	}
+
+	conn, err := b.getConn()
+	if err != nil {
+		return err
+	}
+	if err = conn.Publish(subject, data); err != nil {
+		return b.convertError(err)
+	}
+	if !b.conf.SyncPublish {
+		return nil
+	}
+	return b.flush(ctx, conn)
+}
+
+func (b *natsBroker) Subscribe(subject, queue string, handler Handler) (Subscription, error) {
+	conn, err := b.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := conn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+		handler(&Message{Subject: msg.Subject, Reply: msg.Reply, Data: msg.Data})
+	})
+	if err != nil {
+		return nil, b.convertError(err)
+	}
+
+	if b.conf.PendingMsgs != 0 || b.conf.PendingBytes != 0 {
+		msgs, bytes := b.conf.PendingMsgs, b.conf.PendingBytes
+		if msgs == 0 {
+			msgs = nats.DefaultSubPendingMsgsLimit
+		}
+		if bytes == 0 {
+			bytes = nats.DefaultSubPendingBytesLimit
+		}
+		if err = sub.SetPendingLimits(msgs, bytes); err != nil {
+			_ = sub.Unsubscribe()
+			return nil, err
+		}
+	}
+	return &natsSubscription{sub: sub}, nil
+}
+
+func (b *natsBroker) Ping(ctx context.Context) error {
+	conn, err := b.getConn()
+	if err != nil {
+		return err
+	}
+	if !conn.IsConnected() {
+		return ErrNotConnected
+	}
+	return b.flush(ctx, conn)
+}
+
+func (b *natsBroker) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	if b.conn != nil {
+		// Close把缓冲区的数据发送出去后才断开连接
+		b.conn.Close()
+	}
+	return nil
+}
+
+func (b *natsBroker) convertError(err error) error {
+	if err == nats.ErrConnectionClosed {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if b.closed {
+			return ErrBrokerClosed
+		}
+	}
+	return err
+}
+
+type natsSubscription struct {
+	sub *nats.Subscription
+}
+
+func (s *natsSubscription) Unsubscribe() error {
+	err := s.sub.Unsubscribe()
+	if err == nats.ErrConnectionClosed || err == nats.ErrBadSubscription {
+		return nil
+	}
+	return err
+}
+
+func (s *natsSubscription) Dropped() int {
+	n, err := s.sub.Dropped()
+	if err != nil {
+		// 取消订阅后无法再读取计数
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	publisherFactory       component.Factory       = NewPublisherFactory()
+	_                      component.Component     = &PublisherComponent{}
+	_                      component.HealthChecker = &PublisherComponent{}
+	_                      Publisher               = &PublisherComponent{}
+	defaultPublisherConfig                         = PublisherConfig{
+		Name:   "pubsub_publisher",
+		Broker: defaultBrokerConfig,
+	}
+	publisherDescription = "publisher of a pluggable broker (memory://name, nats://host:4222), " +
+		"injects pubsub.Publisher"
+)
+
+func init() {
+	if err := component.Register("pubsub_publisher", publisherFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewPublisherFactory() component.Factory {
+	return component.NewFactory(
+		defaultPublisherConfig,
+		publisherDescription,
+		inject.InterfaceOf((*Publisher)(nil)),
+		func(c string) (component.Component, error) {
+			return NewPublisherComponent(c)
+		})
+}
+
+type PublisherConfig struct {
+	Name   string       `yaml:"name"`
+	Broker BrokerConfig `yaml:"broker"`
+}
+
+func (c PublisherConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type PublisherComponent struct {
+	config   PublisherConfig
+	broker   Broker
+	instance component.Instance
+}
+
+func NewPublisherComponent(rawConfig string) (*PublisherComponent, error) {
+	conf := defaultPublisherConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Name == "" {
+		return nil, errors.New("Component:pubsub_publisher name cannot be empty")
+	}
+
+	log.Info("Pubsub publisher config: %s %s", conf.Name, conf.Broker)
+
+	broker, err := NewBroker(conf.Broker)
+	if err != nil {
+		return nil, errors.Wrap(err, "pubsub_publisher")
+	}
+
+	p := &PublisherComponent{
+		config: conf,
+		broker: broker,
+	}
+	p.instance = component.NewInstance(
+		conf.Name,
+		inject.InterfaceOf((*Publisher)(nil)),
+		reflect.ValueOf(p),
+		p,
+	)
+	return p, nil
+}
+
+func (p *PublisherComponent) Instance() component.Instance {
+	return p.instance
+}
+
+func (p *PublisherComponent) Publish(ctx context.Context, subject string, data []byte) error {
+	return p.broker.Publish(ctx, subject, data)
+}
+
+func (p *PublisherComponent) Start() error {
+	return errors.Wrap(p.broker.Connect(), "pubsub_publisher")
+}
+
+func (p *PublisherComponent) HealthCheck(ctx context.Context) error {
+	return p.broker.Ping(ctx)
+}
+
+func (p *PublisherComponent) Stop() error {
+	return p.broker.Close()
+}
@@ -0,0 +1,377 @@
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/common/monitor"
+)
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		match   bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.created", "orders.deleted", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.>", "orders.created.eu", true},
+		{"orders.>", "orders", false},
+		{"*.created", "users.created", true},
+		{"orders", "orders.created", false},
+	}
+
+	for _, test := range tests {
+		if got := matchSubject(test.pattern, test.subject); got != test.match {
+			t.Errorf("matchSubject(%s, %s) Expected %v - Got %v", test.pattern, test.subject, test.match, got)
+		}
+	}
+}
+
+func newTestComponents(t *testing.T, brokerConfig, subscriberConfig string) (*PublisherComponent, *SubscriberComponent) {
+	pub, err := NewPublisherComponent(brokerConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := NewSubscriberComponent(brokerConfig + "\n" + subscriberConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sub.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sub.Stop() })
+	if err = pub.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pub.Stop() })
+	return pub, sub
+}
+
+func receive(t *testing.T, sub Subscriber) *Message {
+	t.Helper()
+
+	select {
+	case msg, ok := <-sub.Messages():
+		if !ok {
+			t.Fatal("Messages closed")
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+	return nil
+}
+
+func TestMemoryPubSub(t *testing.T) {
+	pub, sub := newTestComponents(t, "broker: {url: 'memory://test_memory'}", "subjects: [orders.*]")
+
+	ctx := context.Background()
+	if err := pub.Publish(ctx, "users.created", []byte("skip")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Publish(ctx, "orders.created", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := receive(t, sub)
+	if msg.Subject != "orders.created" || string(msg.Data) != "1" {
+		t.Fatalf("Expected orders.created 1 - Got %s %s", msg.Subject, msg.Data)
+	}
+
+	if err := pub.HealthCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sub.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-sub.Messages(); ok {
+		t.Fatal("Expected Messages closed after stop")
+	}
+	if err := sub.HealthCheck(ctx); err != ErrBrokerClosed {
+		t.Fatalf("Expected %v - Got %v", ErrBrokerClosed, err)
+	}
+}
+
+func TestMemoryQueue(t *testing.T) {
+	pub, a := newTestComponents(t, "broker: {url: 'memory://test_queue'}", "subjects: [jobs]\nqueue: workers")
+	_, b := newTestComponents(t, "broker: {url: 'memory://test_queue'}", "subjects: [jobs]\nqueue: workers")
+
+	for i := 0; i < 4; i++ {
+		if err := pub.Publish(context.Background(), "jobs", []byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(a.Messages()) != 2 || len(b.Messages()) != 2 {
+		t.Fatalf("Expected 2 messages of each subscriber - Got %d, %d", len(a.Messages()), len(b.Messages()))
+	}
+}
+
+func TestNATSPubSub(t *testing.T) {
+	server := newFakeNATSServer(t)
+
+	pub, sub := newTestComponents(t,
+		"broker: {url: 'nats://"+server.addr()+"', reconnect_wait: 10ms, sync_publish: true}",
+		"subjects: [orders.>]\nqueue: q")
+
+	ctx := context.Background()
+	if err := pub.Publish(ctx, "orders.created.eu", []byte("hello\r\nworld")); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := receive(t, sub)
+	if msg.Subject != "orders.created.eu" || string(msg.Data) != "hello\r\nworld" {
+		t.Fatalf("Expected orders.created.eu hello world - Got %s %q", msg.Subject, msg.Data)
+	}
+
+	if err := sub.HealthCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 断线后重连并且重新订阅
+	server.dropConnections()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.subscriptions("orders.>") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for resubscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 发布者的连接也被断开了, 等待它重连
+	for pub.HealthCheck(ctx) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for reconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := pub.Publish(ctx, "orders.deleted", []byte("again")); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = receive(t, sub)
+	if string(msg.Data) != "again" {
+		t.Fatalf("Expected again - Got %s", msg.Data)
+	}
+}
+
+func TestNATSSlowConsumer(t *testing.T) {
+	server := newFakeNATSServer(t)
+
+	pub, sub := newTestComponents(t,
+		"broker: {url: 'nats://"+server.addr()+"', pending_msgs: 2}",
+		"subjects: [jobs]\nbuffer_size: 0")
+	m := monitor.NewMonitor("test_slow_consumer")
+	sub.SetMonitor(m)
+
+	// 没有人读取Messages, 投递阻塞在handler中, 后续的消息超过pending_msgs后被丢弃
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if err := pub.Publish(ctx, "jobs", []byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 读循环没有被阻塞, PING/PONG正常
+	timeout, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := sub.HealthCheck(timeout); err != nil {
+		t.Fatal(err)
+	}
+	if v := m.Get(METRICS_KEY_SUBSCRIBER_DROPPED_COUNT); v == nil || v.String() == "0" {
+		t.Fatalf("Expected dropped messages - Got %v", v)
+	}
+
+	msg := receive(t, sub)
+	if string(msg.Data) != "0" {
+		t.Fatalf("Expected 0 - Got %s", msg.Data)
+	}
+}
+
+func TestNATSAuthError(t *testing.T) {
+	server := newFakeNATSServer(t)
+	server.token = "secret"
+
+	pub, err := NewPublisherComponent("broker:\n  url: nats://" + server.addr() + "\n  token: wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pub.Start(); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatalf("Expected Authorization Violation - Got %v", err)
+	}
+
+	pub, err = NewPublisherComponent("broker:\n  url: nats://" + server.addr() + "\n  token: secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pub.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pub.Stop()
+}
+
+// fakeNATSServer implements the subset of the nats protocol used by natsBroker.
+type fakeNATSServer struct {
+	l     net.Listener
+	token string
+
+	lock  sync.Mutex
+	conns map[net.Conn]*fakeNATSConn
+}
+
+type fakeNATSConn struct {
+	lock sync.Mutex
+	conn net.Conn
+	subs map[string]string // key: sid, value: subject
+}
+
+func newFakeNATSServer(t *testing.T) *fakeNATSServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeNATSServer{l: l, conns: map[net.Conn]*fakeNATSConn{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	t.Cleanup(func() {
+		l.Close()
+		s.dropConnections()
+	})
+	return s
+}
+
+func (s *fakeNATSServer) addr() string {
+	return s.l.Addr().String()
+}
+
+func (s *fakeNATSServer) dropConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+func (s *fakeNATSServer) subscriptions(subject string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := 0
+	for _, c := range s.conns {
+		c.lock.Lock()
+		for _, sub := range c.subs {
+			if sub == subject {
+				n++
+			}
+		}
+		c.lock.Unlock()
+	}
+	return n
+}
+
+func (c *fakeNATSConn) write(format string, args ...interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	fmt.Fprintf(c.conn, format, args...)
+}
+
+func (s *fakeNATSServer) serve(conn net.Conn) {
+	c := &fakeNATSConn{conn: conn, subs: map[string]string{}}
+	s.lock.Lock()
+	s.conns[conn] = c
+	s.lock.Unlock()
+
+	c.write("INFO {\"server_id\":\"fake\",\"max_payload\":1048576}\r\n")
+
+	br := bufio.NewReader(conn)
+	for {
+		line, err := readLine(br)
+		if err != nil {
+			return
+		}
+
+		op, args := splitOp(line)
+		fields := strings.Fields(args)
+		switch op {
+		case "CONNECT":
+			if s.token != "" && !strings.Contains(args, `"auth_token":"`+s.token+`"`) {
+				c.write("-ERR 'Authorization Violation'\r\n")
+				conn.Close()
+				return
+			}
+		case "PING":
+			c.write("PONG\r\n")
+		case "SUB":
+			c.lock.Lock()
+			c.subs[fields[len(fields)-1]] = fields[0]
+			c.lock.Unlock()
+		case "UNSUB":
+			c.lock.Lock()
+			delete(c.subs, fields[0])
+			c.lock.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err = io.ReadFull(br, payload); err != nil {
+				return
+			}
+			s.deliver(fields[0], payload[:size])
+		}
+	}
+}
+
+func (s *fakeNATSServer) deliver(subject string, data []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, c := range s.conns {
+		c.lock.Lock()
+		for sid, pattern := range c.subs {
+			if matchSubject(pattern, subject) {
+				fmt.Fprintf(c.conn, "MSG %s %s %d\r\n%s\r\n", subject, sid, len(data), data)
+			}
+		}
+		c.lock.Unlock()
+	}
+}
+
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func splitOp(line string) (string, string) {
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return strings.ToUpper(line), ""
+	}
+	return strings.ToUpper(line[:i]), strings.TrimSpace(line[i+1:])
+}
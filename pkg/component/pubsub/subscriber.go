@@ -0,0 +1,210 @@
+package pubsub
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	METRICS_KEY_SUBSCRIBER_DROPPED_COUNT = "_subscriber_dropped_count"
+)
+
+var (
+	subscriberFactory       component.Factory       = NewSubscriberFactory()
+	_                       component.Component     = &SubscriberComponent{}
+	_                       component.HealthChecker = &SubscriberComponent{}
+	_                       component.MonitorSetter = &SubscriberComponent{}
+	_                       Subscriber              = &SubscriberComponent{}
+	defaultSubscriberConfig                         = SubscriberConfig{
+		Name:       "pubsub_subscriber",
+		Broker:     defaultBrokerConfig,
+		Subjects:   []string{"lotus.>"},
+		BufferSize: 256,
+	}
+	subscriberDescription = "subscriber of a pluggable broker (memory://name, nats://host:4222), " +
+		"injects pubsub.Subscriber"
+)
+
+func init() {
+	if err := component.Register("pubsub_subscriber", subscriberFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewSubscriberFactory() component.Factory {
+	return component.NewFactory(
+		defaultSubscriberConfig,
+		subscriberDescription,
+		inject.InterfaceOf((*Subscriber)(nil)),
+		func(c string) (component.Component, error) {
+			return NewSubscriberComponent(c)
+		})
+}
+
+type SubscriberConfig struct {
+	Name       string       `yaml:"name"`
+	Broker     BrokerConfig `yaml:"broker"`
+	Subjects   []string     `yaml:"subjects"`    // 支持通配符, *匹配一个token, >匹配剩余的所有token
+	Queue      string       `yaml:"queue"`       // 同一个queue的订阅者分摊消息, 为空时每个订阅者都收到全部消息
+	BufferSize int          `yaml:"buffer_size"` // Messages的channel缓冲区大小
+}
+
+func (c SubscriberConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type SubscriberComponent struct {
+	config   SubscriberConfig
+	broker   Broker
+	instance component.Instance
+	monitor  monitor.Monitor
+
+	droppedLock sync.Mutex
+	dropped     int // 已经上报到monitor的丢弃消息数
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock   sync.RWMutex
+	closed bool
+	msgs   chan *Message
+	subs   []Subscription
+}
+
+func NewSubscriberComponent(rawConfig string) (*SubscriberComponent, error) {
+	conf := defaultSubscriberConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Name == "" {
+		return nil, errors.New("Component:pubsub_subscriber name cannot be empty")
+	}
+	if len(conf.Subjects) == 0 {
+		return nil, errors.New("Component:pubsub_subscriber subjects cannot be empty")
+	}
+	if conf.BufferSize < 0 {
+		conf.BufferSize = 0
+	}
+
+	log.Info("Pubsub subscriber config: %s %s subjects: %v queue: %s",
+		conf.Name, conf.Broker, conf.Subjects, conf.Queue)
+
+	broker, err := NewBroker(conf.Broker)
+	if err != nil {
+		return nil, errors.Wrap(err, "pubsub_subscriber")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SubscriberComponent{
+		config:  conf,
+		broker:  broker,
+		monitor: monitor.NewMonitor(conf.Name),
+		ctx:     ctx,
+		cancel:  cancel,
+		msgs:    make(chan *Message, conf.BufferSize),
+	}
+	s.instance = component.NewInstance(
+		conf.Name,
+		inject.InterfaceOf((*Subscriber)(nil)),
+		reflect.ValueOf(s),
+		s,
+	)
+	return s, nil
+}
+
+func (s *SubscriberComponent) Instance() component.Instance {
+	return s.instance
+}
+
+// Messages is closed after the component stopped.
+func (s *SubscriberComponent) Messages() <-chan *Message {
+	return s.msgs
+}
+
+func (s *SubscriberComponent) SetMonitor(m monitor.Monitor) {
+	s.monitor = m
+}
+
+// handle blocks until the message is consumed, messages arriving meanwhile
+// queue up in the broker subscription and are dropped when it is full.
+func (s *SubscriberComponent) handle(msg *Message) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.msgs <- msg:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *SubscriberComponent) Start() error {
+	if err := s.broker.Connect(); err != nil {
+		return errors.Wrap(err, "pubsub_subscriber")
+	}
+
+	for _, subject := range s.config.Subjects {
+		sub, err := s.broker.Subscribe(subject, s.config.Queue, s.handle)
+		if err != nil {
+			return errors.Wrapf(err, "pubsub_subscriber: %s", subject)
+		}
+		s.subs = append(s.subs, sub)
+	}
+	return nil
+}
+
+// HealthCheck also reports the messages dropped since the last check to the monitor.
+func (s *SubscriberComponent) HealthCheck(ctx context.Context) error {
+	// PONG在之前收到的消息之后返回, 先Ping保证丢弃计数包含了这些消息
+	err := s.broker.Ping(ctx)
+
+	// 不能用s.lock, 阻塞在投递中的handler持有读锁
+	s.droppedLock.Lock()
+	defer s.droppedLock.Unlock()
+
+	dropped := 0
+	for _, sub := range s.subs {
+		dropped += sub.Dropped()
+	}
+	if delta := dropped - s.dropped; delta > 0 {
+		s.monitor.Add(METRICS_KEY_SUBSCRIBER_DROPPED_COUNT, int64(delta))
+		log.Warn("pubsub_subscriber: %s dropped %d messages, the consumer is slower than the broker",
+			s.config.Name, delta)
+		s.dropped = dropped
+	}
+	return err
+}
+
+func (s *SubscriberComponent) Stop() error {
+	// 先唤醒阻塞在投递中的handler, 再关闭channel
+	s.cancel()
+
+	for _, sub := range s.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			log.Warn("pubsub_subscriber: %s unsubscribe error: %s", s.config.Name, err)
+		}
+	}
+	err := s.broker.Close()
+
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.msgs)
+	}
+	s.lock.Unlock()
+
+	return err
+}
@@ -1,26 +1,48 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/inject"
 	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/component"
 	"gopkg.in/yaml.v2"
 
 	"github.com/go-redis/redis"
+
+	utiltls "github.com/shima-park/lotus/pkg/util/tls"
+)
+
+const (
+	ModeSingle   = "single"
+	ModeSentinel = "sentinel"
+	ModeCluster  = "cluster"
 )
 
 var (
-	factory       component.Factory   = NewFactory()
-	_             component.Component = &Client{}
-	defaultConfig                     = Config{
-		Name:     "redis_client",
-		Addr:     "127.0.0.1:18000",
-		Password: "",
-		DB:       0,
-		PoolSize: 5,
+	factory       component.Factory           = NewFactory()
+	_             component.Component         = &Client{}
+	_             component.HealthChecker     = &Client{}
+	_             component.ProviderRegistrar = &Client{}
+	defaultConfig                             = Config{
+		Name:         "redis_client",
+		Mode:         ModeSingle,
+		Addr:         "127.0.0.1:18000",
+		Password:     "",
+		DB:           0,
+		PoolSize:     5,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
 	}
-	description = "redis client factory"
+	description = "redis client factory, injects redis.UniversalClient in all modes, " +
+		"single mode also injects *redis.Client"
 )
 
 func init() {
@@ -33,31 +55,104 @@ func NewFactory() component.Factory {
 	return component.NewFactory(
 		defaultConfig,
 		description,
-		reflect.TypeOf(&redis.Client{}),
+		inject.InterfaceOf((*redis.UniversalClient)(nil)),
 		func(c string) (component.Component, error) {
 			return NewClient(c)
 		})
 }
 
 type Config struct {
-	Name     string `yaml:"name"`
-	Addr     string `yaml:"addr"`
-	Password string `yaml:"password"`
-	DB       int    `yaml:"db"`
-	PoolSize int    `yaml:"pool_size"`
+	Name       string   `yaml:"name"`
+	Mode       string   `yaml:"mode"`                  // single, sentinel, cluster
+	Addr       string   `yaml:"addr,omitempty"`        // single模式的地址
+	Addrs      []string `yaml:"addrs,omitempty"`       // sentinel或者cluster模式的节点地址
+	MasterName string   `yaml:"master_name,omitempty"` // sentinel模式的master名称
+	Password   string   `yaml:"password"`
+	DB         int      `yaml:"db"` // cluster模式不支持选择db
+	PoolSize   int      `yaml:"pool_size"`
+
+	MinIdleConns int           `yaml:"min_idle_conns,omitempty"`
+	MaxRetries   int           `yaml:"max_retries,omitempty"`
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	PoolTimeout  time.Duration `yaml:"pool_timeout,omitempty"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout,omitempty"`
+
+	// 只在cluster模式下生效
+	ReadOnly       bool `yaml:"read_only,omitempty"`
+	RouteByLatency bool `yaml:"route_by_latency,omitempty"`
+	RouteRandomly  bool `yaml:"route_randomly,omitempty"`
+
+	TLS utiltls.Config `yaml:"tls"`
 }
 
 func (c Config) Marshal() ([]byte, error) {
 	return yaml.Marshal(c)
 }
 
+func (c Config) options(addr string, tlsConf *tls.Config) *redis.Options {
+	return &redis.Options{
+		Addr:         addr,
+		Password:     c.Password,
+		DB:           c.DB,
+		MaxRetries:   c.MaxRetries,
+		DialTimeout:  c.DialTimeout,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		PoolSize:     c.PoolSize,
+		MinIdleConns: c.MinIdleConns,
+		PoolTimeout:  c.PoolTimeout,
+		IdleTimeout:  c.IdleTimeout,
+		TLSConfig:    tlsConf,
+	}
+}
+
+func (c Config) failoverOptions(tlsConf *tls.Config) *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:    c.MasterName,
+		SentinelAddrs: c.Addrs,
+		Password:      c.Password,
+		DB:            c.DB,
+		MaxRetries:    c.MaxRetries,
+		DialTimeout:   c.DialTimeout,
+		ReadTimeout:   c.ReadTimeout,
+		WriteTimeout:  c.WriteTimeout,
+		PoolSize:      c.PoolSize,
+		MinIdleConns:  c.MinIdleConns,
+		PoolTimeout:   c.PoolTimeout,
+		IdleTimeout:   c.IdleTimeout,
+		TLSConfig:     tlsConf,
+	}
+}
+
+func (c Config) clusterOptions(tlsConf *tls.Config) *redis.ClusterOptions {
+	return &redis.ClusterOptions{
+		Addrs:          c.Addrs,
+		ReadOnly:       c.ReadOnly,
+		RouteByLatency: c.RouteByLatency,
+		RouteRandomly:  c.RouteRandomly,
+		Password:       c.Password,
+		MaxRetries:     c.MaxRetries,
+		DialTimeout:    c.DialTimeout,
+		ReadTimeout:    c.ReadTimeout,
+		WriteTimeout:   c.WriteTimeout,
+		PoolSize:       c.PoolSize,
+		MinIdleConns:   c.MinIdleConns,
+		PoolTimeout:    c.PoolTimeout,
+		IdleTimeout:    c.IdleTimeout,
+		TLSConfig:      tlsConf,
+	}
+}
+
 type Client struct {
-	c        *redis.Client
+	config   Config
+	c        redis.UniversalClient
 	instance component.Instance
 }
 
 func NewClient(rawConfig string) (*Client, error) {
-	var conf Config
+	conf := defaultConfig
 	err := yaml.Unmarshal([]byte(rawConfig), &conf)
 	if err != nil {
 		return nil, err
@@ -65,18 +160,44 @@ func NewClient(rawConfig string) (*Client, error) {
 
 	log.Info("Redis config: %+v", conf)
 
-	c := redis.NewClient(&redis.Options{
-		Addr:     conf.Addr,
-		Password: conf.Password,
-		DB:       conf.DB,
-		PoolSize: conf.PoolSize,
-	})
+	tlsConf, err := conf.TLS.TLSConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "redis_client")
+	}
+
+	var c redis.UniversalClient
+	switch strings.ToLower(conf.Mode) {
+	case ModeSingle, "":
+		addr := conf.Addr
+		if addr == "" && len(conf.Addrs) > 0 {
+			addr = conf.Addrs[0]
+		}
+		if addr == "" {
+			return nil, errors.New("Component:redis_client addr can not be empty in single mode")
+		}
+
+		c = redis.NewClient(conf.options(addr, tlsConf))
+	case ModeSentinel:
+		if conf.MasterName == "" || len(conf.Addrs) == 0 {
+			return nil, errors.New("Component:redis_client master_name and addrs are required in sentinel mode")
+		}
+		c = redis.NewFailoverClient(conf.failoverOptions(tlsConf))
+	case ModeCluster:
+		if len(conf.Addrs) == 0 {
+			return nil, errors.New("Component:redis_client addrs are required in cluster mode")
+		}
+		c = redis.NewClusterClient(conf.clusterOptions(tlsConf))
+	default:
+		return nil, fmt.Errorf("Component:redis_client unsupported mode: %s, supported modes: %s, %s, %s",
+			conf.Mode, ModeSingle, ModeSentinel, ModeCluster)
+	}
 
 	return &Client{
-		c: c,
+		config: conf,
+		c:      c,
 		instance: component.NewInstance(
 			conf.Name,
-			reflect.TypeOf(c),
+			inject.InterfaceOf((*redis.UniversalClient)(nil)),
 			reflect.ValueOf(c),
 			c,
 		),
@@ -87,10 +208,37 @@ func (c *Client) Instance() component.Instance {
 	return c.instance
 }
 
+// RegisterProviders also maps the *redis.Client of single mode by the same name,
+// so the processors which inject *redis.Client keep working.
+func (c *Client) RegisterProviders(m inject.TypeMapper) error {
+	if client, ok := c.c.(*redis.Client); ok {
+		m.Set(reflect.TypeOf(client), c.config.Name, reflect.ValueOf(client))
+	}
+	return nil
+}
+
+// Start pings the server, so an unreachable server fails the pipeline instead of the first processor.
 func (c *Client) Start() error {
+	if err := c.c.Ping().Err(); err != nil {
+		return errors.Wrapf(err, "Failed to ping redis: %s", c.config.Name)
+	}
 	return nil
 }
 
+func (c *Client) HealthCheck(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.c.Ping().Err()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Client) Stop() error {
 	return c.c.Close()
 }
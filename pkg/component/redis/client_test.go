@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+)
+
+// newPongServer starts a server which replies PONG to every command.
+func newPongServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					// 只有命令名称所在的行需要回复, 例如: *1\r\n$4\r\nPING\r\n
+					if strings.HasPrefix(strings.ToUpper(line), "PING") {
+						conn.Write([]byte("+PONG\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDefaultConfig(t *testing.T) {
+	c, err := NewClient("name: my_redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	if c.config.PoolSize != defaultConfig.PoolSize || c.config.Addr != defaultConfig.Addr {
+		t.Fatalf("Expected the default config to be used, got: %+v", c.config)
+	}
+	universal := inject.InterfaceOf((*redis.UniversalClient)(nil))
+	if c.instance.Type() != universal || factory.ExampleType() != universal {
+		t.Fatalf("Expected redis.UniversalClient to be injected in single mode, got: %s", c.instance.Type())
+	}
+
+	// 兼容注入*redis.Client的processor, 按类型注入redis.UniversalClient时不会有歧义
+	inj := inject.New()
+	inj.Set(c.instance.Type(), c.instance.Name(), c.instance.Value())
+	if err = c.RegisterProviders(inj); err != nil {
+		t.Fatal(err)
+	}
+	if v := inj.Get(reflect.TypeOf(&redis.Client{}), "my_redis"); !v.IsValid() || v.Interface() != c.instance.Interface() {
+		t.Fatalf("Expected *redis.Client to be mapped in single mode, got: %v", v)
+	}
+	if _, err = inj.GetByType(universal); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModes(t *testing.T) {
+	for _, conf := range []string{
+		"mode: sentinel\naddrs: [127.0.0.1:26379]",
+		"mode: cluster",
+		"mode: unknown",
+	} {
+		if _, err := NewClient(conf); err == nil {
+			t.Fatalf("Expected an error for config: %s", conf)
+		}
+	}
+
+	c, err := NewClient("mode: cluster\naddrs: [127.0.0.1:7000]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	if _, ok := c.instance.Interface().(*redis.ClusterClient); !ok {
+		t.Fatalf("Expected *redis.ClusterClient in cluster mode, got: %T", c.instance.Interface())
+	}
+	if c.instance.Type().Kind() != reflect.Interface {
+		t.Fatalf("Expected redis.UniversalClient to be injected in cluster mode, got: %s", c.instance.Type())
+	}
+}
+
+func TestStartPing(t *testing.T) {
+	l := newPongServer(t)
+	defer l.Close()
+
+	c, err := NewClient("addr: " + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.HealthCheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 获取一个已经关闭的端口
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	c2, err := NewClient("addr: " + closed.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Stop()
+
+	if err = c2.Start(); err == nil {
+		t.Fatal("Expected an error when the server is unreachable")
+	}
+	if err = c2.HealthCheck(context.Background()); err == nil {
+		t.Fatal("Expected the health check to fail when the server is unreachable")
+	}
+}
@@ -3,6 +3,8 @@ package component
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/schema"
 )
 
 type Factory interface {
@@ -21,6 +23,29 @@ type Factory interface {
 
 type FactoryFunc func(config string) (Component, error)
 
+// SchemaFactory is implemented by the factories whose config is described by a JSON Schema,
+// the factories created by NewFactory with a sample config struct implement it.
+type SchemaFactory interface {
+	// 配置的JSON Schema, 没有时返回nil
+	Schema() *schema.Schema
+}
+
+// SchemaOf returns the schema of the factory, or nil if it has none.
+func SchemaOf(factory Factory) *schema.Schema {
+	if sf, ok := factory.(SchemaFactory); ok {
+		return sf.Schema()
+	}
+	return nil
+}
+
+// ValidateConfig validates the raw config by the schema of the factory before New.
+func ValidateConfig(factory Factory, rawConfig string) error {
+	if s := SchemaOf(factory); s != nil {
+		return s.Validate(rawConfig)
+	}
+	return nil
+}
+
 var registry = make(map[string]Factory)
 
 func Register(name string, factory Factory) error {
@@ -0,0 +1,355 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+)
+
+// SharedConfig 服务级别的共享组件声明, 同一个进程中的各个pipeline通过Name引用同一个组件实例.
+// 引用共享组件的executor运行在服务进程中, 其他executor运行在各自的子进程中.
+// 推送输入的Source组件不能共享, 每个输入只能驱动一个pipeline
+type SharedConfig struct {
+	Name      string `yaml:"name" json:"name"`           // 引用共享组件时使用的名字
+	Component string `yaml:"component" json:"component"` // 组件工厂名, 例如: kafka_producer
+	RawConfig string `yaml:"config" json:"config"`       // 组件配置
+}
+
+// SharedState 共享组件当前的引用情况
+type SharedState struct {
+	SharedConfig
+	Refs    int  // 当前引用该组件的pipeline数量
+	Started bool // 组件实例是否已经启动
+}
+
+type shared struct {
+	config    SharedConfig
+	factory   Factory
+	component Component // 第一次被引用时创建, 引用数归零后停止并释放
+	monitor   *sharedMonitor
+	refs      int
+	running   bool // 实例是否已经启动, 和引用的启动次数无关
+}
+
+var sharedRegistry = struct {
+	sync.Mutex
+	m map[string]*shared
+}{m: map[string]*shared{}}
+
+// DeclareShared declares a shared component, the instance is created when it is referenced first.
+func DeclareShared(conf SharedConfig) error {
+	if conf.Name == "" {
+		return fmt.Errorf("Error declaring shared component: name cannot be empty")
+	}
+
+	factory, err := GetFactory(conf.Component)
+	if err != nil {
+		return err
+	}
+	if err = ValidateConfig(factory, conf.RawConfig); err != nil {
+		return fmt.Errorf("Error declaring shared component '%v': %s", conf.Name, err)
+	}
+
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	if _, exists := sharedRegistry.m[conf.Name]; exists {
+		return fmt.Errorf("Error declaring shared component '%v': already declared", conf.Name)
+	}
+
+	sharedRegistry.m[conf.Name] = &shared{
+		config:  conf,
+		factory: factory,
+	}
+	return nil
+}
+
+// UndeclareShared removes the declaration, it fails while the component is still referenced.
+func UndeclareShared(name string) error {
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	s, exists := sharedRegistry.m[name]
+	if !exists {
+		return fmt.Errorf("No such shared component: '%v'", name)
+	}
+	if s.refs > 0 {
+		return fmt.Errorf("Shared component '%v' is still referenced by %d pipelines", name, s.refs)
+	}
+
+	delete(sharedRegistry.m, name)
+	return nil
+}
+
+// GetShared returns the declaration of the shared component.
+func GetShared(name string) (SharedConfig, error) {
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	s, exists := sharedRegistry.m[name]
+	if !exists {
+		return SharedConfig{}, fmt.Errorf("No such shared component: '%v'", name)
+	}
+	return s.config, nil
+}
+
+// ListShared returns the declared shared components sorted by name.
+func ListShared() []SharedState {
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	var list []SharedState
+	for _, s := range sharedRegistry.m {
+		list = append(list, SharedState{
+			SharedConfig: s.config,
+			Refs:         s.refs,
+			Started:      s.running,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// AcquireShared returns a reference of the shared component.
+// Start of the reference starts the instance only once, Stop releases the reference,
+// the instance is stopped after the last reference is released.
+func AcquireShared(name string) (Component, Factory, error) {
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	s, exists := sharedRegistry.m[name]
+	if !exists {
+		return nil, nil, fmt.Errorf("No such shared component: '%v'", name)
+	}
+
+	if s.component == nil {
+		c, err := s.factory.New(s.config.RawConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := c.(Source); ok {
+			_ = c.Stop()
+			return nil, nil, fmt.Errorf("Shared component '%v': a source component cannot be shared", name)
+		}
+
+		// 实例的监控指标上报到所有引用它的pipeline
+		s.monitor = newSharedMonitor(name)
+		if ms, ok := c.(MonitorSetter); ok {
+			ms.SetMonitor(s.monitor)
+		}
+		s.component = c
+	}
+	s.refs++
+
+	ref := &sharedRef{shared: s, component: s.component, monitor: s.monitor}
+	if _, ok := s.component.(HealthChecker); ok {
+		return &sharedHealthRef{ref}, s.factory, nil
+	}
+	return ref, s.factory, nil
+}
+
+type sharedRef struct {
+	shared    *shared
+	component Component // 引用的实例, 释放后依然可以获取Instance
+	monitor   *sharedMonitor
+	lock      sync.Mutex
+	started   bool
+	released  bool
+	target    monitor.Monitor // 引用方设置的监控
+}
+
+func (r *sharedRef) Instance() Instance {
+	return r.component.Instance()
+}
+
+// SetMonitor adds the monitor of the pipeline to the monitors the instance reports to until the reference is released.
+func (r *sharedRef) SetMonitor(m monitor.Monitor) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.released {
+		return
+	}
+	if r.target != nil {
+		r.monitor.remove(r.target)
+	}
+	r.target = m
+	r.monitor.add(m)
+}
+
+func (r *sharedRef) RegisterProviders(m inject.TypeMapper) error {
+	if pr, ok := r.component.(ProviderRegistrar); ok {
+		return pr.RegisterProviders(m)
+	}
+	return nil
+}
+
+func (r *sharedRef) Start() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.started || r.released {
+		return nil
+	}
+
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	// 释放后重新创建的实例不属于这个引用
+	if r.shared.component == r.component && !r.shared.running {
+		if err := r.component.Start(); err != nil {
+			return err
+		}
+		r.shared.running = true
+	}
+	r.started = true
+	return nil
+}
+
+func (r *sharedRef) Stop() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.released {
+		return nil
+	}
+	r.released = true
+	if r.target != nil {
+		r.monitor.remove(r.target)
+	}
+
+	sharedRegistry.Lock()
+	defer sharedRegistry.Unlock()
+
+	r.shared.refs--
+	if r.shared.refs > 0 {
+		return nil
+	}
+
+	// 最后一个引用释放后停止实例, 下次被引用时重新创建.
+	// 没有启动过的实例也要停止, 释放New时打开的资源
+	r.shared.component = nil
+	r.shared.monitor = nil
+	r.shared.running = false
+	return r.component.Stop()
+}
+
+type sharedHealthRef struct {
+	*sharedRef
+}
+
+func (r *sharedHealthRef) HealthCheck(ctx context.Context) error {
+	return r.component.(HealthChecker).HealthCheck(ctx)
+}
+
+// sharedMonitor is the monitor of a shared instance, the metrics are kept in its own monitor
+// and shown in the monitors of all the pipelines referencing it. A pipeline referencing it later
+// gets the metrics set before, e.g. the expvar.Func set by SetMonitor.
+type sharedMonitor struct {
+	own       monitor.Monitor
+	namespace string // With的命名空间, 为空时是根
+	targets   *sharedTargets
+}
+
+type sharedTargets struct {
+	lock sync.RWMutex
+	list []monitor.Monitor
+}
+
+func newSharedMonitor(name string) *sharedMonitor {
+	return &sharedMonitor{own: monitor.NewMonitor(name), targets: &sharedTargets{}}
+}
+
+func (m *sharedMonitor) list() []monitor.Monitor {
+	m.targets.lock.RLock()
+	defer m.targets.lock.RUnlock()
+
+	list := make([]monitor.Monitor, 0, len(m.targets.list))
+	for _, t := range m.targets.list {
+		if m.namespace != "" {
+			t = t.With(m.namespace)
+		}
+		list = append(list, t)
+	}
+	return list
+}
+
+// add shows the metrics in t, m must be the root.
+func (m *sharedMonitor) add(t monitor.Monitor) {
+	m.targets.lock.Lock()
+	defer m.targets.lock.Unlock()
+
+	m.targets.list = append(m.targets.list, t)
+	m.own.Do(func(root, namespace string, kv monitor.KeyValue) {
+		if namespace != root {
+			t.With(namespace).Set(kv.Key, kv.Value)
+			return
+		}
+		t.Set(kv.Key, kv.Value)
+	})
+}
+
+func (m *sharedMonitor) remove(t monitor.Monitor) {
+	m.targets.lock.Lock()
+	defer m.targets.lock.Unlock()
+
+	list := m.targets.list
+	for i := range list {
+		if list[i] == t {
+			m.targets.list = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *sharedMonitor) With(namespace string) monitor.Monitor {
+	return &sharedMonitor{own: m.own.With(namespace), namespace: namespace, targets: m.targets}
+}
+
+// show sets the var of key in the monitors of the pipelines, they share the same var.
+func (m *sharedMonitor) show(key string, v monitor.Var) {
+	for _, t := range m.list() {
+		t.Set(key, v)
+	}
+}
+
+func (m *sharedMonitor) Add(key string, delta int64) {
+	m.own.Add(key, delta)
+	m.show(key, m.own.Get(key))
+}
+
+func (m *sharedMonitor) AddFloat(key string, delta float64) {
+	m.own.AddFloat(key, delta)
+	m.show(key, m.own.Get(key))
+}
+
+func (m *sharedMonitor) Delete(key string) {
+	m.own.Delete(key)
+	for _, t := range m.list() {
+		t.Delete(key)
+	}
+}
+
+func (m *sharedMonitor) Set(key string, av monitor.Var) {
+	m.own.Set(key, av)
+	m.show(key, av)
+}
+
+func (m *sharedMonitor) Do(f func(root, namespace string, kv monitor.KeyValue)) {
+	m.own.Do(f)
+}
+
+func (m *sharedMonitor) Get(key string) monitor.Var {
+	return m.own.Get(key)
+}
+
+func (m *sharedMonitor) String() string {
+	return m.own.String()
+}
@@ -0,0 +1,220 @@
+package component
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/common/monitor"
+)
+
+type countComponent struct {
+	name   string
+	starts int
+	stops  int
+}
+
+func (c *countComponent) Instance() Instance {
+	return NewInstance(c.name, reflect.TypeOf(c), reflect.ValueOf(c), c)
+}
+
+func (c *countComponent) Start() error { c.starts++; return nil }
+
+func (c *countComponent) Stop() error { c.stops++; return nil }
+
+type countFactory struct {
+	created []*countComponent
+}
+
+func (f *countFactory) SampleConfig() string      { return "" }
+func (f *countFactory) Description() string       { return "" }
+func (f *countFactory) ExampleType() reflect.Type { return reflect.TypeOf(&countComponent{}) }
+
+func (f *countFactory) New(rawConfig string) (Component, error) {
+	c := &countComponent{name: rawConfig}
+	f.created = append(f.created, c)
+	return c, nil
+}
+
+func TestShared(t *testing.T) {
+	f := &countFactory{}
+	Register("test_shared_component", f)
+
+	err := DeclareShared(SharedConfig{Name: "shared_a", Component: "test_shared_component", RawConfig: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UndeclareShared("shared_a")
+
+	if err = DeclareShared(SharedConfig{Name: "shared_a", Component: "test_shared_component"}); err == nil {
+		t.Fatal("Expected an error when the shared component is declared twice")
+	}
+	if _, _, err = AcquireShared("not_exists"); err == nil {
+		t.Fatal("Expected an error when the shared component is not declared")
+	}
+
+	r1, _, err := AcquireShared("shared_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, _, err := AcquireShared("shared_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.created) != 1 {
+		t.Fatalf("Expected the instance to be created once, got: %d", len(f.created))
+	}
+	if r1.Instance().Name() != "a" || r2.Instance().Name() != "a" {
+		t.Fatal("Expected the references share the same instance")
+	}
+
+	for _, r := range []Component{r1, r2, r1} {
+		if err = r.Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := f.created[0]
+	if c.starts != 1 {
+		t.Fatalf("Expected the instance to be started once, got: %d", c.starts)
+	}
+
+	state := ListShared()
+	if len(state) != 1 || state[0].Refs != 2 || !state[0].Started {
+		t.Fatalf("Unexpected shared state: %+v", state)
+	}
+	if err = UndeclareShared("shared_a"); err == nil {
+		t.Fatal("Expected an error when the shared component is still referenced")
+	}
+
+	// 重复Stop同一个引用不会多次释放
+	_ = r1.Stop()
+	_ = r1.Stop()
+	if c.stops != 0 {
+		t.Fatal("Expected the instance not to be stopped while it is still referenced")
+	}
+
+	_ = r2.Stop()
+	if c.stops != 1 {
+		t.Fatalf("Expected the instance to be stopped after the last reference is released, got: %d", c.stops)
+	}
+	if r2.Instance().Name() != "a" {
+		t.Fatal("Expected the instance to be accessible after released")
+	}
+
+	state = ListShared()
+	if state[0].Refs != 0 || state[0].Started {
+		t.Fatalf("Unexpected shared state: %+v", state)
+	}
+
+	// 释放后再次引用时重新创建实例
+	r3, _, err := AcquireShared("shared_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.created) != 2 {
+		t.Fatalf("Expected the instance to be recreated, got: %d", len(f.created))
+	}
+	_ = r3.Stop()
+}
+
+func TestSharedStartAfterRelease(t *testing.T) {
+	f := &countFactory{}
+	Register("test_shared_restart", f)
+
+	err := DeclareShared(SharedConfig{Name: "shared_b", Component: "test_shared_restart", RawConfig: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UndeclareShared("shared_b")
+
+	r1, _, _ := AcquireShared("shared_b")
+	r2, _, _ := AcquireShared("shared_b")
+	if err = r1.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// r1释放时实例还被r2引用, 继续运行, r2启动时不能再启动一次
+	_ = r1.Stop()
+	if err = r2.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c := f.created[0]
+	if c.starts != 1 || c.stops != 0 {
+		t.Fatalf("Expected the running instance not to be started again, starts: %d stops: %d", c.starts, c.stops)
+	}
+	if state := ListShared(); !state[0].Started || state[0].Refs != 1 {
+		t.Fatalf("Unexpected shared state: %+v", state)
+	}
+
+	_ = r2.Stop()
+	if c.stops != 1 {
+		t.Fatalf("Expected the instance to be stopped once, got: %d", c.stops)
+	}
+}
+
+type monitorComponent struct {
+	countComponent
+	m monitor.Monitor
+}
+
+func (c *monitorComponent) SetMonitor(m monitor.Monitor) {
+	c.m = m
+	m.Set("name", monitor.String(c.name))
+}
+
+type sourceComponent struct {
+	countComponent
+}
+
+func (c *sourceComponent) SetEmitter(emit Emitter) {}
+func (c *sourceComponent) InputTypes() []Instance  { return nil }
+
+type funcFactory struct {
+	countFactory
+	new func(rawConfig string) Component
+}
+
+func (f *funcFactory) New(rawConfig string) (Component, error) {
+	return f.new(rawConfig), nil
+}
+
+func TestSharedMonitor(t *testing.T) {
+	var c *monitorComponent
+	Register("test_shared_monitor", &funcFactory{new: func(rawConfig string) Component {
+		c = &monitorComponent{countComponent: countComponent{name: rawConfig}}
+		return c
+	}})
+	Register("test_shared_source", &funcFactory{new: func(rawConfig string) Component {
+		return &sourceComponent{}
+	}})
+
+	_ = DeclareShared(SharedConfig{Name: "shared_m", Component: "test_shared_monitor", RawConfig: "m"})
+	_ = DeclareShared(SharedConfig{Name: "shared_s", Component: "test_shared_source"})
+	defer UndeclareShared("shared_m")
+	defer UndeclareShared("shared_s")
+
+	if _, _, err := AcquireShared("shared_s"); err == nil {
+		t.Fatal("Expected an error when a source component is shared")
+	}
+
+	r1, _, _ := AcquireShared("shared_m")
+	r2, _, _ := AcquireShared("shared_m")
+	m1, m2 := monitor.NewMonitor("p1"), monitor.NewMonitor("p2")
+	r1.(MonitorSetter).SetMonitor(m1)
+	r2.(MonitorSetter).SetMonitor(m2)
+
+	// 两个pipeline都能看到实例设置的指标和之后的计数
+	c.m.Add("count", 2)
+	for _, m := range []monitor.Monitor{m1, m2} {
+		if m.Get("name").String() != "m" || m.Get("count").String() != "2" {
+			t.Fatalf("Expected the metrics of the shared instance, got: %s", m)
+		}
+	}
+
+	// 释放后不再显示新的指标
+	_ = r1.Stop()
+	c.m.Add("errors", 1)
+	if m1.Get("errors").String() != "" || m2.Get("errors").String() != "1" {
+		t.Fatalf("Expected the released pipeline not to be updated, got: %s %s", m1, m2)
+	}
+	_ = r2.Stop()
+}
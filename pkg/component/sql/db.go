@@ -0,0 +1,147 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"reflect"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	METRICS_KEY_DB_OPEN_CONNECTIONS = "_db_open_connections"
+	METRICS_KEY_DB_IN_USE           = "_db_in_use"
+	METRICS_KEY_DB_IDLE             = "_db_idle"
+	METRICS_KEY_DB_WAIT_COUNT       = "_db_wait_count"
+	METRICS_KEY_DB_WAIT_DURATION    = "_db_wait_duration"
+)
+
+var (
+	factory       component.Factory       = NewFactory()
+	_             component.Component     = &DB{}
+	_             component.HealthChecker = &DB{}
+	_             component.MonitorSetter = &DB{}
+	defaultConfig                         = Config{
+		Name:            "sql_db",
+		Driver:          "mysql",
+		DSN:             "user:password@tcp(127.0.0.1:3306)/dbname?parseTime=true",
+		MaxOpenConns:    10,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Hour,
+		PingTimeout:     5 * time.Second,
+	}
+	description = "database/sql connection pool, drivers: mysql, postgres"
+)
+
+func init() {
+	if err := component.Register("sql_db", factory); err != nil {
+		panic(err)
+	}
+}
+
+func NewFactory() component.Factory {
+	return component.NewFactory(
+		defaultConfig,
+		description,
+		reflect.TypeOf(&sql.DB{}),
+		func(c string) (component.Component, error) {
+			return NewDB(c)
+		})
+}
+
+type Config struct {
+	Name            string        `yaml:"name"`
+	Driver          string        `yaml:"driver"`            // 驱动名, 例如: mysql, postgres
+	DSN             string        `yaml:"dsn"`               // 连接串, 格式由驱动决定
+	MaxOpenConns    int           `yaml:"max_open_conns"`    // 最大连接数, 为0时不限制
+	MaxIdleConns    int           `yaml:"max_idle_conns"`    // 最大空闲连接数
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"` // 连接最长使用时间, 为0时不过期
+	PingTimeout     time.Duration `yaml:"ping_timeout"`      // 启动时检查连接的超时时间, 为0时不超时
+}
+
+func (c Config) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type DB struct {
+	config   Config
+	db       *sql.DB
+	instance component.Instance
+}
+
+func NewDB(rawConfig string) (*DB, error) {
+	conf := defaultConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Driver == "" {
+		return nil, errors.New("Component:sql_db driver cannot be empty")
+	}
+	if conf.DSN == "" {
+		return nil, errors.New("Component:sql_db dsn cannot be empty")
+	}
+	if conf.PingTimeout < 0 {
+		return nil, errors.New("Component:sql_db ping_timeout cannot be negative")
+	}
+
+	// sql.Open只校验驱动, 不会建立连接
+	db, err := sql.Open(conf.Driver, conf.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(conf.MaxOpenConns)
+	db.SetMaxIdleConns(conf.MaxIdleConns)
+	db.SetConnMaxLifetime(conf.ConnMaxLifetime)
+
+	return &DB{
+		config: conf,
+		db:     db,
+		instance: component.NewInstance(
+			conf.Name,
+			reflect.TypeOf(db),
+			reflect.ValueOf(db),
+			db,
+		),
+	}, nil
+}
+
+func (d *DB) Instance() component.Instance {
+	return d.instance
+}
+
+func (d *DB) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if d.config.PingTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), d.config.PingTimeout)
+	}
+	defer cancel()
+
+	return d.db.PingContext(ctx)
+}
+
+func (d *DB) HealthCheck(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+func (d *DB) SetMonitor(m monitor.Monitor) {
+	stats := func(f func(s sql.DBStats) interface{}) expvar.Func {
+		return expvar.Func(func() interface{} { return f(d.db.Stats()) })
+	}
+
+	m.Set(METRICS_KEY_DB_OPEN_CONNECTIONS, stats(func(s sql.DBStats) interface{} { return s.OpenConnections }))
+	m.Set(METRICS_KEY_DB_IN_USE, stats(func(s sql.DBStats) interface{} { return s.InUse }))
+	m.Set(METRICS_KEY_DB_IDLE, stats(func(s sql.DBStats) interface{} { return s.Idle }))
+	m.Set(METRICS_KEY_DB_WAIT_COUNT, stats(func(s sql.DBStats) interface{} { return s.WaitCount }))
+	m.Set(METRICS_KEY_DB_WAIT_DURATION, stats(func(s sql.DBStats) interface{} { return s.WaitDuration.String() }))
+}
+
+func (d *DB) Stop() error {
+	return d.db.Close()
+}
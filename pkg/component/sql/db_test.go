@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+)
+
+func TestDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := NewDB(`
+name: my_db
+driver: sqlite3
+dsn: ` + filepath.Join(dir, "test.db") + `
+max_open_conns: 1
+conn_max_lifetime: 1m
+ping_timeout: 0s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := monitor.NewMonitor("test_sql_db")
+	d.SetMonitor(m)
+
+	if err = d.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	db := d.Instance().Interface().(*sql.DB)
+	if db.Stats().MaxOpenConnections != 1 {
+		t.Fatalf("Expected max open connections to be 1, got: %d", db.Stats().MaxOpenConnections)
+	}
+
+	if _, err = db.Exec("CREATE TABLE t (v TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("INSERT INTO t (v) VALUES (?)", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	var v string
+	if err = db.QueryRow("SELECT v FROM t").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != "hello" {
+		t.Fatalf("Expected hello, got: %s", v)
+	}
+
+	if err = d.HealthCheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.Get(METRICS_KEY_DB_OPEN_CONNECTIONS).String() != "1" {
+		t.Fatalf("Expected 1 open connection, got: %s", m.Get(METRICS_KEY_DB_OPEN_CONNECTIONS))
+	}
+
+	if err = d.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.HealthCheck(context.Background()); err == nil {
+		t.Fatal("Expected the health check to fail after stop")
+	}
+}
+
+func TestDBConfig(t *testing.T) {
+	for _, conf := range []string{
+		"driver: ''",
+		"dsn: ''",
+		"driver: unknown",
+		"ping_timeout: -1s",
+	} {
+		if _, err := NewDB(conf); err == nil {
+			t.Fatalf("Expected an error for config: %s", conf)
+		}
+	}
+
+	// 连接失败时Start返回错误
+	d, err := NewDB("driver: postgres\ndsn: postgres://user@127.0.0.1:1/db?sslmode=disable\nping_timeout: 1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	start := time.Now()
+	if err = d.Start(); err == nil {
+		t.Fatal("Expected an error when the database is unreachable")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("Expected the ping to respect ping_timeout")
+	}
+}
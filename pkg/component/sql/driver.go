@@ -0,0 +1,7 @@
+package sql
+
+// 内置的驱动, 其他驱动可以通过插件注册
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
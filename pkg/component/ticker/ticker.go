@@ -0,0 +1,223 @@
+package ticker
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/clock"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputTime = "time" // 注入<-chan time.Time
+	OutputTick = "tick" // 注入<-chan Tick
+
+	METRICS_KEY_TICKER_TICKS   = "_ticks"
+	METRICS_KEY_TICKER_DROPPED = "_dropped"
+)
+
+var (
+	factory       component.Factory       = NewFactory()
+	_             component.Component     = &Ticker{}
+	_             component.MonitorSetter = &Ticker{}
+	defaultConfig                         = Config{
+		Name:       "ticker",
+		Interval:   time.Minute,
+		Output:     OutputTick,
+		BufferSize: 1,
+	}
+	description = "ticker without cron, e.g.: every 30s with 5s jitter"
+)
+
+func init() {
+	if err := component.Register("ticker", factory); err != nil {
+		panic(err)
+	}
+}
+
+func NewFactory() component.Factory {
+	return component.NewFactory(
+		defaultConfig,
+		description,
+		reflect.TypeOf((<-chan Tick)(nil)),
+		func(c string) (component.Component, error) {
+			return NewTicker(c)
+		})
+}
+
+type Config struct {
+	Name        string        `yaml:"name"`
+	Interval    time.Duration `yaml:"interval"`
+	Jitter      time.Duration `yaml:"jitter"`        // 每次间隔随机增加[0, jitter)
+	Output      string        `yaml:"output"`        // time: 注入<-chan time.Time, tick: 注入<-chan Tick
+	BufferSize  int           `yaml:"buffer_size"`   // channel缓冲区满时丢弃新的tick, 和time.Ticker一样
+	TickOnStart bool          `yaml:"tick_on_start"` // 启动时立即触发一次
+}
+
+func (c Config) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// Tick 一次触发
+type Tick struct {
+	Seq  uint64 // 从1开始, 丢弃的tick也会占用序号
+	Time time.Time
+}
+
+type Ticker struct {
+	config   Config
+	instance component.Instance
+
+	clock  clock.Clock
+	random func(n int64) int64
+
+	ticks   chan Tick
+	times   chan time.Time
+	seq     uint64
+	dropped uint64
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func NewTicker(rawConfig string) (*Ticker, error) {
+	conf := defaultConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Name == "" {
+		return nil, errors.New("Component:ticker name cannot be empty")
+	}
+	if conf.Interval <= 0 {
+		return nil, errors.New("Component:ticker interval must be positive")
+	}
+	if conf.Jitter < 0 {
+		return nil, errors.New("Component:ticker jitter cannot be negative")
+	}
+	if conf.BufferSize < 1 {
+		conf.BufferSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Ticker{
+		config: conf,
+		clock:  clock.Real,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())).Int63n,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	switch conf.Output {
+	case OutputTick:
+		t.ticks = make(chan Tick, conf.BufferSize)
+		c := (<-chan Tick)(t.ticks)
+		t.instance = component.NewInstance(conf.Name, reflect.TypeOf(c), reflect.ValueOf(c), c)
+	case OutputTime:
+		t.times = make(chan time.Time, conf.BufferSize)
+		c := (<-chan time.Time)(t.times)
+		t.instance = component.NewInstance(conf.Name, reflect.TypeOf(c), reflect.ValueOf(c), c)
+	default:
+		return nil, errors.Wrap(fmt.Errorf("Unknown output: %s", conf.Output), "ticker")
+	}
+
+	return t, nil
+}
+
+func (t *Ticker) Instance() component.Instance {
+	return t.instance
+}
+
+// SetClock replaces the clock before Start, e.g. with a clock.Fake in tests.
+func (t *Ticker) SetClock(c clock.Clock) {
+	t.clock = c
+}
+
+func (t *Ticker) SetMonitor(m monitor.Monitor) {
+	m.Set(METRICS_KEY_TICKER_TICKS, expvar.Func(func() interface{} { return atomic.LoadUint64(&t.seq) }))
+	m.Set(METRICS_KEY_TICKER_DROPPED, expvar.Func(func() interface{} { return atomic.LoadUint64(&t.dropped) }))
+}
+
+func (t *Ticker) next() time.Duration {
+	d := t.config.Interval
+	if t.config.Jitter > 0 {
+		d += time.Duration(t.random(int64(t.config.Jitter)))
+	}
+	return d
+}
+
+func (t *Ticker) Start() error {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.run()
+	}()
+	return nil
+}
+
+func (t *Ticker) run() {
+	if t.config.TickOnStart {
+		t.tick(t.clock.Now())
+	}
+
+	for {
+		timer := t.clock.NewTimer(t.next())
+		select {
+		case <-t.ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C():
+			t.tick(now)
+		}
+	}
+}
+
+func (t *Ticker) tick(now time.Time) {
+	seq := atomic.AddUint64(&t.seq, 1)
+
+	var sent bool
+	if t.ticks != nil {
+		select {
+		case t.ticks <- Tick{Seq: seq, Time: now}:
+			sent = true
+		default:
+		}
+	} else {
+		select {
+		case t.times <- now:
+			sent = true
+		default:
+		}
+	}
+
+	if !sent {
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// Stop closes the channel after the last tick was sent.
+func (t *Ticker) Stop() error {
+	t.stopOnce.Do(func() {
+		t.cancel()
+		t.wg.Wait()
+
+		if t.ticks != nil {
+			close(t.ticks)
+		} else {
+			close(t.times)
+		}
+	})
+	return nil
+}
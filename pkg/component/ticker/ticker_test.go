@@ -0,0 +1,105 @@
+package ticker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/common/clock"
+)
+
+func newFakeTicker(t *testing.T, rawConfig string) (*Ticker, *clock.Fake) {
+	tk, err := NewTicker(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := clock.NewFake(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	tk.SetClock(fake)
+	if err = tk.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tk.Stop() })
+	return tk, fake
+}
+
+func TestTicker(t *testing.T) {
+	tk, fake := newFakeTicker(t, "interval: 10s\nbuffer_size: 2")
+	start := fake.Now()
+	ticks := tk.Instance().Interface().(<-chan Tick)
+
+	for i := 1; i <= 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(10 * time.Second)
+
+		tick := <-ticks
+		expected := start.Add(time.Duration(i) * 10 * time.Second)
+		if tick.Seq != uint64(i) || !tick.Time.Equal(expected) {
+			t.Fatalf("Expected %d %s - Got %d %s", i, expected, tick.Seq, tick.Time)
+		}
+	}
+
+	// 没有消费时, 超出缓冲区的tick被丢弃
+	for i := 0; i < 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(10 * time.Second)
+	}
+	fake.BlockUntil(1)
+
+	if len(ticks) != 2 || tk.dropped != 1 {
+		t.Fatalf("Expected 2 buffered and 1 dropped - Got %d, %d", len(ticks), tk.dropped)
+	}
+	if tick := <-ticks; tick.Seq != 4 {
+		t.Fatalf("Expected seq 4 - Got %d", tick.Seq)
+	}
+
+	tk.Stop()
+	<-ticks
+	if _, ok := <-ticks; ok {
+		t.Fatal("Expected ticks closed after stop")
+	}
+}
+
+func TestTickerJitter(t *testing.T) {
+	tk, err := NewTicker("interval: 10s\njitter: 5s\noutput: time\ntick_on_start: true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.random = func(n int64) int64 { return n - 1 }
+
+	fake := clock.NewFake(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	tk.SetClock(fake)
+	if err = tk.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer tk.Stop()
+
+	times := tk.Instance().Interface().(<-chan time.Time)
+	if now := <-times; !now.Equal(fake.Now()) {
+		t.Fatalf("Expected tick on start at %s - Got %s", fake.Now(), now)
+	}
+
+	fake.BlockUntil(1)
+	fake.Advance(10 * time.Second)
+	select {
+	case now := <-times:
+		t.Fatalf("Unexpected tick before jitter: %s", now)
+	default:
+	}
+
+	fake.Advance(5 * time.Second)
+	if now := <-times; !now.Equal(fake.Now().Add(-1)) {
+		t.Fatalf("Expected %s - Got %s", fake.Now().Add(-1), now)
+	}
+}
+
+func TestTickerConfig(t *testing.T) {
+	for _, rawConfig := range []string{
+		"interval: 0s",
+		"jitter: -1s",
+		"output: unknown",
+	} {
+		if _, err := NewTicker(rawConfig); err == nil {
+			t.Errorf("Expected error of %s", rawConfig)
+		}
+	}
+}
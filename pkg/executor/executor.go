@@ -2,6 +2,7 @@ package executor
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/shima-park/lotus/pkg/component"
 	"github.com/shima-park/lotus/pkg/processor"
@@ -15,6 +16,8 @@ type Executor interface {
 	State() State
 	ListComponents() []Component
 	ListProcessors() []Processor
+	Health() Health
+	Graph() Graph
 	Error() error
 }
 
@@ -43,11 +46,60 @@ type Component struct {
 	RawConfig string
 	Component component.Component
 	Factory   component.Factory
+	Primary   bool // 多个组件实现同一个接口时优先注入该组件
 }
 
 type Processor struct {
-	Name      string
-	RawConfig string
-	Processor processor.Processor
-	Factory   processor.Factory
+	Name       string
+	RawConfig  string
+	Processor  processor.Processor
+	ParamNames []string // 处理器非结构体参数的注入名, 见processor.WithParamNames
+	Factory    processor.Factory
+}
+
+// Health 执行器的健康状态, 运行中并且所有组件健康时才是ready
+type Health struct {
+	Ready      bool              `json:"ready"`
+	State      string            `json:"state"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// ComponentHealth 实现了component.HealthChecker的组件最近一次的检查结果
+type ComponentHealth struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckTime time.Time `json:"check_time"`
+}
+
+const (
+	GraphNodeComponent = "component"
+	GraphNodeInjector  = "injector" // 执行器内置的值和组件注册的provider, e.g. Context, Checkpoint
+	GraphNodeProcessor = "processor"
+	GraphNodeInput     = "input"
+	GraphNodeOutput    = "output"
+)
+
+// Graph 组件和处理器之间的依赖关系, 和CheckDependence一样通过处理器的签名分析得到
+// 边的方向是从提供值的节点到使用值的节点
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Processor string `json:"processor,omitempty"` // input和output所属的处理器
+	Field     string `json:"field,omitempty"`     // input和output的字段名, 整体注入的参数是argument N
+	Type      string `json:"type,omitempty"`
+	Name      string `json:"name,omitempty"`    // 注入名
+	Missing   bool   `json:"missing,omitempty"` // 找不到提供值的input
+}
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
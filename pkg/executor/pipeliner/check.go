@@ -3,113 +3,283 @@ package pipeliner
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
 	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/executor"
 	"github.com/shima-park/lotus/pkg/processor"
 )
 
-var errorInterface = reflect.TypeOf((*error)(nil)).Elem()
+var (
+	errorInterface = reflect.TypeOf((*error)(nil)).Elem()
+	checkpointType = inject.InterfaceOf((*checkpoint.Checkpoint)(nil))
+)
 
 type MissingDependencyError struct {
 	Field       string
 	ReflectType string
 	InjectName  string
+	// 输出了该值但不是当前stream祖先的stream, e.g. 兄弟分支上的stream
+	Producers []string
+	// 当前stream能注入的同类型的值的名字
+	Suggestions []string
 }
 
 func (e MissingDependencyError) Error() string {
-	return fmt.Sprintf("Value not found for field: %v, type: %v, name: %v",
+	msg := fmt.Sprintf("Value not found for field: %v, type: %v, name: %v",
 		e.Field, e.ReflectType, e.InjectName)
+
+	if len(e.Producers) > 0 {
+		msg += fmt.Sprintf(", it is produced by stream: %s which is not an ancestor",
+			strings.Join(e.Producers, ", "))
+	} else {
+		msg += ", expected producer: a component or an ancestor stream"
+	}
+
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(", did you mean name: %s", strings.Join(e.Suggestions, ", "))
+	}
+	return msg
 }
 
+// check checks the stream tree like it runs: a stream can only consume
+// the outputs of its ancestors, the values of the run and the components.
 func check(s *Stream, inj inject.Injector) []error {
-	if s == nil || s.processor.Processor == nil {
+	if s == nil {
+		return nil
+	}
+	return checkStream(s, inj, s)
+}
+
+func checkStream(s *Stream, inj inject.Injector, root *Stream) []error {
+	f := s.processor.Processor
+	if f == nil {
 		return nil
 	}
 
 	var errs []error
-	if err := processor.Validate(s.processor.Processor); err != nil {
+	if err := processor.Validate(f); err != nil {
 		errs = append(errs, err)
 		return errs
 	}
 
-	errs = append(errs, checkDep(s.Name(), inj, s.processor.Processor)...)
+	for _, err := range checkIn(inj, f, s.processor.ParamNames) {
+		if mde, ok := err.(MissingDependencyError); ok {
+			err = explain(mde, s, inj, root)
+		}
+		errs = append(errs, errors.Wrapf(err, "Stream(%s)", s.Name()))
+	}
+
+	// 输出只对子孙可见, 每个stream的输出放在自己的injector中
+	out := inject.New()
+	out.SetParent(inj)
+	for _, err := range checkOut(out, reflect.TypeOf(f)) {
+		errs = append(errs, errors.Wrapf(err, "Stream(%s)", s.Name()))
+	}
 
 	for i := 0; i < len(s.childs); i++ {
-		errs = append(errs, check(s.childs[i], inj)...)
+		errs = append(errs, checkStream(s.childs[i], out, root)...)
 	}
 	return errs
 }
 
-func checkDep(name string, inj inject.Injector, f interface{}) []error {
-	t := reflect.TypeOf(f)
+// explain fills the producers of the missing value which are not ancestors of s,
+// and the names of the values of the same type that s can consume.
+func explain(e MissingDependencyError, s *Stream, inj inject.Injector, root *Stream) MissingDependencyError {
+	var in *port
+	for _, p := range inputsOf(s.processor.Processor, s.processor.ParamNames) {
+		if p.Field == e.Field {
+			in = &p
+			break
+		}
+	}
+	if in == nil || in.Annotation.Options.Contains(inject.InjectTagOptionsAll) {
+		return e
+	}
 
-	var errs []error
-	for _, err := range checkIn(inj, t) {
-		errs = append(errs, errors.Wrapf(err, "Stream(%s)", name))
+	var others []producer
+	walk(root, func(o *Stream) {
+		if o.processor.Processor == nil || isAncestor(o, s) {
+			return
+		}
+		for _, out := range outputsOf(reflect.TypeOf(o.processor.Processor)) {
+			others = append(others, producer{
+				node: executor.GraphNode{Processor: o.Name()},
+				typ:  out.Type,
+				name: out.Name(),
+			})
+		}
+	})
+	for _, pr := range match(others, in.Type, in.Name(), true) {
+		e.Producers = append(e.Producers, pr.node.Processor)
 	}
 
-	for _, err := range checkOut(inj, t) {
-		errs = append(errs, errors.Wrapf(err, "Stream(%s)", name))
+	// 按类型注入的参数找不到时不会有同类型的值
+	if in.Name() != "" {
+		e.Suggestions = inj.LookupAll(in.Type)
 	}
+	return e
+}
 
-	return errs
+// isAncestor reports whether a is s or an ancestor of s.
+func isAncestor(a, s *Stream) bool {
+	for ; s != nil; s = s.parent {
+		if a == s {
+			return true
+		}
+	}
+	return false
 }
 
-func checkIn(inj inject.Injector, t reflect.Type) []error {
-	var errs []error
+func walk(s *Stream, do func(*Stream)) {
+	do(s)
+	for _, child := range s.childs {
+		walk(child, do)
+	}
+}
+
+// port is an argument or an inject field of the input and output structs of a processor.
+type port struct {
+	Field       string // struct field name or "argument N"
+	Type        reflect.Type
+	Annotation  inject.InjectAnnotation
+	StructField *reflect.StructField // nil if the argument is injected as a whole
+}
+
+func (p port) Name() string {
+	return p.Annotation.Name
+}
+
+// fieldsOf returns the inject fields of a struct or a pointer to a struct.
+// 在check过程中没法直接通过injector.Apply来测试是否能注入成功
+// checkout处只能获取到reflect.Type, 对于接口类型的值没法造出reflect.Value
+// 例如：知道类型是(*io.Reader)(nil)
+// reflect.Type: *io.Reader
+// reflect.Value: nil
+// 导致即使Apply根据type,name找到value, 但是由于value的IsValid返回的false导致注入失败
+// 所以改为判断根据type,name能否找到value，而不关注是否是IsValid
+func fieldsOf(t reflect.Type) []port {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var ports []port
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		ia := inject.GetInjectAnnotation(structField)
+		if !ia.Exists {
+			continue
+		}
+
+		ports = append(ports, port{
+			Field:       structField.Name,
+			Type:        structField.Type,
+			Annotation:  ia,
+			StructField: &structField,
+		})
+	}
+	return ports
+}
+
+// inputsOf returns what the processor f requires like Invoke injects them.
+func inputsOf(f interface{}, names []string) []port {
+	t := reflect.TypeOf(f)
+
+	var ports []port
 	for i := 0; i < t.NumIn(); i++ {
 		argType := t.In(i)
 
-		for argType.Kind() == reflect.Ptr {
-			argType = argType.Elem()
+		// 非结构体的参数整体按类型和处理器的参数名注入
+		if !inject.IsInjectStruct(argType) {
+			ports = append(ports, port{
+				Field:      fmt.Sprintf("argument %d", i),
+				Type:       argType,
+				Annotation: inject.InjectAnnotation{Name: inject.ParamName(names, i), Exists: true},
+			})
+			continue
 		}
 
-		if argType.Kind() != reflect.Struct {
-			errs = append(errs, fmt.Errorf("Cannot support types other than structures %v", argType))
-		}
+		ports = append(ports, fieldsOf(argType)...)
+	}
+	return ports
+}
 
-		val := reflect.New(argType)
+// outputsOf returns what a processor of type t maps like MapValues.
+func outputsOf(t reflect.Type) []port {
+	var ports []port
+	for i := 0; i < t.NumOut(); i++ {
+		if t.Out(i).Implements(errorInterface) {
+			continue
+		}
+		ports = append(ports, fieldsOf(t.Out(i))...)
+	}
+	return ports
+}
 
-		for val.Kind() == reflect.Ptr {
-			val = val.Elem()
+func checkIn(inj inject.Injector, f interface{}, names []string) []error {
+	var errs []error
+	for _, in := range inputsOf(f, names) {
+		if in.StructField == nil {
+			err := inject.CanResolve(inj, in.Type, in.Name())
+			if _, ok := err.(inject.NotFoundError); ok {
+				errs = append(errs, MissingDependencyError{
+					Field:       in.Field,
+					ReflectType: in.Type.String(),
+					InjectName:  in.Name(),
+				})
+			} else if err != nil {
+				errs = append(errs, errors.Wrap(err, in.Field))
+			}
+			continue
 		}
 
-		if val.Kind() != reflect.Struct {
+		if err := inject.CheckField(*in.StructField); err != nil {
+			errs = append(errs, errors.Wrapf(err, "Field %s", in.Field))
 			continue
 		}
 
-		typ := val.Type()
-		// 在check过程中没法直接通过injector.Apply来测试是否能注入成功
-		// checkout处只能获取到reflect.Type, 对于接口类型的值没法造出reflect.Value
-		// 例如：知道类型是(*io.Reader)(nil)
-		// reflect.Type: *io.Reader
-		// reflect.Value: nil
-		// 导致即使Apply根据type,name找到value, 但是由于value的IsValid返回的false导致注入失败
-		// 所以此处改为判断根据type,name能否找到value，而不关注是否是IsValid
-		for i := 0; i < val.NumField(); i++ {
-			f := val.Field(i)
-			structField := typ.Field(i)
-			ia := inject.GetInjectAnnotation(structField)
-
-			var tt reflect.Type
-			if f.Type().Kind() == reflect.Interface {
-				nilPtr := reflect.New(f.Type())
-				tt = inject.InterfaceOf(nilPtr.Interface())
-			} else {
-				tt = f.Type()
+		ia := in.Annotation
+		_, hasDefault := ia.Default()
+		optional := hasDefault || ia.Options.Contains(inject.InjectTagOptionsOptional)
+
+		if ia.Options.Contains(inject.InjectTagOptionsAll) {
+			if len(inj.LookupAll(in.Type.Elem())) == 0 && !optional {
+				errs = append(errs, MissingDependencyError{
+					Field:       in.Field,
+					ReflectType: in.Type.Elem().String(),
+					InjectName:  "*",
+				})
 			}
+			continue
+		}
 
-			val := inj.Get(tt, ia.Name)
-			if !val.IsValid() && !ia.Options.Contains(inject.InjectTagOptionsOptional) {
+		// 只检查能否找到, 不调用provider, 但会检查provider的参数.
+		// 字段总是按名字注入, 名字为空时只能查找同名的值
+		var err error
+		if ia.Name == "" {
+			err = inj.Lookup(in.Type, ia.Name)
+		} else {
+			err = inject.CanResolve(inj, in.Type, ia.Name)
+		}
+		if _, ok := err.(inject.NotFoundError); ok {
+			if !optional {
 				errs = append(errs, MissingDependencyError{
-					Field:       structField.Name,
-					ReflectType: tt.String(),
+					Field:       in.Field,
+					ReflectType: in.Type.String(),
 					InjectName:  ia.Name,
 				})
 			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "Field %s", in.Field))
 		}
-
 	}
 	return errs
 }
@@ -130,16 +300,16 @@ func checkOut(inj inject.Injector, t reflect.Type) []error {
 		if outType.Kind() != reflect.Struct {
 			errs = append(errs, fmt.Errorf("Cannot support types other than structures %v", outType))
 		}
+	}
 
-		val := reflect.New(outType)
-		// 接口类型 (*io.Reader)(nil)
-		// 基础类型 (string)("")
-		// 结构体指针类型 (*Foo)(nil)
-		// 结构体类型 (Foo)({})
-		// 由于check流程是直接反射方法造处对应接口，无法或者接口类型的具体value
-		if err := inj.MapValues(val); err != nil {
-			errs = append(errs, err)
-		}
+	// 由于check流程是直接反射方法造处对应接口，无法获取接口类型的具体value
+	// MapValues会把接口类型的nil映射成无效的value, 所以直接映射零值
+	// 接口类型 (io.Reader)(nil)
+	// 基础类型 (string)("")
+	// 结构体指针类型 (*Foo)(nil)
+	// 结构体类型 (Foo)({})
+	for _, out := range outputsOf(t) {
+		inj.Set(out.Type, out.Name(), reflect.Zero(out.Type))
 	}
 	return errs
 }
@@ -0,0 +1,90 @@
+package pipeliner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/executor"
+)
+
+func TestCheckArgs(t *testing.T) {
+	inj := inject.New()
+	inj.MapTo(context.Background(), "Context", (*context.Context)(nil))
+	inj.MapTo(strings.NewReader("a"), "reader_a", (*io.Reader)(nil))
+
+	f := func(ctx context.Context, r io.Reader) error { return nil }
+	equal(t, len(checkIn(inj, f, nil)), 0)
+
+	// 同一类型有多个值时需要参数名
+	inj.MapTo(strings.NewReader("b"), "reader_b", (*io.Reader)(nil))
+	errs := checkIn(inj, f, nil)
+	equal(t, len(errs), 1)
+	equal(t, strings.Contains(errs[0].Error(), "io.Reader(reader_a), io.Reader(reader_b)"), true)
+
+	equal(t, len(checkIn(inj, f, []string{"", "reader_b"})), 0)
+
+	errs = checkIn(inj, f, []string{"", "reader_c"})
+	equal(t, len(errs), 1)
+	equal(t, strings.Contains(errs[0].Error(), "Value not found for field: argument 1"), true)
+}
+
+func TestCheckFieldOptions(t *testing.T) {
+	inj := inject.New()
+
+	type In struct {
+		Workers int         `inject:"workers,default=4" validate:"min=1"`
+		Writers []io.Writer `inject:",all"`
+	}
+	f := func(in In) error { return nil }
+
+	errs := checkIn(inj, f, nil)
+	equal(t, len(errs), 1)
+	equal(t, strings.Contains(errs[0].Error(), "field: Writers"), true)
+
+	inj.MapTo(&strings.Builder{}, "a", (*io.Writer)(nil))
+	equal(t, len(checkIn(inj, f, nil)), 0)
+
+	type BadIn struct {
+		Workers int    `inject:"workers,default=0" validate:"min=1"`
+		Mode    string `inject:"mode,default=x" validate:"len=a"`
+		Writer  string `inject:",all"`
+	}
+	equal(t, len(checkIn(inj, func(in BadIn) error { return nil }, nil)), 3)
+}
+
+func TestCheckPath(t *testing.T) {
+	type rootOut struct {
+		Line string `inject:"line"`
+	}
+	type readerOut struct {
+		Reader io.Reader `inject:"reader"`
+	}
+	type readerIn struct {
+		Reader io.Reader `inject:"reader"`
+	}
+
+	newStream := func(name string, f interface{}) *Stream {
+		return &Stream{processor: executor.Processor{Name: name, Processor: f}}
+	}
+	root := newStream("root", func() (rootOut, error) { return rootOut{}, nil })
+	a := newStream("a", func() (readerOut, error) { return readerOut{}, nil })
+	// 接口类型的输出也能被子stream注入
+	a.Append(newStream("a1", func(in readerIn) error { return nil }))
+	root.Append(a)
+	// 兄弟分支的输出不能注入
+	root.Append(newStream("b", func(in readerIn, lines struct {
+		Lines string `inject:"lines"`
+	}) error {
+		return nil
+	}))
+
+	errs := check(root, inject.New())
+	equal(t, len(errs), 2)
+	equal(t, errs[0].Error(), "Stream(b): Value not found for field: Reader, type: io.Reader, name: reader, "+
+		"it is produced by stream: a which is not an ancestor")
+	equal(t, errs[1].Error(), "Stream(b): Value not found for field: Lines, type: string, name: lines, "+
+		"expected producer: a component or an ancestor stream, did you mean name: line")
+}
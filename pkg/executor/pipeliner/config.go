@@ -1,33 +1,81 @@
 package pipeliner
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
+	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/component"
 	"github.com/shima-park/lotus/pkg/executor"
 	"github.com/shima-park/lotus/pkg/processor"
+	"gopkg.in/yaml.v2"
 )
 
+// SHARED_COMPONENT_KEY components中引用服务级别共享组件的key, 例如: - shared: my_kafka
+const SHARED_COMPONENT_KEY = "shared"
+
 type Config struct {
 	Name                  string              `yaml:"name"`
 	Schedule              string              `yaml:"schedule"`                // 调度计划，为空时死循环调度，可以传入cron表达式调度
 	CircuitBreakerSamples int64               `yaml:"circuit_breaker_samples"` // 熔断器采样数, 防止stream出现异常耗尽cpu资源
 	CircuitBreakerRate    float64             `yaml:"circuit_breaker_rate"`    // 熔断器采样率
 	Bootstrap             bool                `yaml:"bootstrap"`               // 随进程启动而启动
-	Components            []map[string]string `yaml:"components"`              // key: name, value: rawConfig
+	HealthCheckInterval   time.Duration       `yaml:"health_check_interval"`   // 组件健康检查的间隔, 默认10s
+	HealthCheckTimeout    time.Duration       `yaml:"health_check_timeout"`    // 单个组件健康检查的超时时间, 默认3s
+	Components            []map[string]string `yaml:"components"`              // key: name, value: rawConfig, key为shared时value为共享组件的名字
 	Processors            []map[string]string `yaml:"processors"`              // key: name, value: rawConfig
 	Stream                StreamConfig        `yaml:"stream"`                  // key: name, value: StreamConfig
 }
 
+// SharedReferences returns the names of the shared components referenced by the components.
+func (c Config) SharedReferences() []string {
+	var names []string
+	for _, name2config := range c.Components {
+		if name, ok := name2config[SHARED_COMPONENT_KEY]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// NewComponents creates the components, or acquires the references of the shared ones.
+// If any of them fails, the components created before are stopped and the references are released.
 func (c Config) NewComponents() ([]executor.Component, error) {
 	var components []executor.Component
 	var eg ErrorGroup
 	for _, name2config := range c.Components {
 		for componentName, rawConfig := range name2config {
+			if componentName == SHARED_COMPONENT_KEY {
+				conf, err := component.GetShared(rawConfig)
+				if err != nil {
+					eg = append(eg, errors.Wrapf(err, "Component: %s", componentName))
+					continue
+				}
+				c, factory, err := component.AcquireShared(rawConfig)
+				if err != nil {
+					eg = append(eg, errors.Wrapf(err, "Component: %s", componentName))
+					continue
+				}
+				components = append(components, executor.Component{
+					Name:      rawConfig,
+					RawConfig: conf.RawConfig,
+					Component: c,
+					Factory:   factory,
+					// primary标记写在共享组件声明的配置中
+					Primary: isPrimary(conf.RawConfig),
+				})
+				continue
+			}
+
 			factory, err := component.GetFactory(componentName)
 			if err != nil {
 				eg = append(eg, errors.Wrapf(err, "Component: %s", componentName))
 				continue
 			}
+			if err = component.ValidateConfig(factory, rawConfig); err != nil {
+				eg = append(eg, errors.Wrapf(err, "Component: %s", componentName))
+				continue
+			}
 			c, err := factory.New(rawConfig)
 			if err != nil {
 				eg = append(eg, errors.Wrapf(err, "Component: %s", componentName))
@@ -38,11 +86,36 @@ func (c Config) NewComponents() ([]executor.Component, error) {
 				RawConfig: rawConfig,
 				Component: c,
 				Factory:   factory,
+				Primary:   isPrimary(rawConfig),
 			})
 		}
 	}
 
-	return components, eg.Error()
+	if err := eg.Error(); err != nil {
+		stopComponents(components)
+		return nil, err
+	}
+	return components, nil
+}
+
+// stopComponents stops the components of a pipeline which cannot be created,
+// the shared instances are stopped after their last reference is released.
+func stopComponents(components []executor.Component) {
+	for _, c := range components {
+		if err := c.Component.Stop(); err != nil {
+			log.Error("Failed to stop %s component error: %s", c.Name, err)
+		}
+	}
+}
+
+// isPrimary reads the primary marker shared by all component configs,
+// "primary: true" makes the component preferred when more than one component implements an interface.
+func isPrimary(rawConfig string) bool {
+	var marker struct {
+		Primary bool `yaml:"primary"`
+	}
+	_ = yaml.Unmarshal([]byte(rawConfig), &marker)
+	return marker.Primary
 }
 
 func (c Config) NewProcessors() ([]executor.Processor, error) {
@@ -55,17 +128,23 @@ func (c Config) NewProcessors() ([]executor.Processor, error) {
 				eg = append(eg, errors.Wrapf(err, "Processor: %s", processorName))
 				continue
 			}
+			if err = processor.ValidateConfig(factory, rawConfig); err != nil {
+				eg = append(eg, errors.Wrapf(err, "Processor: %s", processorName))
+				continue
+			}
 
 			p, err := factory.New(rawConfig)
 			if err != nil {
 				eg = append(eg, errors.Wrapf(err, "Processor: %s", processorName))
 				continue
 			}
+			p, names := processor.Unwrap(p)
 			processors = append(processors, executor.Processor{
-				Name:      processorName,
-				RawConfig: rawConfig,
-				Processor: p,
-				Factory:   factory,
+				Name:       processorName,
+				RawConfig:  rawConfig,
+				Processor:  p,
+				ParamNames: names,
+				Factory:    factory,
 			})
 		}
 	}
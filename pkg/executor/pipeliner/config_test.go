@@ -0,0 +1,66 @@
+package pipeliner
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/component"
+)
+
+func TestNewComponentsPrimary(t *testing.T) {
+	handleErr(t, component.Register("test_primary_writer", component.NewFactory(
+		"", "", reflect.TypeOf((*io.Writer)(nil)).Elem(),
+		func(string) (component.Component, error) { return &writerComponent{}, nil },
+	)))
+	handleErr(t, component.DeclareShared(component.SharedConfig{
+		Name:      "test_primary_shared",
+		Component: "test_primary_writer",
+		RawConfig: "primary: true",
+	}))
+
+	components, err := Config{Components: []map[string]string{
+		{"test_primary_writer": "primary: true"},
+		{"test_primary_writer": ""},
+		{SHARED_COMPONENT_KEY: "test_primary_shared"},
+	}}.NewComponents()
+	handleErr(t, err)
+	defer func() {
+		for _, c := range components {
+			handleErr(t, c.Component.Stop())
+		}
+	}()
+
+	equal(t, len(components), 3)
+	equal(t, components[0].Primary, true)
+	equal(t, components[1].Primary, false)
+	equal(t, components[2].Primary, true)
+	equal(t, components[2].Name, "test_primary_shared")
+}
+
+func TestNewComponentsReleaseShared(t *testing.T) {
+	handleErr(t, component.Register("test_release_writer", component.NewFactory(
+		"", "", reflect.TypeOf((*io.Writer)(nil)).Elem(),
+		func(string) (component.Component, error) { return &writerComponent{}, nil },
+	)))
+	handleErr(t, component.DeclareShared(component.SharedConfig{
+		Name:      "test_release_shared",
+		Component: "test_release_writer",
+	}))
+
+	// 后面的组件创建失败时, 已经获取的共享组件引用需要释放
+	_, err := Config{Components: []map[string]string{
+		{SHARED_COMPONENT_KEY: "test_release_shared"},
+		{"test_release_unknown": ""},
+	}}.NewComponents()
+	if err == nil {
+		t.Fatal("Expected an error for unknown component")
+	}
+
+	for _, s := range component.ListShared() {
+		if s.Name == "test_release_shared" {
+			equal(t, s.Refs, 0)
+		}
+	}
+	handleErr(t, component.UndeclareShared("test_release_shared"))
+}
@@ -10,11 +10,16 @@ import (
 	"github.com/pkg/errors"
 
 	circuit "github.com/rubyist/circuitbreaker"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
 	"github.com/shima-park/lotus/pkg/common/inject"
 	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/processor"
 )
 
+var errExecContextStopped = errors.New("Exec context is stopped")
+
 type execContext struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
@@ -24,11 +29,15 @@ type execContext struct {
 	breaker  *circuit.Breaker
 	inputC   chan inject.Injector
 	wg       sync.WaitGroup
+
+	// 保护inputC的关闭, 防止Emit向已经关闭的channel发送
+	inputLock sync.RWMutex
+	closeOnce sync.Once
 }
 
 func (c *execContext) Start() error {
 	if c.isStopped() {
-		return errors.New("Exec context is stopped")
+		return errExecContextStopped
 	}
 
 	c.run(c.stream, c.inputC)
@@ -41,9 +50,36 @@ func (c *execContext) Stop() {
 	}
 
 	c.cancel()
+	c.closeInput()
 	c.wg.Wait()
 }
 
+func (c *execContext) closeInput() {
+	c.closeOnce.Do(func() {
+		c.inputLock.Lock()
+		close(c.inputC)
+		c.inputLock.Unlock()
+	})
+}
+
+func (c *execContext) send(ctx context.Context, inj inject.Injector) error {
+	c.inputLock.RLock()
+	defer c.inputLock.RUnlock()
+
+	if c.isStopped() {
+		return errExecContextStopped
+	}
+
+	select {
+	case <-c.ctx.Done():
+		return errExecContextStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.inputC <- inj:
+		return nil
+	}
+}
+
 func (c *execContext) isStopped() bool {
 	select {
 	case <-c.ctx.Done():
@@ -59,14 +95,54 @@ func (c *execContext) Run() {
 		return
 	}
 
-	select {
-	case <-c.ctx.Done():
-		close(c.inputC)
-		return
-	case c.inputC <- c.injector:
+	if err := c.send(c.ctx, c.newInput()); err != nil {
+		c.closeInput()
 	}
 }
 
+// Emit sends the input pushed by a source component to the root stream.
+func (c *execContext) Emit(ctx context.Context, in component.Input) error {
+	inj := c.newInput()
+	for _, v := range in.Values {
+		inj.Set(v.Type(), v.Name(), v.Value())
+	}
+
+	tracker := trackerOf(inj)
+	if in.OnCommit != nil {
+		tracker.OnCommit(in.OnCommit)
+	}
+	if in.OnAbort != nil {
+		tracker.OnAbort(in.OnAbort)
+	}
+
+	return c.send(ctx, inj)
+}
+
+// 每次调度生成独立的injector, 携带跟踪本次输入在stream树中完成情况的checkpoint
+func (c *execContext) newInput() inject.Injector {
+	tracker := checkpoint.New()
+	tracker.OnCommit(func() {
+		c.monitor.Add(METRICS_KEY_PIPELINE_COMMIT_COUNT, 1)
+	})
+	tracker.OnAbort(func(error) {
+		c.monitor.Add(METRICS_KEY_PIPELINE_ABORT_COUNT, 1)
+	})
+
+	inj := inject.New()
+	inj.SetParent(c.injector)
+	inj.MapTo(tracker, "Checkpoint", (*checkpoint.Checkpoint)(nil))
+	return inj
+}
+
+func trackerOf(inj inject.Injector) *checkpoint.Tracker {
+	v := inj.Get(checkpointType, "Checkpoint")
+	if !v.IsValid() {
+		return nil
+	}
+	t, _ := v.Interface().(*checkpoint.Tracker)
+	return t
+}
+
 func (c *execContext) run(s *Stream, inputC chan inject.Injector) {
 	outputC := make(chan inject.Injector, s.config.BufferSize)
 	var once sync.Once
@@ -120,18 +196,29 @@ func (c *execContext) runStream(s *Stream, inputC, outputC chan inject.Injector,
 			inj.MapTo(moni, "Monitor", (*monitor.Monitor)(nil))
 			startTime := time.Now()
 
-			val, err := s.Invoke(inj)
+			newInj, err := s.Call(inj)
 
 			elapsed += time.Since(startTime)
 			moni.Set(METRICS_KEY_STREAM_ELAPSED, monitor.Elapsed(elapsed))
 			moni.Set(METRICS_KEY_STREAM_LAST_END_TIME, monitor.Time(time.Now()))
 
-			newInj, err := handleResult(s.Name(), inj, val, err)
+			tracker := trackerOf(inj)
+			if errors.Cause(err) == processor.ErrNoOutput {
+				// 没有输出时当前分支完成, 不触发熔断
+				moni.Add(METRICS_KEY_STREAM_NO_OUTPUT_COUNT, 1)
+				if tracker != nil {
+					tracker.Done(nil)
+				}
+				continue
+			}
 			if err != nil {
 				log.Error(err.Error())
 				moni.Add(METRICS_KEY_STREAM_ERROR_COUNT, 1)
 				moni.Set(METRICS_KEY_STREAM_ERROR, monitor.String(err.Error()))
 				c.breaker.Fail()
+				if tracker != nil {
+					tracker.Done(err)
+				}
 				continue
 			}
 			c.breaker.Success()
@@ -139,6 +226,11 @@ func (c *execContext) runStream(s *Stream, inputC, outputC chan inject.Injector,
 			// 有些流程没有子流程, 不能根据塞入队列成功来判断
 			moni.Add(METRICS_KEY_STREAM_SUCCESS_COUNT, 1)
 
+			// 当前分支被所有子流程的分支替代, 没有子流程时当前分支完成
+			if tracker != nil {
+				tracker.Fork(len(s.childs))
+			}
+
 			if len(s.childs) > 0 {
 				select {
 				case <-c.ctx.Done():
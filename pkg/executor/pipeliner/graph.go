@@ -0,0 +1,234 @@
+package pipeliner
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/executor"
+)
+
+// producer is a node that provides a value of type and name to the processors.
+// The node is added to the graph when it is used, except the components.
+type producer struct {
+	node    executor.GraphNode
+	typ     reflect.Type
+	name    string
+	primary bool
+}
+
+// graphBuilder resolves the inputs of the stream tree like the injectors of a run:
+// the outputs of the nearest ancestor first, then the values of the run, then the components.
+type graphBuilder struct {
+	p      *pipeliner
+	graph  executor.Graph
+	nodes  map[string]bool
+	run    []producer
+	global []producer
+}
+
+func (p *pipeliner) Graph() executor.Graph {
+	b := &graphBuilder{
+		p:     p,
+		nodes: map[string]bool{},
+	}
+
+	for _, c := range p.components {
+		instance := c.Component.Instance()
+		node := executor.GraphNode{
+			ID:   "component:" + c.Name,
+			Kind: executor.GraphNodeComponent,
+			Type: instance.Type().String(),
+			Name: instance.Name(),
+		}
+		b.addNode(node)
+		b.global = append(b.global, producer{node: node, typ: instance.Type(), name: instance.Name(), primary: c.Primary})
+
+		// Source推送的值也是每次输入才有
+		if src, ok := c.Component.(component.Source); ok {
+			for _, v := range src.InputTypes() {
+				b.run = append(b.run, producer{node: node, typ: v.Type(), name: v.Name()})
+			}
+		}
+	}
+	// checkpoint在每次调度时才生成
+	b.run = append(b.run, producer{
+		node: executor.GraphNode{
+			ID:   fmt.Sprintf("injector:%s(Checkpoint)", checkpointType),
+			Kind: executor.GraphNodeInjector,
+			Type: checkpointType.String(),
+			Name: "Checkpoint",
+		},
+		typ:  checkpointType,
+		name: "Checkpoint",
+	})
+
+	if p.stream != nil {
+		b.stream(p.stream, nil)
+	}
+	return b.graph
+}
+
+func (b *graphBuilder) addNode(n executor.GraphNode) string {
+	if !b.nodes[n.ID] {
+		b.nodes[n.ID] = true
+		b.graph.Nodes = append(b.graph.Nodes, n)
+	}
+	return n.ID
+}
+
+func (b *graphBuilder) addEdge(from, to string, typ reflect.Type, name string) {
+	b.graph.Edges = append(b.graph.Edges, executor.GraphEdge{
+		From: from,
+		To:   to,
+		Type: typ.String(),
+		Name: name,
+	})
+}
+
+// stream adds the processor of s, ancestors are the outputs of its ancestors, the nearest first.
+func (b *graphBuilder) stream(s *Stream, ancestors [][]producer) {
+	if s.processor.Processor == nil {
+		return
+	}
+
+	name := s.Name()
+	proc := b.addNode(executor.GraphNode{
+		ID:   "processor:" + name,
+		Kind: executor.GraphNodeProcessor,
+		Name: name,
+	})
+
+	for _, in := range inputsOf(s.processor.Processor, s.processor.ParamNames) {
+		id := fmt.Sprintf("input:%s.%s", name, in.Field)
+		producers, typ := b.resolve(in, ancestors)
+
+		_, hasDefault := in.Annotation.Default()
+		optional := hasDefault || in.Annotation.Options.Contains(inject.InjectTagOptionsOptional)
+
+		b.addNode(executor.GraphNode{
+			ID:        id,
+			Kind:      executor.GraphNodeInput,
+			Processor: name,
+			Field:     in.Field,
+			Type:      in.Type.String(),
+			Name:      in.Name(),
+			Missing:   len(producers) == 0 && !optional,
+		})
+		for _, pr := range producers {
+			b.addEdge(b.addNode(pr.node), id, typ, pr.name)
+		}
+		b.addEdge(id, proc, in.Type, in.Name())
+	}
+
+	var outputs []producer
+	for _, out := range outputsOf(reflect.TypeOf(s.processor.Processor)) {
+		node := executor.GraphNode{
+			ID:        fmt.Sprintf("output:%s.%s", name, out.Field),
+			Kind:      executor.GraphNodeOutput,
+			Processor: name,
+			Field:     out.Field,
+			Type:      out.Type.String(),
+			Name:      out.Name(),
+		}
+		b.addEdge(proc, b.addNode(node), out.Type, out.Name())
+		outputs = append(outputs, producer{node: node, typ: out.Type, name: out.Name()})
+	}
+
+	levels := append([][]producer{outputs}, ancestors...)
+	for _, child := range s.childs {
+		b.stream(child, levels)
+	}
+}
+
+// resolve returns the producers of an input and the type they are matched by.
+func (b *graphBuilder) resolve(in port, ancestors [][]producer) ([]producer, reflect.Type) {
+	levels := make([][]producer, 0, len(ancestors)+2)
+	levels = append(append(levels, ancestors...), b.run, b.global)
+
+	// all注入每一层所有可赋值的值, 子injector中同类型同名的值覆盖父injector中的
+	if in.Annotation.Options.Contains(inject.InjectTagOptionsAll) && isCollection(in.Type) {
+		var (
+			res  []producer
+			seen = map[string]bool{}
+		)
+		for _, level := range levels {
+			for _, pr := range match(level, in.Type.Elem(), "", true) {
+				key := fmt.Sprintf("%s(%s)", pr.typ, pr.name)
+				if !seen[key] {
+					seen[key] = true
+					res = append(res, pr)
+				}
+			}
+		}
+		return res, in.Type.Elem()
+	}
+
+	for _, level := range levels {
+		if res := match(level, in.Type, in.Name(), false); len(res) > 0 {
+			return res, in.Type
+		}
+	}
+
+	// 内置的值和provider没有对应的组件
+	inj := b.p.injector
+	if err := inject.CanResolve(inj, in.Type, in.Name()); err != nil {
+		return nil, in.Type
+	}
+	name := in.Name()
+	if name == "" {
+		name, _ = inj.NameByType(in.Type)
+	}
+	node := executor.GraphNode{
+		ID:   fmt.Sprintf("injector:%s(%s)", in.Type, name),
+		Kind: executor.GraphNodeInjector,
+		Type: in.Type.String(),
+		Name: name,
+	}
+	return []producer{{node: node, typ: in.Type, name: name}}, in.Type
+}
+
+func isCollection(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice || (t.Kind() == reflect.Map && t.Key().Kind() == reflect.String)
+}
+
+// match returns the producers of type t like the injector looks them up in one injector:
+// a concrete type takes precedence over the implementors of an interface,
+// and the primary one is chosen if there are more. An empty name matches any name.
+// If all is true every assignable producer is returned.
+func match(producers []producer, t reflect.Type, name string, all bool) []producer {
+	var exact, implementors []producer
+	for _, pr := range producers {
+		if name != "" && pr.name != name {
+			continue
+		}
+		if pr.typ == t {
+			exact = append(exact, pr)
+		} else if t.Kind() == reflect.Interface && pr.typ.Implements(t) {
+			implementors = append(implementors, pr)
+		}
+	}
+
+	if all {
+		return append(exact, implementors...)
+	}
+
+	res := exact
+	if len(res) == 0 {
+		res = implementors
+	}
+	if len(res) > 1 {
+		var primaries []producer
+		for _, pr := range res {
+			if pr.primary {
+				primaries = append(primaries, pr)
+			}
+		}
+		if len(primaries) == 1 {
+			return primaries
+		}
+	}
+	// 有歧义时返回所有候选, CheckDependence会报错
+	return res
+}
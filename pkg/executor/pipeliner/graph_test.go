@@ -0,0 +1,92 @@
+package pipeliner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+type writerComponent struct {
+	buf bytes.Buffer
+}
+
+func (c *writerComponent) Instance() component.Instance {
+	w := io.Writer(&c.buf)
+	return component.NewInstance("out", reflect.TypeOf(&w).Elem(), reflect.ValueOf(&w).Elem(), w)
+}
+
+func (c *writerComponent) Start() error { return nil }
+
+func (c *writerComponent) Stop() error { return nil }
+
+func TestGraph(t *testing.T) {
+	handleErr(t, component.Register("test_graph_writer", component.NewFactory(
+		"", "", reflect.TypeOf((*io.Writer)(nil)).Elem(),
+		func(string) (component.Component, error) { return &writerComponent{}, nil },
+	)))
+	handleErr(t, processor.Register("test_graph_read", processor.NewFactoryWithProcessor(
+		"", "",
+		func(ctx context.Context) (struct {
+			Line string `inject:"line"`
+		}, error) {
+			return struct {
+				Line string `inject:"line"`
+			}{}, nil
+		},
+	)))
+	handleErr(t, processor.Register("test_graph_write", processor.NewFactoryWithProcessor(
+		"", "",
+		func(in struct {
+			Line    string    `inject:"line"`
+			Writer  io.Writer `inject:"out"`
+			Missing int       `inject:"missing"`
+		}) error {
+			return nil
+		},
+	)))
+
+	p := NewPipelineByConfig(Config{
+		Name:       "test_graph",
+		Components: []map[string]string{{"test_graph_writer": ""}},
+		Processors: []map[string]string{{"test_graph_read": ""}, {"test_graph_write": ""}},
+		Stream: StreamConfig{
+			Name:   "test_graph_read",
+			Childs: []StreamConfig{{Name: "test_graph_write"}},
+		},
+	})
+
+	g := p.Graph()
+
+	var edges []string
+	for _, e := range g.Edges {
+		edges = append(edges, e.From+" -> "+e.To)
+	}
+	for _, expected := range []string{
+		"injector:context.Context(Context) -> input:test_graph_read.argument 0",
+		"input:test_graph_read.argument 0 -> processor:test_graph_read",
+		"processor:test_graph_read -> output:test_graph_read.Line",
+		"output:test_graph_read.Line -> input:test_graph_write.Line",
+		"component:test_graph_writer -> input:test_graph_write.Writer",
+	} {
+		if !strings.Contains(strings.Join(edges, "\n"), expected) {
+			t.Errorf("Expected edge %s - Got %v", expected, edges)
+		}
+	}
+
+	missing := map[string]bool{}
+	for _, n := range g.Nodes {
+		missing[n.ID] = n.Missing
+	}
+	equal(t, missing["input:test_graph_write.Missing"], true)
+	equal(t, missing["input:test_graph_write.Writer"], false)
+
+	var buf bytes.Buffer
+	handleErr(t, p.Visualize(&buf, "dependency"))
+	equal(t, strings.Contains(buf.String(), `"input:test_graph_write.Missing" [label="Missing\nint" shape=ellipse color=red];`), true)
+}
@@ -0,0 +1,104 @@
+package pipeliner
+
+import (
+	"context"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/executor"
+)
+
+var (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 3 * time.Second
+)
+
+func (p *pipeliner) healthCheckInterval() time.Duration {
+	if p.config.HealthCheckInterval > 0 {
+		return p.config.HealthCheckInterval
+	}
+	return defaultHealthCheckInterval
+}
+
+func (p *pipeliner) healthCheckTimeout() time.Duration {
+	if p.config.HealthCheckTimeout > 0 {
+		return p.config.HealthCheckTimeout
+	}
+	return defaultHealthCheckTimeout
+}
+
+// pollHealth checks the components periodically until the pipeline is stopped.
+func (p *pipeliner) pollHealth() {
+	ticker := time.NewTicker(p.healthCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+func (p *pipeliner) checkHealth() {
+	var res []executor.ComponentHealth
+	for _, c := range p.components {
+		hc, ok := c.Component.(component.HealthChecker)
+		if !ok {
+			continue
+		}
+
+		name := c.Component.Instance().Name()
+		ctx, cancel := context.WithTimeout(p.ctx, p.healthCheckTimeout())
+		err := hc.HealthCheck(ctx)
+		cancel()
+
+		h := executor.ComponentHealth{
+			Name:      name,
+			Healthy:   err == nil,
+			CheckTime: time.Now(),
+		}
+
+		m := p.monitor.With(name)
+		if err != nil {
+			h.Error = err.Error()
+			m.Set(METRICS_KEY_COMPONENT_HEALTHY, monitor.String("false"))
+			m.Set(METRICS_KEY_COMPONENT_HEALTH_ERROR, monitor.String(h.Error))
+			log.Warn("Pipeline: %s, Component: %s is unhealthy: %s", p.name, name, err)
+		} else {
+			m.Set(METRICS_KEY_COMPONENT_HEALTHY, monitor.String("true"))
+			m.Delete(METRICS_KEY_COMPONENT_HEALTH_ERROR)
+		}
+
+		res = append(res, h)
+	}
+
+	p.healthLock.Lock()
+	p.health = res
+	p.healthLock.Unlock()
+}
+
+func (p *pipeliner) Health() executor.Health {
+	p.healthLock.RLock()
+	components := make([]executor.ComponentHealth, len(p.health))
+	copy(components, p.health)
+	p.healthLock.RUnlock()
+
+	state := p.State()
+	ready := state == executor.Running
+	for _, c := range components {
+		if !c.Healthy {
+			ready = false
+		}
+	}
+
+	return executor.Health{
+		Ready:      ready,
+		State:      state.String(),
+		Components: components,
+	}
+}
@@ -0,0 +1,68 @@
+package pipeliner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/executor"
+)
+
+type healthComponent struct {
+	name string
+	err  error
+}
+
+func (c *healthComponent) Instance() component.Instance {
+	return component.NewInstance(c.name, reflect.TypeOf(c), reflect.ValueOf(c), c)
+}
+
+func (c *healthComponent) Start() error { return nil }
+
+func (c *healthComponent) Stop() error { return nil }
+
+func (c *healthComponent) HealthCheck(ctx context.Context) error { return c.err }
+
+func TestHealth(t *testing.T) {
+	redis := &healthComponent{name: "redis"}
+	p := &pipeliner{
+		name:    "test_health",
+		ctx:     context.Background(),
+		monitor: monitor.NewMonitor("test_health"),
+		state:   int32(executor.Running),
+		components: []executor.Component{
+			{Name: "redis_client", Component: redis},
+			// 只暴露Component接口的方法, 即没有实现HealthChecker
+			{Name: "io_reader", Component: struct{ component.Component }{
+				&healthComponent{name: "reader", err: errors.New("unchecked")},
+			}},
+		},
+	}
+
+	p.checkHealth()
+	h := p.Health()
+	equal(t, h.Ready, true)
+	equal(t, h.State, executor.Running.String())
+	// 没有实现HealthChecker的组件不参与检查
+	equal(t, len(h.Components), 1)
+	equal(t, h.Components[0].Name, "redis")
+	equal(t, h.Components[0].Healthy, true)
+
+	redis.err = errors.New("connection refused")
+	p.checkHealth()
+	h = p.Health()
+	equal(t, h.Ready, false)
+	equal(t, h.Components[0].Healthy, false)
+	equal(t, h.Components[0].Error, "connection refused")
+	equal(t, p.monitor.With("redis").Get(METRICS_KEY_COMPONENT_HEALTH_ERROR).String(), "connection refused")
+
+	redis.err = nil
+	p.checkHealth()
+	p.state = int32(executor.Exited)
+	h = p.Health()
+	equal(t, h.Ready, false)
+	equal(t, h.Components[0].Healthy, true)
+}
@@ -9,6 +9,11 @@ const (
 	METRICS_KEY_PIPELINE_NEXT_RUN_TIME   = "_pipeline_next_run_time"
 	METRICS_KEY_PIPELINE_LAST_START_TIME = "_pipeline_last_start_time"
 	METRICS_KEY_PIPELINE_LAST_END_TIME   = "_pipeline_last_end_time"
+	METRICS_KEY_PIPELINE_COMMIT_COUNT    = "_pipeline_commit_count"
+	METRICS_KEY_PIPELINE_ABORT_COUNT     = "_pipeline_abort_count"
+
+	METRICS_KEY_COMPONENT_HEALTHY      = "_component_healthy"
+	METRICS_KEY_COMPONENT_HEALTH_ERROR = "_component_health_error"
 
 	METRICS_KEY_STREAM_BUFFER_SIZE     = "_stream_buffer_size"
 	METRICS_KEY_STREAM_REPLICA         = "_stream_replica"
@@ -20,6 +25,7 @@ const (
 	METRICS_KEY_STREAM_LAST_END_TIME   = "_stream_last_end_time"
 	METRICS_KEY_STREAM_SUCCESS_COUNT   = "_stream_success_count"
 	METRICS_KEY_STREAM_ERROR_COUNT     = "_stream_error_count"
+	METRICS_KEY_STREAM_NO_OUTPUT_COUNT = "_stream_no_output_count"
 	METRICS_KEY_STREAM_ELAPSED         = "_stream_elapsed"
 	METRICS_KEY_STREAM_BREAKER_OPEN    = "_stream_breaker_open"
 	METRICS_KEY_STREAM_ERROR           = "_stream_error"
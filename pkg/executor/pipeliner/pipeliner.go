@@ -15,9 +15,11 @@ import (
 
 	"github.com/pkg/errors"
 	circuit "github.com/rubyist/circuitbreaker"
+	"github.com/shima-park/lotus/pkg/common/checkpoint"
 	"github.com/shima-park/lotus/pkg/common/inject"
 	"github.com/shima-park/lotus/pkg/common/log"
 	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
 	"github.com/shima-park/lotus/pkg/executor"
 	"gopkg.in/yaml.v2"
 )
@@ -42,6 +44,13 @@ type pipeliner struct {
 	state     int32
 	runningWg sync.WaitGroup
 
+	healthLock sync.RWMutex
+	health     []executor.ComponentHealth
+
+	execLock sync.RWMutex
+	exec     *execContext // 运行中的execContext, 用于接收Source推送的输入
+	sources  []component.Source
+
 	errs []error
 }
 
@@ -125,6 +134,24 @@ func (p *pipeliner) init(conf Config) *pipeliner {
 		}
 
 		p.injector.Set(instance.Type(), instance.Name(), instance.Value())
+		if c.Primary {
+			p.injector.SetPrimary(instance.Type(), instance.Name())
+		}
+
+		if ms, ok := c.Component.(component.MonitorSetter); ok {
+			ms.SetMonitor(p.monitor.With(instance.Name()))
+		}
+
+		if pr, ok := c.Component.(component.ProviderRegistrar); ok {
+			if err = pr.RegisterProviders(p.injector); err != nil {
+				p.errs = append(p.errs, errors.Wrapf(err, "Pipeline: %s, Component: %s RegisterProviders", conf.Name, c.Name))
+			}
+		}
+
+		if src, ok := c.Component.(component.Source); ok {
+			src.SetEmitter(p.emit)
+			p.sources = append(p.sources, src)
+		}
 	}
 
 	if errs := p.CheckDependence(); len(errs) > 0 {
@@ -141,9 +168,39 @@ func (p *pipeliner) Name() string {
 func (p *pipeliner) CheckDependence() []error {
 	checkInj := inject.New()
 	checkInj.SetParent(p.injector)
+	// checkpoint在每次调度时才生成, 检查时用一个占位值代替
+	checkInj.MapTo(checkpoint.New(), "Checkpoint", (*checkpoint.Checkpoint)(nil))
+	// Source推送的值也是每次输入才有
+	for _, src := range p.sources {
+		for _, v := range src.InputTypes() {
+			checkInj.Set(v.Type(), v.Name(), v.Value())
+		}
+	}
 	return check(p.stream, checkInj)
 }
 
+// emit is the Emitter of the source components.
+func (p *pipeliner) emit(ctx context.Context, in component.Input) error {
+	p.execLock.RLock()
+	defer p.execLock.RUnlock()
+
+	if p.exec == nil {
+		return fmt.Errorf("Pipeline: %s is not running", p.name)
+	}
+	return p.exec.Emit(ctx, in)
+}
+
+func (p *pipeliner) setExecContext(c *execContext) {
+	p.execLock.Lock()
+	p.exec = c
+	p.execLock.Unlock()
+}
+
+// pushOnly returns true if the root stream is only driven by the source components.
+func (p *pipeliner) pushOnly() bool {
+	return len(p.sources) > 0 && p.config.Schedule == ""
+}
+
 func (p *pipeliner) newExecContext() *execContext {
 	inj := inject.New()
 	inj.SetParent(p.injector)
@@ -185,10 +242,24 @@ func (p *pipeliner) start() error {
 		}
 	}
 
+	// 启动时先检查一次, 保证Health在启动后就是准确的
+	p.checkHealth()
+	p.runningWg.Add(1)
+	go func() {
+		defer p.runningWg.Done()
+		p.pollHealth()
+	}()
+
+	// 组件启动后值不再变化, 每次启动时编译一次调用计划
+	if p.stream != nil {
+		compile(p.stream, p.injector, runProducers(p.sources))
+	}
+
 	c := p.newExecContext()
 	if err := c.Start(); err != nil {
 		return err
 	}
+	p.setExecContext(c)
 
 	p.runningWg.Add(1)
 	go func() {
@@ -207,6 +278,7 @@ func (p *pipeliner) start() error {
 				log.Error("Pipeline: %s, Panic: %s, Stack: %s",
 					p.Name(), r, string(debug.Stack()))
 			}
+			p.setExecContext(nil)
 			c.Stop()
 
 			p.monitor.Set(METRICS_KEY_PIPELINE_EXIT_TIME, monitor.Time(time.Now()))
@@ -216,6 +288,11 @@ func (p *pipeliner) start() error {
 
 		p.monitor.Set(METRICS_KEY_PIPELINE_START_TIME, monitor.Time(time.Now()))
 
+		if p.pushOnly() {
+			<-p.ctx.Done()
+			return
+		}
+
 		now := time.Now()
 		next := p.schedule.Next(now)
 		timer := time.NewTimer(next.Sub(now))
@@ -6,17 +6,21 @@ import (
 )
 
 var (
-	factory      executor.Factory  = &PipelinerFactory{}
-	_            executor.Executor = &pipeliner{}
-	sampleConfig                   = `
+	factory      executor.Factory        = &PipelinerFactory{}
+	_            executor.Executor       = &pipeliner{}
+	_            executor.SharedReferrer = &PipelinerFactory{}
+	sampleConfig                         = `
 name: test
-schedule: ""  # 为空时死循环调度，支持cron表达式
+schedule: ""  # 为空时死循环调度(有http_source等推送式组件时只由推送驱动)，支持cron表达式
 circuit_breaker_samples: 10 # 熔断采样数量
 circuit_breaker_rate: 0.6 # 熔断采样率
 bootstrap: true # 是否随进程启动而启动
+health_check_interval: 10s # 组件健康检查的间隔
+health_check_timeout: 3s # 单个组件健康检查的超时时间
 components: # 组件配置列表
 #  - test_component: |
 #    name: test
+#  - shared: my_kafka # 引用服务级别声明的共享组件
 processors: # 处理器配置列表
 #  - test_component: |
 #    name: test
@@ -52,5 +56,19 @@ func (f *PipelinerFactory) New(config string) (executor.Executor, error) {
 		return nil, err
 	}
 	p := NewPipelineByConfig(conf)
-	return p, p.Error()
+	if err = p.Error(); err != nil {
+		// 释放已经创建的组件和共享组件的引用, e.g. 组件创建成功但是处理器创建失败
+		p.Stop()
+		return nil, err
+	}
+	return p, nil
+}
+
+// SharedReferences returns the shared components referenced by the components of config.
+func (f *PipelinerFactory) SharedReferences(config string) ([]string, error) {
+	var conf Config
+	if err := yaml.Unmarshal([]byte(config), &conf); err != nil {
+		return nil, err
+	}
+	return conf.SharedReferences(), nil
 }
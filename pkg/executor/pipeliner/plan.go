@@ -0,0 +1,316 @@
+package pipeliner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+var (
+	contextType = inject.InterfaceOf((*context.Context)(nil))
+	monitorType = inject.InterfaceOf((*monitor.Monitor)(nil))
+)
+
+// plan is the invocation of a processor compiled from its signature,
+// Invoke walks the arguments, parses the tags and looks up the values on every call,
+// the plan does it once and only binds the values that change between calls.
+type plan struct {
+	fn      reflect.Value
+	args    []argPlan
+	value   int // 映射输出的返回值的下标, -1表示没有
+	err     int // error返回值的下标, -1表示没有
+	outputs []outputPlan
+}
+
+type argPlan struct {
+	typ    reflect.Type
+	name   string
+	fixed  reflect.Value // 整体注入且只由组件提供的参数
+	elem   reflect.Type  // inject结构体的类型, 整体注入的参数为nil
+	fields []fieldPlan
+	pool   *sync.Pool // 按值传递的inject结构体, Call会复制参数, 调用后可以复用
+}
+
+type fieldPlan struct {
+	index    int
+	typ      reflect.Type
+	name     string
+	optional bool
+	def      reflect.Value // 解析好的默认值
+	apply    bool          // 按类型, all或有validate的字段交给inject.ApplyField
+	fixed    reflect.Value // 只由组件提供的值
+}
+
+type outputPlan struct {
+	index int
+	typ   reflect.Type
+	name  string
+}
+
+// runProducers returns the values that are mapped for every run or input,
+// a field that one of them or an output of an ancestor can match is bound on every call.
+func runProducers(sources []component.Source) []producer {
+	run := []producer{
+		{typ: checkpointType, name: "Checkpoint"},
+		{typ: contextType, name: "Context"},
+		{typ: monitorType, name: "Monitor"},
+	}
+	for _, src := range sources {
+		for _, v := range src.InputTypes() {
+			run = append(run, producer{typ: v.Type(), name: v.Name()})
+		}
+	}
+	return run
+}
+
+// compile builds the plans of the stream tree, the values of the components are resolved from inj.
+// A stream whose processor cannot be compiled is invoked by reflection.
+func compile(s *Stream, inj inject.Injector, dynamic []producer) {
+	f := s.processor.Processor
+	if f == nil {
+		return
+	}
+
+	s.plan = nil
+	if _, ok := f.(processor.Typed); !ok {
+		s.plan = newPlan(f, s.processor.ParamNames, inj, dynamic)
+	}
+
+	outputs := make([]producer, 0, len(dynamic))
+	for _, out := range outputsOf(reflect.TypeOf(f)) {
+		outputs = append(outputs, producer{typ: out.Type, name: out.Name()})
+	}
+	outputs = append(outputs, dynamic...)
+
+	for _, child := range s.childs {
+		compile(child, inj, outputs)
+	}
+}
+
+func newPlan(f interface{}, names []string, inj inject.Injector, dynamic []producer) *plan {
+	t := reflect.TypeOf(f)
+	if t.Kind() != reflect.Func {
+		return nil
+	}
+
+	p := &plan{fn: reflect.ValueOf(f), value: -1, err: -1}
+	switch t.NumOut() {
+	case 1:
+		if t.Out(0).Implements(errorInterface) {
+			p.err = 0
+		} else {
+			p.value = 0
+		}
+	case 2:
+		p.value = 0
+		if t.Out(1).Implements(errorInterface) {
+			p.err = 1
+		}
+	default:
+		// 没有返回值或多于两个返回值时Invoke会报错, 保持一致
+		return nil
+	}
+
+	fixed := func(typ reflect.Type, name string) reflect.Value {
+		if len(match(dynamic, typ, name, true)) > 0 {
+			return reflect.Value{}
+		}
+		v, _ := inject.Fixed(inj, typ, name)
+		return v
+	}
+
+	for i := 0; i < t.NumIn(); i++ {
+		argType := t.In(i)
+		if !inject.IsInjectStruct(argType) {
+			name := inject.ParamName(names, i)
+			p.args = append(p.args, argPlan{
+				typ:   argType,
+				name:  name,
+				fixed: fixed(argType, name),
+			})
+			continue
+		}
+
+		elem := argType
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem != argType && argType.Elem() != elem {
+			// Invoke只会生成一级指针
+			return nil
+		}
+
+		arg := argPlan{typ: argType, elem: elem}
+		if argType == elem {
+			arg.pool = &sync.Pool{New: func() interface{} {
+				return reflect.New(elem).Interface()
+			}}
+		}
+		for k := 0; k < elem.NumField(); k++ {
+			structField := elem.Field(k)
+			ia := inject.GetInjectAnnotation(structField)
+			// 和Apply一样跳过未导出的字段
+			if !ia.Exists || structField.PkgPath != "" {
+				continue
+			}
+
+			_, validate := structField.Tag.Lookup(inject.ValidateTagKey)
+			field := fieldPlan{
+				index:    k,
+				typ:      structField.Type,
+				name:     ia.Name,
+				optional: ia.Options.Contains(inject.InjectTagOptionsOptional),
+				apply:    validate || ia.Name == "" || ia.Options.Contains(inject.InjectTagOptionsAll),
+			}
+			if def, ok := ia.Default(); ok && !field.apply {
+				// 错误的默认值由ApplyField在每次调用时报错
+				v, err := inject.ParseDefault(field.typ, def)
+				field.def, field.apply = v, err != nil
+			}
+			if !field.apply {
+				field.fixed = fixed(field.typ, field.name)
+			}
+			arg.fields = append(arg.fields, field)
+		}
+		p.args = append(p.args, arg)
+	}
+
+	if p.value != -1 {
+		for _, out := range fieldsOf(t.Out(p.value)) {
+			p.outputs = append(p.outputs, outputPlan{
+				index: out.StructField.Index[0],
+				typ:   out.Type,
+				name:  out.Name(),
+			})
+		}
+	}
+	return p
+}
+
+// call invokes the processor and maps its outputs like Invoke and MapValues.
+func (p *plan) call(inj inject.Injector) (inject.Injector, error) {
+	in := make([]reflect.Value, len(p.args))
+	for i, arg := range p.args {
+		if arg.elem == nil {
+			if arg.fixed.IsValid() {
+				in[i] = arg.fixed
+				continue
+			}
+
+			v, err := inject.Resolve(inj, arg.typ, arg.name)
+			if err != nil {
+				return nil, fmt.Errorf("Argument %d: %s", i, err)
+			}
+			in[i] = v
+			continue
+		}
+
+		v, err := arg.bind(inj)
+		if err != nil {
+			return nil, err
+		}
+		in[i] = v
+	}
+
+	vals := p.fn.Call(in)
+	for i, arg := range p.args {
+		if arg.pool != nil {
+			in[i].Set(reflect.Zero(arg.elem))
+			arg.pool.Put(in[i].Addr().Interface())
+		}
+	}
+	if p.err != -1 && !vals[p.err].IsNil() {
+		return nil, vals[p.err].Interface().(error)
+	}
+
+	// 输出的injector交给子流程后仍被引用, 不能复用
+	out := inject.New()
+	out.SetParent(inj)
+	if p.value == -1 {
+		return out, nil
+	}
+
+	v := vals[p.value]
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return out, nil
+		}
+		v = v.Elem()
+	}
+	for _, o := range p.outputs {
+		f := v.Field(o.index)
+		if o.typ.Kind() == reflect.Interface {
+			// 和MapTo一样映射接口中的具体值
+			f = f.Elem()
+		}
+		out.Set(o.typ, o.name, f)
+	}
+	return out, nil
+}
+
+// bind fills an inject struct with the fixed values and the values of inj,
+// the struct passed by value comes from the pool and is put back by call.
+func (arg argPlan) bind(inj inject.Injector) (reflect.Value, error) {
+	var ptr reflect.Value
+	if arg.pool != nil {
+		ptr = reflect.ValueOf(arg.pool.Get())
+	} else {
+		ptr = reflect.New(arg.elem)
+	}
+	v := ptr.Elem()
+	for _, f := range arg.fields {
+		if f.apply {
+			if err := inject.ApplyField(inj, v, f.index); err != nil {
+				return reflect.Value{}, err
+			}
+			continue
+		}
+
+		val := f.fixed
+		if !val.IsValid() {
+			var err error
+			val, err = inject.Find(inj, f.typ, f.name)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("Field %s: %s", arg.elem.Field(f.index).Name, err)
+			}
+			if !val.IsValid() {
+				if f.def.IsValid() {
+					v.Field(f.index).Set(f.def)
+					continue
+				}
+				if f.optional {
+					continue
+				}
+				return reflect.Value{}, fmt.Errorf("Value not found for type: %v name: %v", f.typ, f.name)
+			}
+		}
+		v.Field(f.index).Set(val)
+	}
+
+	if arg.typ.Kind() == reflect.Ptr {
+		return ptr, nil
+	}
+	return v, nil
+}
+
+func (f *Stream) callPlan(inj inject.Injector) (out inject.Injector, err error) {
+	defer f.Recover(func() {
+		if out == nil && err == nil {
+			err = errors.Errorf("Stream: %s, Panic", f.Name())
+		}
+	})
+
+	out, err = f.plan.call(inj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Stream: %s", f.Name())
+	}
+	return out, nil
+}
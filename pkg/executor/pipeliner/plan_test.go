@@ -0,0 +1,117 @@
+package pipeliner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/executor"
+)
+
+type planClient struct {
+	prefix string
+}
+
+type planRequest struct {
+	Client *planClient `inject:"client"`
+	Line   string      `inject:"line"`
+	Sep    string      `inject:"sep,default=:"`
+	Extra  string      `inject:"extra,optional"`
+}
+
+type planResponse struct {
+	Upper string `inject:"upper"`
+}
+
+func planProcessor(req planRequest) (*planResponse, error) {
+	return &planResponse{Upper: req.Client.prefix + req.Sep + strings.ToUpper(req.Line) + req.Extra}, nil
+}
+
+// newPlanStream returns a stream whose client is a component and line is an output of its parent.
+func newPlanStream(components inject.Injector) *Stream {
+	type lineResponse struct {
+		Line string `inject:"line"`
+	}
+	root := &Stream{processor: executor.Processor{
+		Name:      "read",
+		Processor: func() lineResponse { return lineResponse{} },
+	}}
+	s := &Stream{processor: executor.Processor{Name: "upper", Processor: planProcessor}}
+	root.Append(s)
+
+	compile(root, components, runProducers(nil))
+	return s
+}
+
+func TestStreamPlan(t *testing.T) {
+	components := inject.New()
+	components.Map(&planClient{prefix: "p"}, "client")
+	// 组件中同名的值会被父stream的输出覆盖, 不能固定下来
+	components.Map("component", "line")
+
+	s := newPlanStream(components)
+	if s.plan == nil {
+		t.Fatal("Expected the stream to be compiled")
+	}
+	fields := s.plan.args[0].fields
+	equal(t, fields[0].fixed.IsValid(), true)
+	equal(t, fields[1].fixed.IsValid(), false)
+	equal(t, fields[2].def.Interface(), ":")
+
+	// 复用的请求结构体在调用之间被清空, b没有extra
+	for _, line := range []string{"a", "b"} {
+		inj := inject.New()
+		inj.SetParent(components)
+		inj.Map(line, "line")
+		upper := "p:" + strings.ToUpper(line)
+		if line == "a" {
+			inj.Map("!", "extra")
+			upper += "!"
+		}
+
+		out, err := s.Call(inj)
+		handleErr(t, err)
+		equal(t, out.Get(reflect.TypeOf(""), "upper").Interface(), upper)
+
+		// 和反射调用的结果一致
+		val, err := s.Invoke(inj)
+		expected, err := handleResult(s.Name(), inj, val, err)
+		handleErr(t, err)
+		equal(t, out.Get(reflect.TypeOf(""), "upper").Interface(), expected.Get(reflect.TypeOf(""), "upper").Interface())
+	}
+
+	_, err := s.Call(inject.New())
+	equal(t, err.Error(), "Stream: upper: Value not found for type: string name: line")
+}
+
+func benchmarkStream(b *testing.B, call func(*Stream, inject.Injector) (inject.Injector, error)) {
+	components := inject.New()
+	components.Map(&planClient{prefix: "p"}, "client")
+	s := newPlanStream(components)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inj := inject.New()
+		inj.SetParent(components)
+		inj.Map("line", "line")
+		if _, err := call(s, inj); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 每次调用的分配中, 5次来自benchmarkStream创建的injector, 2次来自planProcessor本身.
+// plan复用按值传递的请求结构体, 缺少的默认值和optional字段不分配错误,
+// 剩下的是输出的injector(5次)和reflect.Value.Call.
+func BenchmarkStreamInvoke(b *testing.B) {
+	benchmarkStream(b, func(s *Stream, inj inject.Injector) (inject.Injector, error) {
+		val, err := s.Invoke(inj)
+		return handleResult(s.Name(), inj, val, err)
+	})
+}
+
+func BenchmarkStreamPlan(b *testing.B) {
+	benchmarkStream(b, (*Stream).Call)
+}
@@ -0,0 +1,164 @@
+package pipeliner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/component"
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+type pushRequest struct {
+	value string
+}
+
+type pushSource struct {
+	emit component.Emitter
+}
+
+func (s *pushSource) Instance() component.Instance {
+	return component.NewInstance("push", reflect.TypeOf(s), reflect.ValueOf(s), s)
+}
+
+func (s *pushSource) Start() error { return nil }
+
+func (s *pushSource) Stop() error { return nil }
+
+func (s *pushSource) SetEmitter(emit component.Emitter) { s.emit = emit }
+
+func (s *pushSource) InputTypes() []component.Instance {
+	return []component.Instance{
+		component.NewInstance("push", reflect.TypeOf(&pushRequest{}), reflect.ValueOf(&pushRequest{}), nil),
+	}
+}
+
+// push returns the result of the pipeline and the error of emitting.
+func (s *pushSource) push(value string) (result error, err error) {
+	req := &pushRequest{value: value}
+	done := make(chan error, 1)
+	err = s.emit(context.Background(), component.Input{
+		Values:   []component.Instance{component.NewInstance("push", reflect.TypeOf(req), reflect.ValueOf(req), req)},
+		OnCommit: func() { done <- nil },
+		OnAbort:  func(err error) { done <- err },
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case result = <-done:
+		return result, nil
+	case <-time.After(2 * time.Second):
+		return errors.New("timeout"), nil
+	}
+}
+
+func TestSource(t *testing.T) {
+	src := &pushSource{}
+	var invoked int32
+	handleErr(t, component.Register("test_push_source", component.NewFactory(
+		"", "", reflect.TypeOf(src),
+		func(string) (component.Component, error) { return src, nil },
+	)))
+	handleErr(t, processor.Register("test_push_processor", processor.NewFactoryWithProcessor(
+		"", "",
+		func(in struct {
+			Request *pushRequest `inject:"push"`
+		}) error {
+			atomic.AddInt32(&invoked, 1)
+			if in.Request.value == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	)))
+
+	p := NewPipelineByConfig(Config{
+		Name:       "test_source",
+		Components: []map[string]string{{"test_push_source": ""}},
+		Processors: []map[string]string{{"test_push_processor": ""}},
+		Stream:     StreamConfig{Name: "test_push_processor"},
+	})
+	// 依赖检查时注入的值来自InputTypes
+	handleErr(t, p.Error())
+
+	_, err := src.push("ok")
+	if err == nil {
+		t.Fatal("Expected an error when the pipeline is not running")
+	}
+
+	handleErr(t, p.Start())
+
+	res, err := src.push("ok")
+	handleErr(t, err)
+	handleErr(t, res)
+
+	res, _ = src.push("fail")
+	if res == nil || res.Error() != "Stream: test_push_processor: failed" {
+		t.Fatalf("Expected the input to be aborted, got: %v", res)
+	}
+
+	p.Stop()
+	// 没有配置schedule时只由Source推送的输入驱动
+	equal(t, atomic.LoadInt32(&invoked), int32(2))
+
+	_, err = src.push("ok")
+	if err == nil {
+		t.Fatal("Expected an error after the pipeline is stopped")
+	}
+}
+
+func TestNoOutput(t *testing.T) {
+	src := &pushSource{}
+	var childInvoked int32
+	handleErr(t, component.Register("test_no_output_source", component.NewFactory(
+		"", "", reflect.TypeOf(src),
+		func(string) (component.Component, error) { return src, nil },
+	)))
+	handleErr(t, processor.Register("test_no_output_processor", processor.NewFactoryWithProcessor(
+		"", "",
+		func(in struct {
+			Request *pushRequest `inject:"push"`
+		}) error {
+			if in.Request.value == "skip" {
+				return processor.ErrNoOutput
+			}
+			return nil
+		},
+	)))
+	handleErr(t, processor.Register("test_no_output_child", processor.NewFactoryWithProcessor(
+		"", "",
+		func() error {
+			atomic.AddInt32(&childInvoked, 1)
+			return nil
+		},
+	)))
+
+	p := NewPipelineByConfig(Config{
+		Name:       "test_no_output",
+		Components: []map[string]string{{"test_no_output_source": ""}},
+		Processors: []map[string]string{{"test_no_output_processor": ""}, {"test_no_output_child": ""}},
+		Stream: StreamConfig{
+			Name:   "test_no_output_processor",
+			Childs: []StreamConfig{{Name: "test_no_output_child"}},
+		},
+	})
+	handleErr(t, p.Error())
+	handleErr(t, p.Start())
+	defer p.Stop()
+
+	// 没有输出时输入直接完成, 子流程不会被调用
+	res, err := src.push("skip")
+	handleErr(t, err)
+	handleErr(t, res)
+	equal(t, atomic.LoadInt32(&childInvoked), int32(0))
+
+	res, err = src.push("ok")
+	handleErr(t, err)
+	handleErr(t, res)
+	equal(t, atomic.LoadInt32(&childInvoked), int32(1))
+}
@@ -21,6 +21,7 @@ type Stream struct {
 	parent    *Stream
 	childs    []*Stream
 	config    StreamConfig
+	plan      *plan // 启动时编译, 为nil时通过反射调用
 }
 
 func NewStream(conf StreamConfig, processors map[string]executor.Processor) (*Stream, error) {
@@ -151,6 +152,35 @@ func (f *Stream) Get(name string) (*Stream, bool) {
 	return f, ok
 }
 
+// Call invokes the processor and returns the injector of its outputs whose parent is inj,
+// a processor.Typed is called without reflection, others by the plan compiled at start if any.
+func (f *Stream) Call(inj inject.Injector) (inject.Injector, error) {
+	if t, ok := f.processor.Processor.(processor.Typed); ok {
+		return f.callTyped(t, inj)
+	}
+	if f.plan != nil {
+		return f.callPlan(inj)
+	}
+
+	val, err := f.Invoke(inj)
+	return handleResult(f.Name(), inj, val, err)
+}
+
+func (f *Stream) callTyped(t processor.Typed, inj inject.Injector) (out inject.Injector, err error) {
+	defer f.Recover(func() {
+		if out == nil && err == nil {
+			err = errors.Errorf("Stream: %s, Panic", f.Name())
+		}
+	})
+
+	newInj := inject.New()
+	newInj.SetParent(inj)
+	if err = t.Call(inj, newInj); err != nil {
+		return nil, errors.Wrapf(err, "Stream: %s", f.Name())
+	}
+	return newInj, nil
+}
+
 func (f *Stream) Invoke(inj inject.Injector) (outVal reflect.Value, err error) {
 	defer f.Recover(nil)
 
@@ -167,7 +197,7 @@ func (f *Stream) Invoke(inj inject.Injector) (outVal reflect.Value, err error) {
 	}
 
 	var vals []reflect.Value
-	vals, err = inj.Invoke(p)
+	vals, err = inject.InvokeWithNames(inj, p, f.processor.ParamNames)
 	if err != nil {
 		err = errors.Wrapf(err, "Stream: %s", f.Name())
 		return
@@ -4,32 +4,35 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/executor"
 )
 
 func TestStream(t *testing.T) {
-	f := &Stream{processor: Processor{Name: "root"}}
+	f := &Stream{processor: executor.Processor{Name: "root"}}
 
 	equalsSlice(t, travel(f, 0), []string{"root"})
 
-	err := f.AppendByParentName("root", &Stream{processor: Processor{Name: "step1"}})
+	err := f.AppendByParentName("root", &Stream{processor: executor.Processor{Name: "step1"}})
 	handleErr(t, err)
 
 	equalsSlice(t, travel(f, 0), []string{"root", "step1"})
 
-	err = f.InsertBefore("step1", &Stream{processor: Processor{Name: "step0"}})
+	err = f.InsertBefore("step1", &Stream{processor: executor.Processor{Name: "step0"}})
 	handleErr(t, err)
 
 	equalsSlice(t, travel(f, 0), []string{"root", "step0", "step1"})
 
-	err = f.InsertAfter("step1", &Stream{processor: Processor{Name: "step2"}})
+	err = f.InsertAfter("step1", &Stream{processor: executor.Processor{Name: "step2"}})
 	handleErr(t, err)
 	equalsSlice(t, travel(f, 0), []string{"root", "step0", "step1", "step2"})
 
-	err = f.InsertAfter("step2", &Stream{processor: Processor{Name: "step3"}})
+	err = f.InsertAfter("step2", &Stream{processor: executor.Processor{Name: "step3"}})
 	handleErr(t, err)
 	equalsSlice(t, travel(f, 0), []string{"root", "step0", "step1", "step2", "step3"})
 
-	err = f.AppendByParentName("step1", &Stream{processor: Processor{Name: "step1.5"}})
+	err = f.AppendByParentName("step1", &Stream{processor: executor.Processor{Name: "step1.5"}})
 	handleErr(t, err)
 	equalsSlice(t, travel(f, 0), []string{"root", "step0", "step1", "step1.5", "step2", "step3"})
 
@@ -61,3 +64,48 @@ func handleErr(t *testing.T, err error) {
 		t.Fatal(err)
 	}
 }
+
+type typedRequest struct {
+	Line string `inject:"line"`
+}
+
+type typedResponse struct {
+	Upper string `inject:"upper"`
+}
+
+// typedProcessor 和lotus-processor-gen生成的适配器一样实现processor.Typed
+type typedProcessor func(typedRequest) (typedResponse, error)
+
+func (f typedProcessor) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req typedRequest
+	req.Line = in.Get(reflect.TypeOf(""), "line").Interface().(string)
+	resp, err := f(req)
+	if err != nil {
+		return err
+	}
+	out.Set(reflect.TypeOf(""), "upper", reflect.ValueOf(resp.Upper))
+	return nil
+}
+
+func TestStreamCallTyped(t *testing.T) {
+	s := &Stream{processor: executor.Processor{
+		Name: "typed",
+		Processor: typedProcessor(func(req typedRequest) (typedResponse, error) {
+			if req.Line == "panic" {
+				panic(req.Line)
+			}
+			return typedResponse{Upper: strings.ToUpper(req.Line)}, nil
+		}),
+	}}
+
+	inj := inject.New()
+	inj.Map("a", "line")
+	out, err := s.Call(inj)
+	handleErr(t, err)
+	equal(t, out.Get(reflect.TypeOf(""), "upper").Interface(), "A")
+	equal(t, out.Get(reflect.TypeOf(""), "line").Interface(), "a")
+
+	inj.Map("panic", "line")
+	_, err = s.Call(inj)
+	equal(t, err.Error(), "Stream: typed, Panic")
+}
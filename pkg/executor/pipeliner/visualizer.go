@@ -8,7 +8,9 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os/exec"
+	"strconv"
 
+	"github.com/shima-park/lotus/pkg/executor"
 	"gopkg.in/yaml.v2"
 )
 
@@ -17,6 +19,8 @@ var (
 		"svg": DotVisualizer("svg"),
 		"png": DotVisualizer("png"),
 		"dot": DotGrgphVisualizer,
+		// 组件和处理器之间的依赖关系
+		"dependency": DependencyVisualizer,
 	}
 	supportedVisualizerTypes []string
 )
@@ -157,3 +161,62 @@ func buildRefRalationship(c StreamConfig, w io.Writer) {
 		buildRefRalationship(x, w)
 	}
 }
+
+// DependencyVisualizer writes the dependency graph as dot,
+// the inputs and outputs of a processor are grouped in a cluster and the missing inputs are red.
+func DependencyVisualizer(w io.Writer, p *pipeliner) error {
+	g := p.Graph()
+
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph {\n")
+	buffer.WriteString("  rankdir=LR;\n")
+	buffer.WriteString(`  node [fontname="Sans serif" fontsize="12"];` + "\n")
+
+	clusters := map[string][]executor.GraphNode{}
+	var processors []string
+	for _, n := range g.Nodes {
+		switch n.Kind {
+		case executor.GraphNodeProcessor:
+			processors = append(processors, n.Name)
+			clusters[n.Name] = append(clusters[n.Name], n)
+		case executor.GraphNodeInput, executor.GraphNodeOutput:
+			clusters[n.Processor] = append(clusters[n.Processor], n)
+		default:
+			buffer.WriteString("  " + dotNode(n) + "\n")
+		}
+	}
+
+	for i, name := range processors {
+		buffer.WriteString(fmt.Sprintf("  subgraph cluster_%d {\n", i))
+		buffer.WriteString("    label=" + strconv.Quote(name) + ";\n")
+		for _, n := range clusters[name] {
+			buffer.WriteString("    " + dotNode(n) + "\n")
+		}
+		buffer.WriteString("  }\n")
+	}
+
+	for _, e := range g.Edges {
+		buffer.WriteString(fmt.Sprintf("  %s -> %s [label=%s];\n",
+			strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(e.Type+"("+e.Name+")")))
+	}
+
+	buffer.WriteString("}")
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+func dotNode(n executor.GraphNode) string {
+	var label, attrs string
+	switch n.Kind {
+	case executor.GraphNodeProcessor:
+		label, attrs = n.Name, "shape=box style=bold"
+	case executor.GraphNodeInput, executor.GraphNodeOutput:
+		label, attrs = n.Field+"\n"+n.Type, "shape=ellipse"
+		if n.Missing {
+			attrs += " color=red"
+		}
+	default:
+		label, attrs = n.Name+"\n"+n.Type, "shape=component"
+	}
+	return fmt.Sprintf("%s [label=%s %s];", strconv.Quote(n.ID), strconv.Quote(label), attrs)
+}
@@ -15,6 +15,13 @@ type Factory interface {
 	New(config string) (Executor, error)
 }
 
+// SharedReferrer is implemented by the factories whose configs can reference the shared components,
+// the server runs the executors referencing them in its own process, see component.DeclareShared.
+type SharedReferrer interface {
+	// SharedReferences returns the names of the shared components referenced by config.
+	SharedReferences(config string) ([]string, error)
+}
+
 type FactoryFunc func(config string) (Executor, error)
 
 var registry = make(map[string]Factory)
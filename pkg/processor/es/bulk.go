@@ -0,0 +1,63 @@
+package es
+
+import (
+	"fmt"
+
+	"github.com/olivere/elastic/v7"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+var (
+	bulkFactory       processor.Factory = NewBulkFactory()
+	defaultBulkConfig                   = IndexConfig{
+		Index: "lotus",
+	}
+	bulkDescription = "adds the record to the *elastic.BulkProcessor injected by type, which indexes the records in batches"
+)
+
+func init() {
+	if err := processor.Register("es_bulk", bulkFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewBulkFactory() processor.Factory {
+	return processor.NewFactory(
+		defaultBulkConfig,
+		bulkDescription,
+		newBulk(defaultBulkConfig),
+		func(c string) (processor.Processor, error) {
+			return NewBulk(c)
+		})
+}
+
+type BulkRequest struct {
+	Record record.Record `inject:"record"`
+}
+
+func NewBulk(rawConfig string) (processor.Processor, error) {
+	conf := defaultBulkConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Index == "" {
+		return nil, fmt.Errorf("es_bulk: index cannot be empty")
+	}
+	return newBulk(conf), nil
+}
+
+// newBulk 批量写入的结果由es_bulk_processor组件的after回调处理, 这里只负责添加请求
+func newBulk(conf IndexConfig) func(BulkRequest, *elastic.BulkProcessor) error {
+	return func(req BulkRequest, bulk *elastic.BulkProcessor) error {
+		r := elastic.NewBulkIndexRequest().Index(conf.Index).Doc(req.Record)
+		if id := conf.id(req.Record); id != "" {
+			r = r.Id(id)
+		}
+		bulk.Add(r)
+		return nil
+	}
+}
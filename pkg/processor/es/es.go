@@ -0,0 +1,27 @@
+// Package es contains the processors that write the record to elasticsearch,
+// the client and the bulk processor are the es components injected by type, mark one primary if there are more.
+package es
+
+import (
+	"context"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+type IndexConfig struct {
+	Index   string `yaml:"index"`
+	IDField string `yaml:"id_field"` // 文档id所在的字段, 为空时由es生成id
+}
+
+type IndexRequest struct {
+	Ctx    context.Context `inject:"Context"`
+	Record record.Record   `inject:"record"`
+}
+
+func (c IndexConfig) id(r record.Record) string {
+	if c.IDField == "" {
+		return ""
+	}
+	id, _ := r.GetString(c.IDField)
+	return id
+}
@@ -0,0 +1,142 @@
+package es
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+type esRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+// newTestClient returns a client of a fake elasticsearch which records the requests.
+func newTestClient(t *testing.T) (*elastic.Client, func() []esRequest) {
+	var (
+		lock     sync.Mutex
+		requests []esRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		requests = append(requests, esRequest{r.Method, r.URL.Path, string(body)})
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/broken"):
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":{"type":"exception","reason":"broken"},"status":500}`))
+		case r.URL.Path == "/_bulk":
+			w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+		default:
+			w.Write([]byte(`{"_index":"lotus","_id":"1","result":"created"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := elastic.NewClient(elastic.SetURL(srv.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() []esRequest {
+		lock.Lock()
+		defer lock.Unlock()
+		return requests
+	}
+}
+
+func TestIndexConfig(t *testing.T) {
+	if _, err := NewIndex("index: ''"); err == nil {
+		t.Errorf("Expected empty index error")
+	}
+	if _, err := NewBulk("index: ''"); err == nil {
+		t.Errorf("Expected empty index error")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		config string
+		record record.Record
+		method string
+		path   string
+		err    string
+	}{
+		{"", record.Record{"id": "1"}, "POST", "/lotus/_doc/", ""},
+		{"index: users\nid_field: user.id", record.Record{"user": map[string]interface{}{"id": "u1"}}, "PUT", "/users/_doc/u1", ""},
+		// id字段不存在时由es生成id
+		{"id_field: id", record.Record{"name": "a"}, "POST", "/lotus/_doc/", ""},
+		{"index: broken", record.Record{"id": "1"}, "POST", "/broken/_doc/", "es_index: elastic: Error 500"},
+	}
+
+	for _, test := range tests {
+		client, requests := newTestClient(t)
+		p, err := NewIndex(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index := p.(func(IndexRequest, *elastic.Client) error)
+
+		err = index(IndexRequest{Ctx: context.Background(), Record: test.record}, client)
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%q: Expected error %s - Got %v", test.config, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%q: %s", test.config, err)
+		}
+
+		reqs := requests()
+		if len(reqs) != 1 || reqs[0].method != test.method || reqs[0].path != test.path {
+			t.Errorf("%q: Expected %s %s - Got %v", test.config, test.method, test.path, reqs)
+		}
+	}
+}
+
+func TestBulk(t *testing.T) {
+	tests := []struct {
+		config string
+		record record.Record
+		action string
+	}{
+		{"", record.Record{"id": "1"}, `{"index":{"_index":"lotus"}}`},
+		{"index: users\nid_field: id", record.Record{"id": "1"}, `{"index":{"_index":"users","_id":"1"}}`},
+	}
+
+	for _, test := range tests {
+		client, requests := newTestClient(t)
+		bulk, err := client.BulkProcessor().Do(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := NewBulk(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		add := p.(func(BulkRequest, *elastic.BulkProcessor) error)
+		if err = add(BulkRequest{Record: test.record}, bulk); err != nil {
+			t.Fatal(err)
+		}
+		if err = bulk.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		reqs := requests()
+		expected := test.action + "\n" + `{"id":"1"}` + "\n"
+		if len(reqs) != 1 || reqs[0].path != "/_bulk" || reqs[0].body != expected {
+			t.Errorf("%q: Expected _bulk %q - Got %v", test.config, expected, reqs)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package es
+
+import (
+	"fmt"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+var (
+	indexFactory       processor.Factory = NewIndexFactory()
+	defaultIndexConfig                   = IndexConfig{
+		Index: "lotus",
+	}
+	indexDescription = "indexes the record into elasticsearch by the *elastic.Client injected by type"
+)
+
+func init() {
+	if err := processor.Register("es_index", indexFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewIndexFactory() processor.Factory {
+	return processor.NewFactory(
+		defaultIndexConfig,
+		indexDescription,
+		newIndex(defaultIndexConfig),
+		func(c string) (processor.Processor, error) {
+			return NewIndex(c)
+		})
+}
+
+func (c IndexConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func NewIndex(rawConfig string) (processor.Processor, error) {
+	conf := defaultIndexConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Index == "" {
+		return nil, fmt.Errorf("es_index: index cannot be empty")
+	}
+	return newIndex(conf), nil
+}
+
+func newIndex(conf IndexConfig) func(IndexRequest, *elastic.Client) error {
+	return func(req IndexRequest, client *elastic.Client) error {
+		s := client.Index().Index(conf.Index).BodyJson(req.Record)
+		if id := conf.id(req.Record); id != "" {
+			s = s.Id(id)
+		}
+		_, err := s.Do(req.Ctx)
+		return errors.Wrap(err, "es_index")
+	}
+}
@@ -3,6 +3,8 @@ package processor
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/schema"
 )
 
 type FactoryTemplate struct {
@@ -10,10 +12,14 @@ type FactoryTemplate struct {
 	description  string
 	factoryFunc  FactoryFunc
 	example      interface{}
+	schema       *schema.Schema
 }
 
 func NewFactory(sampleConfig interface{}, description string, example interface{}, factoryFunc FactoryFunc) Factory {
-	var conf string
+	var (
+		conf string
+		s    *schema.Schema
+	)
 	if sampleConfig != nil {
 		t := reflect.TypeOf(sampleConfig)
 		if t.Kind() == reflect.String {
@@ -26,6 +32,10 @@ func NewFactory(sampleConfig interface{}, description string, example interface{
 				data, _ := m.Marshal()
 				conf = string(data)
 			}
+			// 文本的示例配置没有Schema
+			if s = schema.Of(sampleConfig); s != nil {
+				s.Description = description
+			}
 		}
 	}
 
@@ -34,6 +44,7 @@ func NewFactory(sampleConfig interface{}, description string, example interface{
 		description:  description,
 		factoryFunc:  factoryFunc,
 		example:      example,
+		schema:       s,
 	}
 }
 
@@ -58,3 +69,7 @@ func (f FactoryTemplate) New(config string) (Processor, error) {
 func (f FactoryTemplate) Example() Processor {
 	return f.example
 }
+
+func (f FactoryTemplate) Schema() *schema.Schema {
+	return f.schema
+}
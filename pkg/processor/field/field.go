@@ -0,0 +1,18 @@
+// Package field contains the processors that change the fields of the record by config,
+// the output is a copy of the record, the record consumed by the sibling streams is not changed.
+package field
+
+import (
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+//go:generate go run ../../../cmd/lotus-processor-gen -type FilterProcessor -request RecordRequest -response RecordResponse
+//go:generate go run ../../../cmd/lotus-processor-gen -type RenameProcessor -request RecordRequest -response RecordResponse
+
+type RecordRequest struct {
+	Record record.Record `inject:"record"`
+}
+
+type RecordResponse struct {
+	Record record.Record `inject:"record"`
+}
@@ -0,0 +1,59 @@
+package field
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+func newRecord() record.Record {
+	return record.Record{
+		"id":   "1",
+		"tmp":  true,
+		"user": map[string]interface{}{"name": "a", "password": "p"},
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := NewFilter("include: [id, user, missing]\nexclude: [user.password]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRecord()
+	res, err := filter(RecordRequest{Record: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := record.Record{"id": "1", "user": map[string]interface{}{"name": "a"}}
+	if !reflect.DeepEqual(res.Record, expected) {
+		t.Errorf("Expected %v - Got %v", expected, res.Record)
+	}
+	if !reflect.DeepEqual(r, newRecord()) {
+		t.Errorf("Expected the input record unchanged - Got %v", r)
+	}
+}
+
+func TestRename(t *testing.T) {
+	if _, err := NewRename("fields: {id: ''}"); err == nil {
+		t.Errorf("Expected empty field error")
+	}
+
+	rename, err := NewRename("fields: {user.name: username, id: user.id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := rename(RecordRequest{Record: newRecord()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := record.Record{
+		"tmp":      true,
+		"username": "a",
+		"user":     map[string]interface{}{"id": "1", "password": "p"},
+	}
+	if !reflect.DeepEqual(res.Record, expected) {
+		t.Errorf("Expected %v - Got %v", expected, res.Record)
+	}
+}
@@ -0,0 +1,68 @@
+package field
+
+import (
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+var (
+	filterFactory       processor.Factory = NewFilterFactory()
+	defaultFilterConfig                   = FilterConfig{}
+	filterDescription                     = "keeps the include fields of the record and removes the exclude fields, e.g. user.name"
+)
+
+func init() {
+	if err := processor.Register("field_filter", filterFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewFilterFactory() processor.Factory {
+	return processor.NewTypedFactory(
+		defaultFilterConfig,
+		filterDescription,
+		FilterProcessor(nil),
+		func(c string) (processor.Typed, error) {
+			return NewFilter(c)
+		})
+}
+
+type FilterConfig struct {
+	Include []string `yaml:"include"` // 为空时保留所有字段
+	Exclude []string `yaml:"exclude"` // 在include之后删除
+}
+
+func (c FilterConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func NewFilter(rawConfig string) (FilterProcessor, error) {
+	conf := defaultFilterConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req RecordRequest) (RecordResponse, error) {
+		var r record.Record
+		if len(conf.Include) > 0 {
+			r = record.Record{}
+			for _, path := range conf.Include {
+				if v, ok := req.Record.Get(path); ok {
+					r.Set(path, v)
+				}
+			}
+			// 只复制了保留的值, 嵌套的对象仍然是共享的
+			r = r.Copy()
+		} else {
+			r = req.Record.Copy()
+		}
+
+		for _, path := range conf.Exclude {
+			r.Delete(path)
+		}
+		return RecordResponse{Record: r}, nil
+	}, nil
+}
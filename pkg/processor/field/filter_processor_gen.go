@@ -0,0 +1,40 @@
+// Code generated by lotus-processor-gen. DO NOT EDIT.
+
+package field
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+// FilterProcessor is the typed processor of RecordRequest and RecordResponse.
+type FilterProcessor func(RecordRequest) (RecordResponse, error)
+
+var (
+	_ processor.Typed = FilterProcessor(nil)
+
+	filterProcessorTypes = [...]reflect.Type{
+		reflect.TypeOf((*record.Record)(nil)).Elem(),
+		reflect.TypeOf((*record.Record)(nil)).Elem(),
+	}
+)
+
+func (f FilterProcessor) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req RecordRequest
+	if v, err := inject.Resolve(in, filterProcessorTypes[0], "record"); err == nil {
+		req.Record = v.Interface().(record.Record)
+	} else {
+		return fmt.Errorf("Field Record: %s", err)
+	}
+
+	resp, err := f(req)
+	if err != nil {
+		return err
+	}
+	out.Set(filterProcessorTypes[1], "record", reflect.ValueOf(resp.Record))
+	return nil
+}
@@ -0,0 +1,70 @@
+package field
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+var (
+	renameFactory       processor.Factory = NewRenameFactory()
+	defaultRenameConfig                   = RenameConfig{
+		Fields: map[string]string{},
+	}
+	renameDescription = "renames the fields of the record, e.g. user.name: username"
+)
+
+func init() {
+	if err := processor.Register("field_rename", renameFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewRenameFactory() processor.Factory {
+	return processor.NewTypedFactory(
+		defaultRenameConfig,
+		renameDescription,
+		RenameProcessor(nil),
+		func(c string) (processor.Typed, error) {
+			return NewRename(c)
+		})
+}
+
+type RenameConfig struct {
+	Fields map[string]string `yaml:"fields"` // key: 原字段, value: 新字段
+}
+
+func (c RenameConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func NewRename(rawConfig string) (RenameProcessor, error) {
+	conf := defaultRenameConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按原字段排序, 保证重命名的顺序是确定的
+	var from []string
+	for k, v := range conf.Fields {
+		if k == "" || v == "" {
+			return nil, fmt.Errorf("field_rename: field cannot be empty, %q: %q", k, v)
+		}
+		from = append(from, k)
+	}
+	sort.Strings(from)
+
+	return func(req RecordRequest) (RecordResponse, error) {
+		r := req.Record.Copy()
+		for _, k := range from {
+			if v, ok := r.Delete(k); ok {
+				r.Set(conf.Fields[k], v)
+			}
+		}
+		return RecordResponse{Record: r}, nil
+	}, nil
+}
@@ -0,0 +1,354 @@
+// Package gen generates the adapters of typed processors,
+// go 1.14 has no generics, so processor.Typed is implemented by a generated func type per request and response.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+)
+
+type Options struct {
+	Dir      string // 请求和响应结构体所在的包目录
+	Type     string // 生成的func类型的名字
+	Request  string // 请求结构体的名字, 至少有一个inject字段
+	Response string // 响应结构体的名字, 为空时处理器只返回error
+	Output   string // 生成的文件名, 默认是<type>_gen.go, 生成时会忽略该文件
+}
+
+// OutputName returns the name of the generated file.
+func OutputName(o Options) string {
+	if o.Output != "" {
+		return o.Output
+	}
+	return snake(o.Type) + "_gen.go"
+}
+
+type field struct {
+	Index      int // 结构体中字段的下标
+	TypeIndex  int // 生成的类型数组中的下标
+	Name       string
+	Type       string
+	InjectName string
+	Optional   bool
+	Simple     bool // 只有名字和optional的字段直接注入, 其他字段交给inject.ApplyField
+}
+
+type data struct {
+	Package    string
+	StdImports []string
+	Imports    []string
+	Type       string
+	Types      string // 类型数组的变量名
+	Request    string
+	Response   string
+	In         []field
+	Out        []field
+	AllTypes   []string
+}
+
+// Generate returns the formatted source of the adapter.
+func Generate(opts Options) ([]byte, error) {
+	if opts.Type == "" || opts.Request == "" {
+		return nil, fmt.Errorf("Type and request cannot be empty")
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, opts.Dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != OutputName(opts)
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("Expected one package in %s, got %d", opts.Dir, len(pkgs))
+	}
+
+	d := data{
+		Type:     opts.Type,
+		Types:    lowerFirst(opts.Type) + "Types",
+		Request:  opts.Request,
+		Response: opts.Response,
+	}
+	imports := map[string]bool{}
+
+	for name, pkg := range pkgs {
+		d.Package = name
+
+		d.In, err = structFields(pkg, opts.Request, imports)
+		if err != nil {
+			return nil, err
+		}
+		if len(d.In) == 0 {
+			return nil, fmt.Errorf("Request %s has no inject fields", opts.Request)
+		}
+
+		if opts.Response != "" {
+			d.Out, err = structFields(pkg, opts.Response, imports)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 交给inject.ApplyField的字段不需要类型
+	for i := range d.In {
+		if d.In[i].Simple {
+			d.In[i].TypeIndex = len(d.AllTypes)
+			d.AllTypes = append(d.AllTypes, d.In[i].Type)
+		}
+	}
+	for i := range d.Out {
+		d.Out[i].TypeIndex = len(d.AllTypes)
+		d.AllTypes = append(d.AllTypes, d.Out[i].Type)
+	}
+
+	std := map[string]bool{`"fmt"`: true, `"reflect"`: true}
+	others := map[string]bool{
+		`"github.com/shima-park/lotus/pkg/common/inject"`: true,
+		`"github.com/shima-park/lotus/pkg/processor"`:     true,
+	}
+	for imp := range imports {
+		// 标准库的路径第一段没有点
+		p := imp[strings.Index(imp, `"`)+1:]
+		if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+			others[imp] = true
+		} else {
+			std[imp] = true
+		}
+	}
+	d.StdImports, d.Imports = sortedKeys(std), sortedKeys(others)
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Format generated source: %s\n%s", err, buf.String())
+	}
+	return src, nil
+}
+
+// structFields returns the inject fields of the struct declared in pkg,
+// and adds the imports used by the types of the fields.
+func structFields(pkg *ast.Package, name string, imports map[string]bool) ([]field, error) {
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != name {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					return nil, fmt.Errorf("%s is not a struct", name)
+				}
+				return fieldsOf(name, st, file, imports)
+			}
+		}
+	}
+	return nil, fmt.Errorf("Not found struct %s", name)
+}
+
+func fieldsOf(name string, st *ast.StructType, file *ast.File, imports map[string]bool) ([]field, error) {
+	var (
+		fields []field
+		index  int
+	)
+	for _, f := range st.Fields.List {
+		names := f.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil} // 嵌入字段
+		}
+
+		for _, n := range names {
+			i := index
+			index++
+
+			var tag reflect.StructTag
+			if f.Tag != nil {
+				s, err := strconv.Unquote(f.Tag.Value)
+				if err != nil {
+					return nil, err
+				}
+				tag = reflect.StructTag(s)
+			}
+
+			if _, ok := tag.Lookup(inject.InjectTagKey); !ok {
+				continue
+			}
+			if n == nil {
+				return nil, fmt.Errorf("%s: embedded inject fields are not supported", name)
+			}
+			// 和inject.Apply一样忽略未导出的字段
+			if !n.IsExported() {
+				continue
+			}
+			ia := inject.GetInjectAnnotation(reflect.StructField{Name: n.Name, Tag: tag})
+
+			_, validate := tag.Lookup(inject.ValidateTagKey)
+			fields = append(fields, field{
+				Index:      i,
+				Name:       n.Name,
+				Type:       types.ExprString(f.Type),
+				InjectName: ia.Name,
+				Optional:   ia.Options.Contains(inject.InjectTagOptionsOptional),
+				Simple: !validate && ia.Name != "" &&
+					(ia.Options == "" || ia.Options == inject.InjectTagOptionsOptional),
+			})
+			addImports(f.Type, file, imports)
+		}
+	}
+	return fields, nil
+}
+
+// addImports adds the imports of file used by the selectors of expr, e.g. io.Reader.
+func addImports(expr ast.Expr, file *ast.File, imports map[string]bool) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		for _, imp := range file.Imports {
+			p, _ := strconv.Unquote(imp.Path.Value)
+			if imp.Name != nil {
+				if imp.Name.Name == x.Name {
+					imports[imp.Name.Name+" "+imp.Path.Value] = true
+				}
+			} else if packageName(p) == x.Name {
+				imports[imp.Path.Value] = true
+			}
+		}
+		return false
+	})
+}
+
+// packageName guesses the name of a package by its path, e.g. gopkg.in/yaml.v2 is yaml,
+// imports whose name cannot be guessed should be named explicitly.
+func packageName(p string) string {
+	name := path.Base(p)
+	if idx := strings.Index(name, ".v"); idx > 0 {
+		name = name[:idx]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.Replace(name, "-", "", -1)
+}
+
+// sortedKeys sorts the imports by path like goimports.
+func sortedKeys(m map[string]bool) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i][strings.Index(list[i], `"`):] < list[j][strings.Index(list[j], `"`):]
+	})
+	return list
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func snake(s string) string {
+	var buf bytes.Buffer
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				buf.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+var tmpl = template.Must(template.New("adapter").Parse(`// Code generated by lotus-processor-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports}}
+	{{.}}
+{{- end}}
+{{range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+// {{.Type}} is the typed processor of {{.Request}}{{if .Response}} and {{.Response}}{{end}}.
+type {{.Type}} func({{.Request}}) {{if .Response}}({{.Response}}, error){{else}}error{{end}}
+
+var (
+	_ processor.Typed = {{.Type}}(nil)
+
+	{{.Types}} = [...]reflect.Type{
+	{{- range .AllTypes}}
+		reflect.TypeOf((*{{.}})(nil)).Elem(),
+	{{- end}}
+	}
+)
+
+func (f {{.Type}}) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req {{.Request}}
+{{- range .In}}
+{{- if .Simple}}
+	if v, err := inject.Resolve(in, {{$.Types}}[{{.TypeIndex}}], {{printf "%q" .InjectName}}); err == nil {
+		req.{{.Name}} = v.Interface().({{.Type}})
+{{- if .Optional}}
+	} else if _, ok := err.(inject.NotFoundError); !ok {
+		return fmt.Errorf("Field {{.Name}}: %s", err)
+	}
+{{- else}}
+	} else {
+		return fmt.Errorf("Field {{.Name}}: %s", err)
+	}
+{{- end}}
+{{- else}}
+	if err := inject.ApplyField(in, reflect.ValueOf(&req).Elem(), {{.Index}}); err != nil {
+		return err
+	}
+{{- end}}
+{{- end}}
+
+{{if .Response}}
+	resp, err := f(req)
+	if err != nil {
+		return err
+	}
+{{- range .Out}}
+	out.Set({{$.Types}}[{{.TypeIndex}}], {{printf "%q" .InjectName}}, reflect.ValueOf(resp.{{.Name}}))
+{{- end}}
+	return nil
+{{- else}}
+	return f(req)
+{{- end}}
+}
+`))
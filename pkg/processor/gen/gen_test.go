@@ -0,0 +1,47 @@
+package gen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	opts := Options{
+		Dir:      filepath.Join("internal", "sample"),
+		Type:     "ReadProcessor",
+		Request:  "ReadRequest",
+		Response: "ReadResponse",
+	}
+	src, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 提交的生成文件需要和生成器保持一致, 修改生成器后执行go generate ./pkg/processor/gen/...
+	golden, err := ioutil.ReadFile(filepath.Join(opts.Dir, OutputName(opts)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != string(golden) {
+		t.Fatalf("Generated source is out of date:\n%s", src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := filepath.Join("internal", "sample")
+	for _, c := range []struct {
+		opts Options
+		err  string
+	}{
+		{Options{Dir: dir, Type: "P", Request: "NotFound"}, "Not found struct NotFound"},
+		{Options{Dir: dir, Type: "P", Request: "ReadResponse", Response: "NotFound"}, "Not found struct NotFound"},
+		{Options{Dir: dir, Type: "P"}, "cannot be empty"},
+	} {
+		_, err := Generate(c.opts)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("Expected error %s - Got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Code generated by lotus-processor-gen. DO NOT EDIT.
+
+package sample
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+// ReadProcessor is the typed processor of ReadRequest and ReadResponse.
+type ReadProcessor func(ReadRequest) (ReadResponse, error)
+
+var (
+	_ processor.Typed = ReadProcessor(nil)
+
+	readProcessorTypes = [...]reflect.Type{
+		reflect.TypeOf((*io.Reader)(nil)).Elem(),
+		reflect.TypeOf((*string)(nil)).Elem(),
+		reflect.TypeOf((*string)(nil)).Elem(),
+		reflect.TypeOf((*time.Duration)(nil)).Elem(),
+	}
+)
+
+func (f ReadProcessor) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req ReadRequest
+	if v, err := inject.Resolve(in, readProcessorTypes[0], "reader"); err == nil {
+		req.Reader = v.Interface().(io.Reader)
+	} else {
+		return fmt.Errorf("Field Reader: %s", err)
+	}
+	if v, err := inject.Resolve(in, readProcessorTypes[1], "prefix"); err == nil {
+		req.Prefix = v.Interface().(string)
+	} else if _, ok := err.(inject.NotFoundError); !ok {
+		return fmt.Errorf("Field Prefix: %s", err)
+	}
+	if err := inject.ApplyField(in, reflect.ValueOf(&req).Elem(), 2); err != nil {
+		return err
+	}
+	if err := inject.ApplyField(in, reflect.ValueOf(&req).Elem(), 3); err != nil {
+		return err
+	}
+
+	resp, err := f(req)
+	if err != nil {
+		return err
+	}
+	out.Set(readProcessorTypes[2], "line", reflect.ValueOf(resp.Line))
+	out.Set(readProcessorTypes[3], "timeout", reflect.ValueOf(resp.Timeout))
+	return nil
+}
@@ -0,0 +1,36 @@
+// Package sample is the processor generated in the tests of lotus-processor-gen.
+package sample
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+//go:generate go run ../../../../../cmd/lotus-processor-gen -type ReadProcessor -request ReadRequest -response ReadResponse
+
+type ReadRequest struct {
+	Reader  io.Reader     `inject:"reader"`
+	Prefix  string        `inject:"prefix,optional"`
+	Timeout time.Duration `inject:"timeout,default=5s"`
+	Writers []io.Writer   `inject:",all,optional"`
+	ignored int
+}
+
+type ReadResponse struct {
+	Line    string        `inject:"line"`
+	Timeout time.Duration `inject:"timeout"`
+}
+
+func Read(req ReadRequest) (ReadResponse, error) {
+	b, err := ioutil.ReadAll(req.Reader)
+	if err != nil {
+		return ReadResponse{}, err
+	}
+	for _, w := range req.Writers {
+		if _, err = w.Write(b); err != nil {
+			return ReadResponse{}, err
+		}
+	}
+	return ReadResponse{Line: req.Prefix + string(b), Timeout: req.Timeout}, nil
+}
@@ -0,0 +1,49 @@
+package sample
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+func TestReadProcessor(t *testing.T) {
+	p := ReadProcessor(Read)
+
+	sig, err := processor.SignatureOf(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sig.Typed || len(sig.Request) != 4 || len(sig.Response) != 2 {
+		t.Fatalf("Unexpected signature: %+v", sig)
+	}
+
+	var w strings.Builder
+	in := inject.New()
+	in.MapTo(strings.NewReader("line"), "reader", (*io.Reader)(nil))
+	in.Map("> ", "prefix")
+	in.MapTo(&w, "w", (*io.Writer)(nil))
+
+	out := inject.New()
+	if err = p.Call(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if line := out.Get(reflect.TypeOf(""), "line").Interface(); line != "> line" {
+		t.Fatalf("Expected > line - Got %v", line)
+	}
+	if timeout := out.Get(reflect.TypeOf(time.Second), "timeout").Interface(); timeout != 5*time.Second {
+		t.Fatalf("Expected default timeout 5s - Got %v", timeout)
+	}
+	if w.String() != "line" {
+		t.Fatalf("Expected all writers injected - Got %q", w.String())
+	}
+
+	err = p.Call(inject.New(), out)
+	if err == nil || !strings.HasPrefix(err.Error(), "Field Reader: Value not found") {
+		t.Fatalf("Expected missing reader - Got %v", err)
+	}
+}
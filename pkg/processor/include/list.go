@@ -0,0 +1,10 @@
+package include
+
+import (
+	_ "github.com/shima-park/lotus/pkg/processor/es"
+	_ "github.com/shima-park/lotus/pkg/processor/field"
+	_ "github.com/shima-park/lotus/pkg/processor/io"
+	_ "github.com/shima-park/lotus/pkg/processor/json"
+	_ "github.com/shima-park/lotus/pkg/processor/kafka"
+	_ "github.com/shima-park/lotus/pkg/processor/redis"
+)
@@ -0,0 +1,127 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+func TestLineReader(t *testing.T) {
+	p, err := NewLineReader("max_line_size: 4\neof_delay: 0s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, names := processor.Unwrap(p)
+	if len(names) != 1 || names[0] != "Context" {
+		t.Fatalf("Expected the param names [Context] - Got %v", names)
+	}
+	readCtx := f.(func(context.Context, io.Reader) (LineReaderResponse, error))
+	read := func(r io.Reader) (LineReaderResponse, error) {
+		return readCtx(context.Background(), r)
+	}
+
+	r := strings.NewReader("a\n\nbc\nlong line\n")
+	for _, expected := range []string{"a", "bc"} {
+		res, err := read(r)
+		if err != nil || res.Line != expected {
+			t.Errorf("Expected %s - Got %s %v", expected, res.Line, err)
+		}
+	}
+	if _, err = read(r); err == nil || err == io.EOF {
+		t.Errorf("Expected too long error - Got %v", err)
+	}
+
+	// 新的reader从头开始读
+	res, err := read(strings.NewReader("d"))
+	if err != nil || res.Line != "d" {
+		t.Errorf("Expected d - Got %s %v", res.Line, err)
+	}
+	if _, err = read(strings.NewReader("")); err != processor.ErrNoOutput {
+		t.Errorf("Expected %v - Got %v", processor.ErrNoOutput, err)
+	}
+
+	// 不可比较的reader类型不会panic
+	lines := strings.NewReader("e\nf\n")
+	fr := funcReader(lines.Read)
+	for _, expected := range []string{"e", "f"} {
+		res, err := read(fr)
+		if err != nil || res.Line != expected {
+			t.Errorf("Expected %s - Got %s %v", expected, res.Line, err)
+		}
+	}
+	if _, err = read(fr); err != processor.ErrNoOutput {
+		t.Errorf("Expected %v - Got %v", processor.ErrNoOutput, err)
+	}
+}
+
+func TestLineReaderEOFDelay(t *testing.T) {
+	p, err := NewLineReader("eof_delay: 50ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := processor.Unwrap(p)
+	read := f.(func(context.Context, io.Reader) (LineReaderResponse, error))
+
+	// 读到结尾后等待eof_delay, 默认的调度间隔为0时不会空转
+	r := strings.NewReader("a\n")
+	if res, err := read(context.Background(), r); err != nil || res.Line != "a" {
+		t.Fatalf("Expected a - Got %s %v", res.Line, err)
+	}
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err = read(context.Background(), r); err != processor.ErrNoOutput {
+			t.Fatalf("Expected %v - Got %v", processor.ErrNoOutput, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("Expected to wait eof_delay at the end of input - Got %s", elapsed)
+	}
+
+	// 停止时不再等待
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	if _, err = read(ctx, r); err != processor.ErrNoOutput {
+		t.Fatalf("Expected %v - Got %v", processor.ErrNoOutput, err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("Expected to return when ctx is done - Got %s", elapsed)
+	}
+}
+
+type funcReader func(p []byte) (int, error)
+
+func (f funcReader) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestStdout(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stdout = buf
+	defer func() { stdout = os.Stdout }()
+
+	if _, err := NewStdout("source: value"); err == nil {
+		t.Errorf("Expected unknown source error")
+	}
+
+	p, err := NewStdout("source: record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p(StdoutRequest{Record: record.Record{"a": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p(StdoutRequest{Line: "x"}); err == nil {
+		t.Errorf("Expected no record error")
+	}
+	if buf.String() != "{\"a\":\"b\"}\n" {
+		t.Errorf("Expected the record - Got %q", buf.String())
+	}
+}
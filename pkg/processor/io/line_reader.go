@@ -0,0 +1,145 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+)
+
+var (
+	lineReaderFactory       processor.Factory = NewLineReaderFactory()
+	defaultLineReaderConfig                   = LineReaderConfig{
+		MaxLineSize: 1 << 20,
+		SkipEmpty:   true,
+		EOFDelay:    time.Second,
+	}
+	lineReaderDescription = "reads a line from the io.Reader injected by type on every run, e.g. the io_reader component, " +
+		"has no output at the end of the input and waits eof_delay before returning"
+)
+
+func init() {
+	if err := processor.Register("line_reader", lineReaderFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewLineReaderFactory() processor.Factory {
+	return processor.NewFactory(
+		defaultLineReaderConfig,
+		lineReaderDescription,
+		(&lineReader{}).Read,
+		func(c string) (processor.Processor, error) {
+			return NewLineReader(c)
+		})
+}
+
+type LineReaderConfig struct {
+	MaxLineSize int           `yaml:"max_line_size"` // 超过该长度的行会返回错误
+	SkipEmpty   bool          `yaml:"skip_empty"`    // 跳过空行
+	EOFDelay    time.Duration `yaml:"eof_delay"`     // 读到结尾后等待的时间, 避免调度间隔为0时空转
+}
+
+func (c LineReaderConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type LineReaderResponse struct {
+	Line string `inject:"line"`
+}
+
+type lineReader struct {
+	config  LineReaderConfig
+	lock    sync.Mutex // replica大于1时并发读取
+	reader  io.Reader
+	scanner *bufio.Scanner
+}
+
+func NewLineReader(rawConfig string) (processor.Processor, error) {
+	conf := defaultLineReaderConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	lr := &lineReader{config: conf}
+	return processor.WithParamNames(lr.Read, "Context")
+}
+
+// Read returns the next line of r, the reader is injected as a whole by type.
+// At the end of r it waits eof_delay or until ctx is done and returns processor.ErrNoOutput,
+// so that the child streams are skipped and the default schedule does not spin on the empty input.
+func (lr *lineReader) Read(ctx context.Context, r io.Reader) (LineReaderResponse, error) {
+	res, err := lr.next(r)
+	if err == processor.ErrNoOutput && lr.config.EOFDelay > 0 {
+		timer := time.NewTimer(lr.config.EOFDelay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	return res, err
+}
+
+func (lr *lineReader) next(r io.Reader) (LineReaderResponse, error) {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	// 组件重新创建后从新的reader开始读
+	if lr.scanner == nil || !sameReader(lr.reader, r) {
+		lr.reader = r
+		lr.scanner = bufio.NewScanner(r)
+		// scanner的最大长度是max和初始buffer容量中较大的一个
+		size := 64 * 1024
+		if lr.config.MaxLineSize < size {
+			size = lr.config.MaxLineSize
+		}
+		lr.scanner.Buffer(make([]byte, 0, size), lr.config.MaxLineSize)
+	}
+
+	for lr.scanner.Scan() {
+		line := lr.scanner.Text()
+		if line == "" && lr.config.SkipEmpty {
+			continue
+		}
+		return LineReaderResponse{Line: line}, nil
+	}
+
+	if err := lr.scanner.Err(); err != nil {
+		return LineReaderResponse{}, err
+	}
+	return LineReaderResponse{}, processor.ErrNoOutput
+}
+
+// sameReader compares the readers without panicking on non-comparable types,
+// which compare by pointer if possible and are otherwise treated as new readers.
+func sameReader(a, b io.Reader) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) {
+		return false
+	}
+	if t.Comparable() {
+		return a == b
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch t.Kind() {
+	case reflect.Map, reflect.Func:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package io
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+//go:generate go run ../../../cmd/lotus-processor-gen -type StdoutProcessor -request StdoutRequest
+
+var (
+	stdoutFactory       processor.Factory = NewStdoutFactory()
+	defaultStdoutConfig                   = StdoutConfig{
+		Source: record.LineName,
+	}
+	stdoutDescription = "writes the line or the record encoded as json to stdout, one per line"
+
+	stdout     io.Writer = os.Stdout
+	stdoutLock sync.Mutex
+)
+
+func init() {
+	if err := processor.Register("stdout", stdoutFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewStdoutFactory() processor.Factory {
+	return processor.NewTypedFactory(
+		defaultStdoutConfig,
+		stdoutDescription,
+		StdoutProcessor(nil),
+		func(c string) (processor.Typed, error) {
+			return NewStdout(c)
+		})
+}
+
+type StdoutConfig struct {
+	Source string `yaml:"source"` // line或者record
+}
+
+func (c StdoutConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type StdoutRequest struct {
+	Line   string        `inject:"line,optional"`
+	Record record.Record `inject:"record,optional"`
+}
+
+func NewStdout(rawConfig string) (StdoutProcessor, error) {
+	conf := defaultStdoutConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+	if err = record.CheckSource(conf.Source); err != nil {
+		return nil, errors.Wrap(err, "stdout")
+	}
+
+	return func(req StdoutRequest) error {
+		data, err := record.Payload(conf.Source, req.Line, req.Record)
+		if err != nil {
+			return errors.Wrap(err, "stdout")
+		}
+
+		// 多个stream并发写时保证每行完整
+		stdoutLock.Lock()
+		defer stdoutLock.Unlock()
+		_, err = stdout.Write(append(data, '\n'))
+		return err
+	}, nil
+}
@@ -0,0 +1,40 @@
+// Code generated by lotus-processor-gen. DO NOT EDIT.
+
+package io
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+// StdoutProcessor is the typed processor of StdoutRequest.
+type StdoutProcessor func(StdoutRequest) error
+
+var (
+	_ processor.Typed = StdoutProcessor(nil)
+
+	stdoutProcessorTypes = [...]reflect.Type{
+		reflect.TypeOf((*string)(nil)).Elem(),
+		reflect.TypeOf((*record.Record)(nil)).Elem(),
+	}
+)
+
+func (f StdoutProcessor) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req StdoutRequest
+	if v, err := inject.Resolve(in, stdoutProcessorTypes[0], "line"); err == nil {
+		req.Line = v.Interface().(string)
+	} else if _, ok := err.(inject.NotFoundError); !ok {
+		return fmt.Errorf("Field Line: %s", err)
+	}
+	if v, err := inject.Resolve(in, stdoutProcessorTypes[1], "record"); err == nil {
+		req.Record = v.Interface().(record.Record)
+	} else if _, ok := err.(inject.NotFoundError); !ok {
+		return fmt.Errorf("Field Record: %s", err)
+	}
+
+	return f(req)
+}
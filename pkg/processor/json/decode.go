@@ -0,0 +1,76 @@
+package json
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+//go:generate go run ../../../cmd/lotus-processor-gen -type DecodeProcessor -request DecodeRequest -response DecodeResponse
+
+var (
+	decodeFactory       processor.Factory = NewDecodeFactory()
+	defaultDecodeConfig                   = DecodeConfig{}
+	decodeDescription                     = "decodes the json object of the line into the record"
+)
+
+func init() {
+	if err := processor.Register("json_decode", decodeFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewDecodeFactory() processor.Factory {
+	return processor.NewTypedFactory(
+		defaultDecodeConfig,
+		decodeDescription,
+		DecodeProcessor(nil),
+		func(c string) (processor.Typed, error) {
+			return NewDecode(c)
+		})
+}
+
+type DecodeConfig struct {
+	UseNumber bool `yaml:"use_number"` // 数字解码为json.Number而不是float64, 避免大整数丢失精度
+}
+
+func (c DecodeConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type DecodeRequest struct {
+	Line string `inject:"line"`
+}
+
+type DecodeResponse struct {
+	Record record.Record `inject:"record"`
+}
+
+func NewDecode(rawConfig string) (DecodeProcessor, error) {
+	conf := defaultDecodeConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req DecodeRequest) (DecodeResponse, error) {
+		dec := json.NewDecoder(strings.NewReader(req.Line))
+		if conf.UseNumber {
+			dec.UseNumber()
+		}
+
+		var r record.Record
+		if err := dec.Decode(&r); err != nil {
+			return DecodeResponse{}, errors.Wrap(err, "json_decode")
+		}
+		if r == nil {
+			return DecodeResponse{}, errors.New("json_decode: line is not a json object")
+		}
+		return DecodeResponse{Record: r}, nil
+	}, nil
+}
@@ -0,0 +1,40 @@
+// Code generated by lotus-processor-gen. DO NOT EDIT.
+
+package json
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+// DecodeProcessor is the typed processor of DecodeRequest and DecodeResponse.
+type DecodeProcessor func(DecodeRequest) (DecodeResponse, error)
+
+var (
+	_ processor.Typed = DecodeProcessor(nil)
+
+	decodeProcessorTypes = [...]reflect.Type{
+		reflect.TypeOf((*string)(nil)).Elem(),
+		reflect.TypeOf((*record.Record)(nil)).Elem(),
+	}
+)
+
+func (f DecodeProcessor) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req DecodeRequest
+	if v, err := inject.Resolve(in, decodeProcessorTypes[0], "line"); err == nil {
+		req.Line = v.Interface().(string)
+	} else {
+		return fmt.Errorf("Field Line: %s", err)
+	}
+
+	resp, err := f(req)
+	if err != nil {
+		return err
+	}
+	out.Set(decodeProcessorTypes[1], "record", reflect.ValueOf(resp.Record))
+	return nil
+}
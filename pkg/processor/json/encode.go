@@ -0,0 +1,75 @@
+package json
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+//go:generate go run ../../../cmd/lotus-processor-gen -type EncodeProcessor -request EncodeRequest -response EncodeResponse
+
+var (
+	encodeFactory       processor.Factory = NewEncodeFactory()
+	defaultEncodeConfig                   = EncodeConfig{}
+	encodeDescription                     = "encodes the record into the line as a json object"
+)
+
+func init() {
+	if err := processor.Register("json_encode", encodeFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewEncodeFactory() processor.Factory {
+	return processor.NewTypedFactory(
+		defaultEncodeConfig,
+		encodeDescription,
+		EncodeProcessor(nil),
+		func(c string) (processor.Typed, error) {
+			return NewEncode(c)
+		})
+}
+
+type EncodeConfig struct {
+	Indent string `yaml:"indent"` // 不为空时输出多行的json, e.g. 两个空格
+}
+
+func (c EncodeConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type EncodeRequest struct {
+	Record record.Record `inject:"record"`
+}
+
+type EncodeResponse struct {
+	Line string `inject:"line"`
+}
+
+func NewEncode(rawConfig string) (EncodeProcessor, error) {
+	conf := defaultEncodeConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req EncodeRequest) (EncodeResponse, error) {
+		var (
+			data []byte
+			err  error
+		)
+		if conf.Indent != "" {
+			data, err = json.MarshalIndent(req.Record, "", conf.Indent)
+		} else {
+			data, err = json.Marshal(req.Record)
+		}
+		if err != nil {
+			return EncodeResponse{}, errors.Wrap(err, "json_encode")
+		}
+		return EncodeResponse{Line: string(data)}, nil
+	}, nil
+}
@@ -0,0 +1,40 @@
+// Code generated by lotus-processor-gen. DO NOT EDIT.
+
+package json
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+// EncodeProcessor is the typed processor of EncodeRequest and EncodeResponse.
+type EncodeProcessor func(EncodeRequest) (EncodeResponse, error)
+
+var (
+	_ processor.Typed = EncodeProcessor(nil)
+
+	encodeProcessorTypes = [...]reflect.Type{
+		reflect.TypeOf((*record.Record)(nil)).Elem(),
+		reflect.TypeOf((*string)(nil)).Elem(),
+	}
+)
+
+func (f EncodeProcessor) Call(in inject.Injector, out inject.TypeMapper) error {
+	var req EncodeRequest
+	if v, err := inject.Resolve(in, encodeProcessorTypes[0], "record"); err == nil {
+		req.Record = v.Interface().(record.Record)
+	} else {
+		return fmt.Errorf("Field Record: %s", err)
+	}
+
+	resp, err := f(req)
+	if err != nil {
+		return err
+	}
+	out.Set(encodeProcessorTypes[1], "line", reflect.ValueOf(resp.Line))
+	return nil
+}
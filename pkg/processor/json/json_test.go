@@ -0,0 +1,86 @@
+package json
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		config   string
+		line     string
+		expected record.Record
+		err      string
+	}{
+		{"", `{"id":1,"user":{"name":"a"}}`, record.Record{"id": float64(1), "user": map[string]interface{}{"name": "a"}}, ""},
+		{"use_number: true", `{"id":12345678901234567890}`, record.Record{"id": json.Number("12345678901234567890")}, ""},
+		{"", `{}`, record.Record{}, ""},
+		{"", `null`, nil, "json_decode: line is not a json object"},
+		{"", `[1, 2]`, nil, "json_decode: json: cannot unmarshal array"},
+		{"", `{"id":`, nil, "json_decode: unexpected EOF"},
+		{"", ``, nil, "json_decode: EOF"},
+	}
+
+	for _, test := range tests {
+		decode, err := NewDecode(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := decode(DecodeRequest{Line: test.line})
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%q: Expected error %s - Got %v", test.line, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(res.Record, test.expected) {
+			t.Errorf("%q: Expected %v - Got %v", test.line, test.expected, res.Record)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		config   string
+		record   record.Record
+		expected string
+		err      string
+	}{
+		{"", record.Record{"id": 1, "user": map[string]interface{}{"name": "a"}}, `{"id":1,"user":{"name":"a"}}`, ""},
+		{"indent: '  '", record.Record{"id": 1}, "{\n  \"id\": 1\n}", ""},
+		{"", record.Record{}, `{}`, ""},
+		{"", nil, `null`, ""},
+		{"", record.Record{"ch": make(chan int)}, "", "json_encode: json: unsupported type: chan int"},
+	}
+
+	for _, test := range tests {
+		encode, err := NewEncode(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := encode(EncodeRequest{Record: test.record})
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: Expected error %s - Got %v", test.record, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %s", test.record, err)
+			continue
+		}
+		if res.Line != test.expected {
+			t.Errorf("%v: Expected %q - Got %q", test.record, test.expected, res.Line)
+		}
+	}
+}
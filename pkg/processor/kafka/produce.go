@@ -0,0 +1,91 @@
+// Package kafka contains the processor that produces the lines or records to kafka,
+// the producer is the kafka_producer component injected by type, mark one primary if there are more.
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+var (
+	produceFactory       processor.Factory = NewProduceFactory()
+	defaultProduceConfig                   = ProduceConfig{
+		Topic:  "lotus",
+		Source: record.RecordName,
+	}
+	produceDescription = "produces the line or the record encoded as json to the topic with a sync producer"
+)
+
+func init() {
+	if err := processor.Register("kafka_produce", produceFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewProduceFactory() processor.Factory {
+	return processor.NewFactory(
+		defaultProduceConfig,
+		produceDescription,
+		newProduce(defaultProduceConfig),
+		func(c string) (processor.Processor, error) {
+			return NewProduce(c)
+		})
+}
+
+type ProduceConfig struct {
+	Topic    string `yaml:"topic"`
+	KeyField string `yaml:"key_field"` // 为空时不设置key, 由partitioner决定分区
+	Source   string `yaml:"source"`    // line或者record
+}
+
+func (c ProduceConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type ProduceRequest struct {
+	Line   string        `inject:"line,optional"`
+	Record record.Record `inject:"record,optional"`
+}
+
+func NewProduce(rawConfig string) (processor.Processor, error) {
+	conf := defaultProduceConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Topic == "" {
+		return nil, errors.New("kafka_produce: topic cannot be empty")
+	}
+	if err = record.CheckSource(conf.Source); err != nil {
+		return nil, errors.Wrap(err, "kafka_produce")
+	}
+	return newProduce(conf), nil
+}
+
+func newProduce(conf ProduceConfig) func(ProduceRequest, sarama.SyncProducer) error {
+	return func(req ProduceRequest, producer sarama.SyncProducer) error {
+		data, err := record.Payload(conf.Source, req.Line, req.Record)
+		if err != nil {
+			return errors.Wrap(err, "kafka_produce")
+		}
+
+		msg := &sarama.ProducerMessage{
+			Topic: conf.Topic,
+			Value: sarama.ByteEncoder(data),
+		}
+		if conf.KeyField != "" {
+			key, ok := req.Record.GetString(conf.KeyField)
+			if !ok {
+				return errors.Errorf("kafka_produce: Key field %s not found", conf.KeyField)
+			}
+			msg.Key = sarama.StringEncoder(key)
+		}
+
+		_, _, err = producer.SendMessage(msg)
+		return errors.Wrapf(err, "kafka_produce: %s", conf.Topic)
+	}
+}
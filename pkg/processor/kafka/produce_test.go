@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+// fakeProducer records the messages instead of sending them.
+type fakeProducer struct {
+	sarama.SyncProducer
+	err  error
+	msgs []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p.err != nil {
+		return 0, 0, p.err
+	}
+	p.msgs = append(p.msgs, msg)
+	return 0, int64(len(p.msgs) - 1), nil
+}
+
+func TestProduceConfig(t *testing.T) {
+	for _, config := range []string{"topic: ''", "source: value"} {
+		if _, err := NewProduce(config); err == nil {
+			t.Errorf("%s: Expected an error", config)
+		}
+	}
+}
+
+func TestProduce(t *testing.T) {
+	tests := []struct {
+		config  string
+		req     ProduceRequest
+		sendErr error
+		key     string
+		value   string
+		err     string
+	}{
+		{
+			config: "source: line",
+			req:    ProduceRequest{Line: "hello"},
+			value:  "hello",
+		},
+		{
+			config: "",
+			req:    ProduceRequest{Record: record.Record{"id": "1"}},
+			value:  `{"id":"1"}`,
+		},
+		{
+			config: "key_field: user.id",
+			req:    ProduceRequest{Record: record.Record{"user": map[string]interface{}{"id": "u1"}}},
+			key:    "u1",
+			value:  `{"user":{"id":"u1"}}`,
+		},
+		{
+			config: "key_field: user.id",
+			req:    ProduceRequest{Record: record.Record{"id": "1"}},
+			err:    "kafka_produce: Key field user.id not found",
+		},
+		{
+			config: "",
+			req:    ProduceRequest{Line: "no record"},
+			err:    "kafka_produce: No record to write",
+		},
+		{
+			config:  "topic: logs\nsource: line",
+			req:     ProduceRequest{Line: "hello"},
+			sendErr: errors.New("broker down"),
+			err:     "kafka_produce: logs: broker down",
+		},
+	}
+
+	for _, test := range tests {
+		p, err := NewProduce(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		produce := p.(func(ProduceRequest, sarama.SyncProducer) error)
+
+		producer := &fakeProducer{err: test.sendErr}
+		err = produce(test.req, producer)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: Expected error %s - Got %v", test.config, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.config, err)
+			continue
+		}
+
+		if len(producer.msgs) != 1 {
+			t.Fatalf("%q: Expected 1 message - Got %d", test.config, len(producer.msgs))
+		}
+		msg := producer.msgs[0]
+		value, _ := msg.Value.Encode()
+		var key []byte
+		if msg.Key != nil {
+			key, _ = msg.Key.Encode()
+		}
+		if msg.Topic != "lotus" || string(key) != test.key || string(value) != test.value {
+			t.Errorf("%q: Expected lotus %q %q - Got %s %q %q",
+				test.config, test.key, test.value, msg.Topic, key, value)
+		}
+	}
+}
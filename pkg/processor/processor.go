@@ -3,12 +3,43 @@ package processor
 import (
 	"errors"
 	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
 )
 
 type Processor interface{}
 
+// ErrNoOutput is returned by a processor which has nothing to pass on, e.g. at the end of its input.
+// The stream counts it as neither a success nor an error and skips its child streams.
+var ErrNoOutput = errors.New("No output")
+
+// Params is a processor with the inject names of its arguments which are not structs, by position.
+// The names are kept per processor, so the processors created by one factory can use different names.
+type Params struct {
+	Processor Processor
+	Names     []string
+}
+
+// WithParamNames returns p with the inject names of its arguments by position, the factory returns it from New.
+// An empty name or a missing trailing name resolves the argument only by its type.
+func WithParamNames(p Processor, names ...string) (Processor, error) {
+	if err := inject.ValidateParamNames(p, names); err != nil {
+		return nil, err
+	}
+	return Params{Processor: p, Names: names}, nil
+}
+
+// Unwrap returns the processor and its param names if it was returned by WithParamNames.
+func Unwrap(p Processor) (Processor, []string) {
+	if params, ok := p.(Params); ok {
+		return params.Processor, params.Names
+	}
+	return p, nil
+}
+
 func Validate(processor Processor) error {
-	if reflect.TypeOf(processor).Kind() != reflect.Func {
+	processor, _ = Unwrap(processor)
+	if processor == nil || reflect.TypeOf(processor).Kind() != reflect.Func {
 		return errors.New("Processor must be a callable func")
 	}
 	return nil
@@ -0,0 +1,144 @@
+// Package record is the data passed between the built-in processors:
+// the text of a line is injected by name "line", a decoded json object by name "record".
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	LineName   = "line"   // string, 一行文本, e.g. line_reader和json_encode的输出
+	RecordName = "record" // Record, 解码后的json对象, e.g. json_decode的输出
+)
+
+// Payload returns what a sink writes, the line if source is LineName, the record encoded as json if RecordName.
+func Payload(source string, line string, r Record) ([]byte, error) {
+	switch source {
+	case LineName:
+		return []byte(line), nil
+	case RecordName:
+		if r == nil {
+			return nil, fmt.Errorf("No record to write")
+		}
+		return json.Marshal(r)
+	}
+	return nil, CheckSource(source)
+}
+
+// CheckSource checks the source of a sink in its config.
+func CheckSource(source string) error {
+	if source != LineName && source != RecordName {
+		return fmt.Errorf("Unknown source: %s, expected %s or %s", source, LineName, RecordName)
+	}
+	return nil
+}
+
+// Record is a json object, nested objects are map[string]interface{} like encoding/json decodes them.
+// The fields are addressed by dotted paths, e.g. "user.name".
+type Record map[string]interface{}
+
+// Get returns the value of the path.
+func (r Record) Get(path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	m := map[string]interface{}(r)
+	for i, k := range keys {
+		v, ok := m[k]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return v, true
+		}
+		if m, ok = asMap(v); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// GetString returns the value of the path formatted by fmt, e.g. a number as a key.
+func (r Record) GetString(path string) (string, bool) {
+	v, ok := r.Get(path)
+	if !ok || v == nil {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	return fmt.Sprint(v), true
+}
+
+// Set sets the value of the path, the missing objects along the path are created.
+// A value along the path which is not an object is replaced.
+func (r Record) Set(path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	m := map[string]interface{}(r)
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := asMap(m[k])
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// Delete removes the path and returns its value.
+func (r Record) Delete(path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	m := map[string]interface{}(r)
+	for _, k := range keys[:len(keys)-1] {
+		var ok bool
+		if m, ok = asMap(m[k]); !ok {
+			return nil, false
+		}
+	}
+
+	last := keys[len(keys)-1]
+	v, ok := m[last]
+	delete(m, last)
+	return v, ok
+}
+
+// Copy returns a deep copy of the objects of the record, so a processor can change its output
+// without changing the record consumed by the sibling streams.
+func (r Record) Copy() Record {
+	return Record(copyMap(r))
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			res[k] = copyMap(vv)
+		case Record:
+			res[k] = copyMap(vv)
+		case []interface{}:
+			list := make([]interface{}, len(vv))
+			for i, item := range vv {
+				if im, ok := item.(map[string]interface{}); ok {
+					item = copyMap(im)
+				}
+				list[i] = item
+			}
+			res[k] = list
+		default:
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case Record:
+		return m, true
+	}
+	return nil, false
+}
@@ -0,0 +1,43 @@
+package record
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	r := Record{"id": 1.0, "user": map[string]interface{}{"name": "a"}}
+
+	if v, ok := r.GetString("id"); !ok || v != "1" {
+		t.Errorf("Expected id 1 - Got %v %v", v, ok)
+	}
+	if v, ok := r.Get("user.name"); !ok || v != "a" {
+		t.Errorf("Expected user.name a - Got %v %v", v, ok)
+	}
+	if _, ok := r.Get("id.name"); ok {
+		t.Errorf("Unexpected id.name")
+	}
+
+	c := r.Copy()
+	c.Set("user.name", "b")
+	c.Set("user.address.city", "c")
+	if v, _ := r.Get("user.name"); v != "a" {
+		t.Errorf("Expected the copy not to change the record - Got %v", v)
+	}
+
+	if v, ok := c.Delete("user.address"); !ok || !reflect.DeepEqual(v, map[string]interface{}{"city": "c"}) {
+		t.Errorf("Expected user.address deleted - Got %v %v", v, ok)
+	}
+	expected := Record{"id": 1.0, "user": map[string]interface{}{"name": "b"}}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %v - Got %v", expected, c)
+	}
+
+	data, err := Payload(RecordName, "", Record{"a": 1})
+	if err != nil || string(data) != `{"a":1}` {
+		t.Errorf("Expected payload of the record - Got %s %v", data, err)
+	}
+	if _, err := Payload("value", "", nil); err == nil {
+		t.Errorf("Expected unknown source error")
+	}
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"encoding/json"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+var (
+	getFactory       processor.Factory = NewGetFactory()
+	defaultGetConfig                   = GetConfig{
+		KeyConfig:   KeyConfig{KeyField: "id"},
+		TargetField: "value",
+	}
+	getDescription = "gets the value of the key in the record from redis into the target field, the key not found leaves the field unset"
+)
+
+func init() {
+	if err := processor.Register("redis_get", getFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewGetFactory() processor.Factory {
+	return processor.NewFactory(
+		defaultGetConfig,
+		getDescription,
+		newGet(defaultGetConfig),
+		func(c string) (processor.Processor, error) {
+			return NewGet(c)
+		})
+}
+
+type GetConfig struct {
+	KeyConfig   `yaml:",inline"`
+	TargetField string `yaml:"target_field"`
+	JSON        bool   `yaml:"json"` // 值是json时解码后再写入
+}
+
+func (c GetConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type GetRequest struct {
+	Record record.Record `inject:"record"`
+}
+
+type GetResponse struct {
+	Record record.Record `inject:"record"`
+}
+
+func NewGet(rawConfig string) (processor.Processor, error) {
+	conf := defaultGetConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+	if err = conf.check("redis_get"); err != nil {
+		return nil, err
+	}
+	if conf.TargetField == "" {
+		return nil, errors.New("redis_get: target_field cannot be empty")
+	}
+	return newGet(conf), nil
+}
+
+func newGet(conf GetConfig) func(GetRequest, redis.UniversalClient) (GetResponse, error) {
+	return func(req GetRequest, client redis.UniversalClient) (GetResponse, error) {
+		key, err := conf.key(req.Record)
+		if err != nil {
+			return GetResponse{}, errors.Wrap(err, "redis_get")
+		}
+
+		r := req.Record.Copy()
+		val, err := client.Get(key).Result()
+		if err == redis.Nil {
+			return GetResponse{Record: r}, nil
+		}
+		if err != nil {
+			return GetResponse{}, errors.Wrapf(err, "redis_get: %s", key)
+		}
+
+		if !conf.JSON {
+			r.Set(conf.TargetField, val)
+			return GetResponse{Record: r}, nil
+		}
+
+		var v interface{}
+		if err = json.Unmarshal([]byte(val), &v); err != nil {
+			return GetResponse{}, errors.Wrapf(err, "redis_get: %s", key)
+		}
+		r.Set(conf.TargetField, v)
+		return GetResponse{Record: r}, nil
+	}
+}
@@ -0,0 +1,29 @@
+// Package redis contains the processors that read and write redis by the keys in the record,
+// the client is the redis_client component injected by type, mark one primary if there are more.
+package redis
+
+import (
+	"fmt"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+type KeyConfig struct {
+	KeyPrefix string `yaml:"key_prefix"` // e.g. user:
+	KeyField  string `yaml:"key_field"`  // key所在的字段, key为key_prefix加上字段的值
+}
+
+func (c KeyConfig) check(name string) error {
+	if c.KeyField == "" {
+		return fmt.Errorf("%s: key_field cannot be empty", name)
+	}
+	return nil
+}
+
+func (c KeyConfig) key(r record.Record) (string, error) {
+	v, ok := r.GetString(c.KeyField)
+	if !ok {
+		return "", fmt.Errorf("Key field %s not found", c.KeyField)
+	}
+	return c.KeyPrefix + v, nil
+}
@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+type fakeClient struct {
+	redis.UniversalClient
+	values map[string]string
+	ttl    time.Duration
+}
+
+func (c *fakeClient) Get(key string) *redis.StringCmd {
+	v, ok := c.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (c *fakeClient) Set(key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	c.values[key] = value.(string)
+	c.ttl = ttl
+	return redis.NewStatusResult("OK", nil)
+}
+
+func TestGetSet(t *testing.T) {
+	client := &fakeClient{values: map[string]string{}}
+
+	p, err := NewSet("key_prefix: 'user:'\nttl: 1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := p.(func(SetRequest, redis.UniversalClient) error)
+	if err = set(SetRequest{Record: record.Record{"id": 1.0, "name": "a"}}, client); err != nil {
+		t.Fatal(err)
+	}
+	if client.values["user:1"] != `{"id":1,"name":"a"}` || client.ttl != time.Minute {
+		t.Errorf("Expected the record set to user:1 - Got %v %v", client.values, client.ttl)
+	}
+	if err = set(SetRequest{Record: record.Record{}}, client); err == nil {
+		t.Errorf("Expected key field not found error")
+	}
+
+	p, err = NewGet("key_prefix: 'user:'\ntarget_field: user\njson: true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := p.(func(GetRequest, redis.UniversalClient) (GetResponse, error))
+	for _, id := range []string{"1", "2"} {
+		r := record.Record{"id": id}
+		res, err := get(GetRequest{Record: r}, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := record.Record{"id": id}
+		if id == "1" {
+			expected["user"] = map[string]interface{}{"id": 1.0, "name": "a"}
+		}
+		if !reflect.DeepEqual(res.Record, expected) {
+			t.Errorf("Expected %v - Got %v", expected, res.Record)
+		}
+		if len(r) != 1 {
+			t.Errorf("Expected the input record unchanged - Got %v", r)
+		}
+	}
+}
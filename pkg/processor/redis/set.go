@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/shima-park/lotus/pkg/processor"
+	"github.com/shima-park/lotus/pkg/processor/record"
+)
+
+var (
+	setFactory       processor.Factory = NewSetFactory()
+	defaultSetConfig                   = SetConfig{
+		KeyConfig: KeyConfig{KeyField: "id"},
+	}
+	setDescription = "sets the value field of the record, or the whole record as json, to the key in the record"
+)
+
+func init() {
+	if err := processor.Register("redis_set", setFactory); err != nil {
+		panic(err)
+	}
+}
+
+func NewSetFactory() processor.Factory {
+	return processor.NewFactory(
+		defaultSetConfig,
+		setDescription,
+		newSet(defaultSetConfig),
+		func(c string) (processor.Processor, error) {
+			return NewSet(c)
+		})
+}
+
+type SetConfig struct {
+	KeyConfig  `yaml:",inline"`
+	ValueField string        `yaml:"value_field"` // 为空时写入json编码的整个记录, 不是字符串的值也编码为json
+	TTL        time.Duration `yaml:"ttl"`         // 为0时不过期
+}
+
+func (c SetConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+type SetRequest struct {
+	Record record.Record `inject:"record"`
+}
+
+func NewSet(rawConfig string) (processor.Processor, error) {
+	conf := defaultSetConfig
+	err := yaml.Unmarshal([]byte(rawConfig), &conf)
+	if err != nil {
+		return nil, err
+	}
+	if err = conf.check("redis_set"); err != nil {
+		return nil, err
+	}
+	return newSet(conf), nil
+}
+
+func newSet(conf SetConfig) func(SetRequest, redis.UniversalClient) error {
+	return func(req SetRequest, client redis.UniversalClient) error {
+		key, err := conf.key(req.Record)
+		if err != nil {
+			return errors.Wrap(err, "redis_set")
+		}
+
+		val, err := conf.value(req.Record)
+		if err != nil {
+			return errors.Wrapf(err, "redis_set: %s", key)
+		}
+		return errors.Wrapf(client.Set(key, val, conf.TTL).Err(), "redis_set: %s", key)
+	}
+}
+
+func (c SetConfig) value(r record.Record) (string, error) {
+	var v interface{} = r
+	if c.ValueField != "" {
+		var ok bool
+		if v, ok = r.Get(c.ValueField); !ok {
+			return "", errors.Errorf("Value field %s not found", c.ValueField)
+		}
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+
+	data, err := json.Marshal(v)
+	return string(data), err
+}
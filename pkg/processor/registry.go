@@ -2,6 +2,8 @@ package processor
 
 import (
 	"fmt"
+
+	"github.com/shima-park/lotus/pkg/common/schema"
 )
 
 type Factory interface {
@@ -20,6 +22,29 @@ type Factory interface {
 
 type FactoryFunc func(config string) (Processor, error)
 
+// SchemaFactory is implemented by the factories whose config is described by a JSON Schema,
+// the factories created by NewFactory with a sample config struct implement it.
+type SchemaFactory interface {
+	// 配置的JSON Schema, 没有时返回nil
+	Schema() *schema.Schema
+}
+
+// SchemaOf returns the schema of the factory, or nil if it has none.
+func SchemaOf(factory Factory) *schema.Schema {
+	if sf, ok := factory.(SchemaFactory); ok {
+		return sf.Schema()
+	}
+	return nil
+}
+
+// ValidateConfig validates the raw config by the schema of the factory before New.
+func ValidateConfig(factory Factory, rawConfig string) error {
+	if s := SchemaOf(factory); s != nil {
+		return s.Validate(rawConfig)
+	}
+	return nil
+}
+
 var registry = make(map[string]Factory)
 
 func Register(name string, factory Factory) error {
@@ -32,6 +57,12 @@ func Register(name string, factory Factory) error {
 	if _, exists := registry[name]; exists {
 		return fmt.Errorf("Error registering processor '%v': already registered", name)
 	}
+	// 注册时校验处理器的签名, 而不是等到第一次执行
+	if example := factory.Example(); example != nil {
+		if _, err := SignatureOf(example); err != nil {
+			return fmt.Errorf("Error registering processor '%v': %s", name, err)
+		}
+	}
 
 	registry[name] = factory
 
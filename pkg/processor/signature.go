@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shima-park/lotus/pkg/common/inject"
+)
+
+var errorInterface = reflect.TypeOf((*error)(nil)).Elem()
+
+// Field 处理器的一个输入或者输出
+type Field struct {
+	Name       string `json:"name"` // 结构体的字段名, 整体注入的参数是argument N
+	Type       string `json:"type"`
+	InjectName string `json:"inject_name"`
+	Options    string `json:"options,omitempty"`
+}
+
+// Signature 处理器的请求和响应字段, 注册时从处理器的签名得到
+type Signature struct {
+	Request  []Field `json:"request"`
+	Response []Field `json:"response"`
+	Typed    bool    `json:"typed"` // 是否是生成的Typed适配器
+}
+
+// SignatureOf validates the signature of a processor and returns its fields.
+// A processor is a func whose arguments are structs with inject tags or values injected as a whole by type
+// and the names given by WithParamNames,
+// and whose results are structs or pointers to structs followed by an optional error.
+func SignatureOf(p Processor) (Signature, error) {
+	if err := Validate(p); err != nil {
+		return Signature{}, err
+	}
+
+	p, names := Unwrap(p)
+	t := reflect.TypeOf(p)
+	_, typed := p.(Typed)
+	sig := Signature{Typed: typed}
+
+	for i := 0; i < t.NumIn(); i++ {
+		argType := t.In(i)
+		if !inject.IsInjectStruct(argType) {
+			sig.Request = append(sig.Request, Field{
+				Name:       fmt.Sprintf("argument %d", i),
+				Type:       argType.String(),
+				InjectName: inject.ParamName(names, i),
+			})
+			continue
+		}
+		sig.Request = append(sig.Request, fieldsOf(argType)...)
+	}
+
+	for i := 0; i < t.NumOut(); i++ {
+		outType := t.Out(i)
+		if outType == errorInterface {
+			if i != t.NumOut()-1 {
+				return Signature{}, fmt.Errorf("Processor %v: error must be the last result", t)
+			}
+			continue
+		}
+
+		for outType.Kind() == reflect.Ptr {
+			outType = outType.Elem()
+		}
+		if outType.Kind() != reflect.Struct {
+			return Signature{}, fmt.Errorf("Processor %v: cannot support results other than structures %v", t, outType)
+		}
+		sig.Response = append(sig.Response, fieldsOf(outType)...)
+	}
+	return sig, nil
+}
+
+func fieldsOf(t reflect.Type) []Field {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		ia := inject.GetInjectAnnotation(t.Field(i))
+		if !ia.Exists {
+			continue
+		}
+		fields = append(fields, Field{
+			Name:       t.Field(i).Name,
+			Type:       t.Field(i).Type.String(),
+			InjectName: ia.Name,
+			Options:    string(ia.Options),
+		})
+	}
+	return fields
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSignatureOf(t *testing.T) {
+	type in struct {
+		Line string `inject:"line,optional"`
+	}
+	type out struct {
+		Upper string `inject:"upper"`
+	}
+
+	sig, err := SignatureOf(func(ctx context.Context, req in) (*out, error) { return nil, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Signature{
+		Request: []Field{
+			{Name: "argument 0", Type: "context.Context"},
+			{Name: "Line", Type: "string", InjectName: "line", Options: "optional"},
+		},
+		Response: []Field{{Name: "Upper", Type: "string", InjectName: "upper"}},
+	}
+	if len(sig.Request) != 2 || sig.Request[0] != expected.Request[0] || sig.Request[1] != expected.Request[1] ||
+		len(sig.Response) != 1 || sig.Response[0] != expected.Response[0] {
+		t.Fatalf("Expected %+v - Got %+v", expected, sig)
+	}
+
+	p, err := WithParamNames(func(ctx context.Context, r strings.Reader) error { return nil }, "Context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig, err = SignatureOf(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(sig.Request) != 1 || sig.Request[0].InjectName != "Context" {
+		t.Fatalf("Expected the param name of argument 0 - Got %+v", sig.Request)
+	}
+	if _, err = WithParamNames(func() error { return nil }, "a"); err == nil {
+		t.Fatal("Expected error of too many param names")
+	}
+
+	for _, p := range []Processor{
+		"not a func",
+		func() string { return "" },
+		func() (error, out) { return nil, out{} },
+	} {
+		if _, err := SignatureOf(p); err == nil {
+			t.Errorf("Expected error of %T", p)
+		}
+	}
+
+	err = Register("test_invalid_signature", NewFactoryWithProcessor("", "", func() string { return "" }))
+	if err == nil || !strings.Contains(err.Error(), "cannot support results other than structures") {
+		t.Fatalf("Expected the signature validated at registration - Got %v", err)
+	}
+}
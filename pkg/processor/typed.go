@@ -0,0 +1,29 @@
+package processor
+
+import (
+	"github.com/shima-park/lotus/pkg/common/inject"
+)
+
+// Typed is implemented by the adapters generated by lotus-processor-gen, e.g.
+//
+//	//go:generate lotus-processor-gen -type ParseProcessor -request ParseRequest -response ParseResponse
+//
+// generates the func type ParseProcessor func(ParseRequest) (ParseResponse, error).
+// The adapter is still a func, so it is checked like any other processor,
+// but the pipeline calls it without reflect.Call and without parsing the inject tags on every run.
+type Typed interface {
+	// Call injects the request from in, calls the processor and maps the response to out.
+	Call(in inject.Injector, out inject.TypeMapper) error
+}
+
+// NewTypedFactory returns the factory of a typed processor created from its config.
+func NewTypedFactory(sampleConfig interface{}, description string, example Typed, factoryFunc func(config string) (Typed, error)) Factory {
+	return NewFactory(sampleConfig, description, example, func(config string) (Processor, error) {
+		return factoryFunc(config)
+	})
+}
+
+// RegisterTyped registers a typed processor which has no config.
+func RegisterTyped(name string, description string, p Typed) error {
+	return Register(name, NewFactoryWithProcessor("", description, p))
+}
@@ -22,3 +22,22 @@ func (c *component) Find(name string) (*proto.ComponentView, error) {
 	err := http.GetJSON(c.api("/component?name="+name), &res)
 	return &res, err
 }
+
+func (c *component) ListShared() ([]proto.SharedComponentView, error) {
+	var res []proto.SharedComponentView
+	err := http.GetJSON(c.api("/component/shared/list"), &res)
+	return res, err
+}
+
+func (c *component) AddShared(name, comp, rawConfig string) error {
+	req := proto.SharedComponentView{
+		Name:      name,
+		Component: comp,
+		RawConfig: rawConfig,
+	}
+	return http.PostJSON(c.api("/component/shared/add"), &req, nil)
+}
+
+func (c *component) RemoveShared(names ...string) error {
+	return http.PostJSON(c.api("/component/shared/remove"), &names, nil)
+}
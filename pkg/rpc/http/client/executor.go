@@ -1,6 +1,8 @@
 package client
 
 import (
+	"fmt"
+	nethttp "net/http"
 	"net/url"
 
 	"github.com/shima-park/lotus/pkg/rpc/proto"
@@ -58,3 +60,34 @@ func (p *executor) Visualize(format proto.VisualizeFormat, id string) ([]byte, e
 	err := http.GetJSON(p.api("/executor/visualize?"+vals.Encode()), &data)
 	return data, err
 }
+
+func (p *executor) Graph(id string) (proto.GraphView, error) {
+	vals := url.Values{}
+	vals.Add("id", id)
+
+	var res proto.GraphView
+	err := http.GetJSON(p.api("/executor/graph?"+vals.Encode()), &res)
+	return res, err
+}
+
+func (p *executor) Health(ids ...string) (proto.HealthView, error) {
+	vals := url.Values{}
+	for _, id := range ids {
+		vals.Add("name", id)
+	}
+
+	var res proto.HealthView
+	resp, err := nethttp.Get(p.api("/health?" + vals.Encode()))
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	// 没有ready时返回503, 但是body中依然有健康状态
+	if resp.StatusCode != nethttp.StatusOK && resp.StatusCode != nethttp.StatusServiceUnavailable {
+		return res, fmt.Errorf("HTTP status code: %d", resp.StatusCode)
+	}
+
+	err = http.HandleBody(resp.Body, &res)
+	return res, err
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
+	"github.com/shima-park/lotus/pkg/rpc/proto"
 )
 
 func (s *Server) listComponents(c *gin.Context) {
@@ -23,3 +26,57 @@ func (s *Server) findComponent(c *gin.Context) {
 
 	Success(c, comp)
 }
+
+func (s *Server) componentSchema(c *gin.Context) {
+	comp, err := s.Component.Find(c.Query("name"))
+	if err != nil {
+		Failed(c, err)
+		return
+	}
+	if len(comp.Schema) == 0 {
+		Failed(c, fmt.Errorf("Component: %s has no config schema", comp.Name))
+		return
+	}
+
+	Schema(c, comp.Schema)
+}
+
+func (s *Server) listSharedComponents(c *gin.Context) {
+	res, err := s.Component.ListShared()
+	if err != nil {
+		Failed(c, err)
+		return
+	}
+
+	Success(c, res)
+}
+
+func (s *Server) addSharedComponent(c *gin.Context) {
+	var req proto.SharedComponentView
+	if err := c.BindJSON(&req); err != nil {
+		Failed(c, err)
+		return
+	}
+
+	if err := s.Component.AddShared(req.Name, req.Component, req.RawConfig); err != nil {
+		Failed(c, err)
+		return
+	}
+
+	Success(c, nil)
+}
+
+func (s *Server) removeSharedComponent(c *gin.Context) {
+	var names []string
+	if err := c.BindJSON(&names); err != nil {
+		Failed(c, err)
+		return
+	}
+
+	if err := s.Component.RemoveShared(names...); err != nil {
+		Failed(c, err)
+		return
+	}
+
+	Success(c, nil)
+}
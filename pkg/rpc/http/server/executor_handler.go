@@ -109,3 +109,13 @@ func (s *Server) visualizeExecutor(c *gin.Context) {
 
 	Success(c, data)
 }
+
+func (s *Server) graphExecutor(c *gin.Context) {
+	graph, err := s.Executor.Graph(c.Query("id"))
+	if err != nil {
+		Failed(c, err)
+		return
+	}
+
+	Success(c, graph)
+}
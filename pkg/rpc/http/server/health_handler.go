@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shima-park/lotus/pkg/rpc/proto"
+)
+
+// health returns 503 when any executor is not ready, so it can be used as a readiness probe.
+func (s *Server) health(c *gin.Context) {
+	res, err := s.Executor.Health(c.QueryArray("name")...)
+	if err != nil {
+		Failed(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !res.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, proto.Result{
+		Data: res,
+	})
+}
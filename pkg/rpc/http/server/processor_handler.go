@@ -1,6 +1,8 @@
 package server
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,3 +25,17 @@ func (s *Server) findProcessor(c *gin.Context) {
 
 	Success(c, proc)
 }
+
+func (s *Server) processorSchema(c *gin.Context) {
+	proc, err := s.Processor.Find(c.Query("name"))
+	if err != nil {
+		Failed(c, err)
+		return
+	}
+	if len(proc.Schema) == 0 {
+		Failed(c, fmt.Errorf("Processor: %s has no config schema", proc.Name))
+		return
+	}
+
+	Schema(c, proc.Schema)
+}
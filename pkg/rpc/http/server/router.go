@@ -15,19 +15,27 @@ func (s *Server) setRouter() {
 	r.GET("/executor/ctrl", s.ctrlExecutor)
 	r.GET("/executor/list", s.listExecutors)
 	r.GET("/executor/visualize", s.visualizeExecutor)
+	r.GET("/executor/graph", s.graphExecutor)
 	r.GET("/executor", s.findExecutor)
 
 	r.GET("/component/list", s.listComponents)
 	r.GET("/component", s.findComponent)
+	r.GET("/component/schema", s.componentSchema)
+	r.GET("/component/shared/list", s.listSharedComponents)
+	r.POST("/component/shared/add", s.addSharedComponent)
+	r.POST("/component/shared/remove", s.removeSharedComponent)
 
 	r.GET("/processor/list", s.listProcessors)
 	r.GET("/processor", s.findProcessor)
+	r.GET("/processor/schema", s.processorSchema)
 
 	r.GET("/plugin/list", s.listPlugins)
 	r.POST("/plugin/upload", s.uploadPlugin)
 	r.POST("/plugin/remove", s.removePlugin)
 
 	r.GET("/metadata", s.getMetadata)
+
+	r.GET("/health", s.health)
 }
 
 func Success(c *gin.Context, data interface{}) {
@@ -36,6 +44,11 @@ func Success(c *gin.Context, data interface{}) {
 	})
 }
 
+// Schema writes a JSON Schema as is, editors load it by the url without unwrapping proto.Result.
+func Schema(c *gin.Context, schema []byte) {
+	c.Data(http.StatusOK, "application/schema+json", schema)
+}
+
 func Failed(c *gin.Context, err error) {
 	c.JSON(http.StatusOK, proto.Result{
 		Code: http.StatusInternalServerError,
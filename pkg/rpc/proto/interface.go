@@ -8,11 +8,16 @@ type Executor interface {
 	Find(executorInstanceID string) (*ExecutorView, error)
 	Control(cmd ControlCommand, executorInstanceIDs ...string) error
 	Visualize(format VisualizeFormat, executorInstanceID string) ([]byte, error)
+	Health(executorInstanceIDs ...string) (HealthView, error)
+	Graph(executorInstanceID string) (GraphView, error)
 }
 
 type Component interface {
 	List() ([]ComponentView, error)
 	Find(name string) (*ComponentView, error)
+	ListShared() ([]SharedComponentView, error)
+	AddShared(name, component, rawConfig string) error
+	RemoveShared(names ...string) error
 }
 
 type Processor interface {
@@ -41,6 +46,13 @@ const (
 type Snapshot struct {
 	PluginPaths         []string
 	ExecutorConfigPaths map[string][]string // key: executor type
+	SharedComponents    []SharedComponentConfig
+}
+
+type SharedComponentConfig struct {
+	Name      string `yaml:"name"`
+	Component string `yaml:"component"`
+	RawConfig string `yaml:"config"`
 }
 
 type Metadata interface {
@@ -50,5 +62,7 @@ type Metadata interface {
 	AddExecutorConfigPath(_type, path string) error
 	RemovePluginPath(path string) error
 	RemoveExecutorConfigPath(_type, path string) error
+	PutSharedComponent(conf SharedComponentConfig) error
+	RemoveSharedComponent(name string) error
 	Snapshot(do func(Snapshot))
 }
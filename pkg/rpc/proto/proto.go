@@ -1,5 +1,7 @@
 package proto
 
+import "encoding/json"
+
 type ControlCommand string
 
 const (
@@ -15,6 +17,8 @@ const (
 	VisualizeFormatPng  VisualizeFormat = "png"
 	VisualizeFormatDot  VisualizeFormat = "dot"
 	VisualizeFormatTerm VisualizeFormat = "term"
+	// 组件和处理器之间依赖关系的dot
+	VisualizeFormatDependency VisualizeFormat = "dependency"
 )
 
 type Result struct {
@@ -50,13 +54,82 @@ type ComponentView struct {
 	InjectName   string `json:"inject_name,omitempty"`
 	ReflectType  string `json:"reflect_type,omitempty"`
 	ReflectValue string `json:"reflect_value,omitempty"`
+	Primary      bool   `json:"primary,omitempty"`
+	// 配置的JSON Schema, 示例配置是文本时为空
+	Schema json.RawMessage `json:"schema,omitempty"`
+
+	// 执行器中实现了component.HealthChecker的组件才有健康状态
+	Health *ComponentHealthView `json:"health,omitempty"`
+}
+
+type SharedComponentView struct {
+	Name      string `json:"name"`
+	Component string `json:"component"`
+	RawConfig string `json:"raw_config"`
+	Refs      int    `json:"refs"`    // 引用该组件的pipeline数量
+	Started   bool   `json:"started"` // 组件实例是否已经启动
+}
+
+type HealthView struct {
+	Ready     bool                 `json:"ready"`
+	Executors []ExecutorHealthView `json:"executors"`
+}
+
+type ExecutorHealthView struct {
+	Name       string                `json:"name"`
+	Ready      bool                  `json:"ready"`
+	State      string                `json:"state"`
+	Components []ComponentHealthView `json:"components"`
+	Error      string                `json:"error,omitempty"`
+}
+
+type ComponentHealthView struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+	CheckTime string `json:"check_time"`
+}
+
+// GraphView 执行器中组件和处理器之间的依赖关系, 边的方向是从提供值的节点到使用值的节点
+type GraphView struct {
+	Name  string          `json:"name"`
+	Nodes []GraphNodeView `json:"nodes"`
+	Edges []GraphEdgeView `json:"edges"`
+}
+
+type GraphNodeView struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"` // component, injector, processor, input, output
+	Processor string `json:"processor,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Missing   bool   `json:"missing,omitempty"`
+}
+
+type GraphEdgeView struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
 
 type ProcessorView struct {
-	Name         string `json:"name"`
-	RawConfig    string `json:"raw_config,omitempty"`
-	SampleConfig string `json:"sample_config"`
-	Description  string `json:"description"`
+	Name         string               `json:"name"`
+	RawConfig    string               `json:"raw_config,omitempty"`
+	SampleConfig string               `json:"sample_config"`
+	Description  string               `json:"description"`
+	Request      []ProcessorFieldView `json:"request,omitempty"`  // 处理器注入的字段
+	Response     []ProcessorFieldView `json:"response,omitempty"` // 处理器输出的字段
+	Typed        bool                 `json:"typed,omitempty"`    // 是否是生成的Typed适配器
+	Schema       json.RawMessage      `json:"schema,omitempty"`   // 配置的JSON Schema, 示例配置是文本时为空
+}
+
+type ProcessorFieldView struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	InjectName string `json:"inject_name"`
+	Options    string `json:"options,omitempty"`
 }
 
 type PluginView struct {
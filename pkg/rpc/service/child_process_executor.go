@@ -13,12 +13,18 @@ import (
 	"github.com/docker/docker/pkg/reexec"
 	"github.com/gin-gonic/gin"
 	"github.com/shima-park/lotus/pkg/common/monitor"
+	"github.com/shima-park/lotus/pkg/component"
 	"github.com/shima-park/lotus/pkg/executor"
 	"github.com/shima-park/lotus/pkg/rpc/proto"
 
 	utilhttp "github.com/shima-park/lotus/pkg/util/http"
 )
 
+const (
+	// METADATA_PATH_ENV 传给executor子进程的元数据目录, 组件的状态保存在该目录下
+	METADATA_PATH_ENV = "LOTUS_METADATA_PATH"
+)
+
 func init() {
 	reexec.Register("executor", startExecutor)
 	if reexec.Init() {
@@ -33,6 +39,10 @@ func startExecutor() {
 
 	flag.Parse()
 
+	if path := os.Getenv(METADATA_PATH_ENV); path != "" {
+		component.SetMetadataPath(path)
+	}
+
 	body, err := ioutil.ReadFile(*configPath)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -66,6 +76,9 @@ func startExecutor() {
 
 func StartExecutorChildProcess(configPath, masterAddr string) (executor.Executor, error) {
 	cmd := reexec.Command("executor", "--config", configPath, "--master", masterAddr)
+	cmd.Env = append(os.Environ(),
+		METADATA_PATH_ENV+"="+component.MetadataPath(),
+	)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -162,6 +175,12 @@ func (e *ExecutorServer) setRouter() {
 	r.GET("/error", func(c *gin.Context) {
 		Success(c, e.exec.Error())
 	})
+	r.GET("/health", func(c *gin.Context) {
+		Success(c, e.exec.Health())
+	})
+	r.GET("/graph", func(c *gin.Context) {
+		Success(c, e.exec.Graph())
+	})
 }
 
 func (p *ExecutorServer) Start() error {
@@ -226,6 +245,16 @@ func (c *ExecutorClient) Visualize(w io.Writer, format string) error {
 func (c *ExecutorClient) CheckDependence() []error {
 	return nil
 }
+func (c *ExecutorClient) Health() executor.Health {
+	var h executor.Health
+	utilhttp.GetJSON(c.api("/health"), &h)
+	return h
+}
+func (c *ExecutorClient) Graph() executor.Graph {
+	var g executor.Graph
+	utilhttp.GetJSON(c.api("/graph"), &g)
+	return g
+}
 func (c *ExecutorClient) Error() error {
 	return nil
 }
@@ -10,10 +10,13 @@ import (
 )
 
 type componentService struct {
+	metadata proto.Metadata
 }
 
-func NewComponentService() proto.Component {
-	return &componentService{}
+func NewComponentService(metadata proto.Metadata) proto.Component {
+	return &componentService{
+		metadata: metadata,
+	}
 }
 
 func (s *componentService) List() ([]proto.ComponentView, error) {
@@ -37,6 +40,82 @@ func (s *componentService) Find(name string) (*proto.ComponentView, error) {
 	return newComponentView(name, factory), nil
 }
 
+func (s *componentService) ListShared() ([]proto.SharedComponentView, error) {
+	var res []proto.SharedComponentView
+	for _, c := range component.ListShared() {
+		res = append(res, proto.SharedComponentView{
+			Name:      c.Name,
+			Component: c.Component,
+			RawConfig: c.RawConfig,
+			Refs:      c.Refs,
+			Started:   c.Started,
+		})
+	}
+	return res, nil
+}
+
+func (s *componentService) AddShared(name, comp, rawConfig string) error {
+	err := component.DeclareShared(component.SharedConfig{
+		Name:      name,
+		Component: comp,
+		RawConfig: rawConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.metadata.PutSharedComponent(proto.SharedComponentConfig{
+		Name:      name,
+		Component: comp,
+		RawConfig: rawConfig,
+	})
+	if err != nil {
+		_ = component.UndeclareShared(name)
+		return err
+	}
+	return nil
+}
+
+func (s *componentService) RemoveShared(names ...string) error {
+	for _, name := range names {
+		if err := s.checkSharedReferences(name); err != nil {
+			return err
+		}
+
+		if err := component.UndeclareShared(name); err != nil {
+			return err
+		}
+
+		if err := s.metadata.RemoveSharedComponent(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSharedReferences 没有运行的executor也不能引用被移除的共享组件, 否则重启服务时无法创建
+func (s *componentService) checkSharedReferences(name string) error {
+	var err error
+	s.metadata.Snapshot(func(snapshot proto.Snapshot) {
+		for _type, paths := range snapshot.ExecutorConfigPaths {
+			for _, path := range paths {
+				var refs []string
+				refs, err = sharedReferences(_type, path)
+				if err != nil {
+					return
+				}
+				for _, ref := range refs {
+					if ref == name {
+						err = fmt.Errorf("Shared component '%v' is still referenced by executor config: %s", name, path)
+						return
+					}
+				}
+			}
+		}
+	})
+	return err
+}
+
 func newComponentView(name string, factory component.Factory) *proto.ComponentView {
 	return &proto.ComponentView{
 		Name:         name,
@@ -44,6 +123,7 @@ func newComponentView(name string, factory component.Factory) *proto.ComponentVi
 		Description:  factory.Description(),
 		InjectName:   getInjectName(factory),
 		ReflectType:  fmt.Sprint(factory.ExampleType()),
+		Schema:       marshalSchema(component.SchemaOf(factory)),
 	}
 }
 
@@ -3,8 +3,10 @@ package service
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shima-park/lotus/pkg/common/log"
@@ -131,14 +133,27 @@ func (s *executorService) Add(_type string, config []byte) error {
 }
 
 func (s *executorService) addExecutor(_type, path string) error {
-	exec, err := StartExecutorChildProcess(path, "") // TODO fix me serverAddr
+	refs, err := sharedReferences(_type, path)
+	if err != nil {
+		return err
+	}
+
+	var exec executor.Executor
+	if len(refs) > 0 {
+		// 共享组件的实例只存在于服务进程中, 引用共享组件的executor在服务进程中运行,
+		// 这样各个executor引用的是同一个实例, 引用数也由服务进程统计
+		exec, err = newExecutor(_type, path)
+	} else {
+		exec, err = StartExecutorChildProcess(path, "") // TODO fix me serverAddr
+	}
 	if err != nil {
 		return err
 	}
 	name := exec.Name()
 	_, ok := s.executors[name]
 	if ok {
-		// exec.Stop() TODO stop it?
+		// 释放引用的共享组件
+		exec.Stop()
 		return fmt.Errorf("Executor: %s is already register", name)
 	}
 	s.executors[name] = Executor{
@@ -164,7 +179,7 @@ func (s *executorService) Remove(names ...string) error {
 			}
 
 			exec.Executor.Stop()
-			delete(s.executors, exec.Type)
+			delete(s.executors, name)
 		}
 	}
 
@@ -279,44 +294,106 @@ func (s *executorService) Visualize(format proto.VisualizeFormat, name string) (
 	return nil, nil
 }
 
+func (s *executorService) Health(names ...string) (proto.HealthView, error) {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+
+	if len(names) == 0 {
+		for name := range s.executors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	res := proto.HealthView{Ready: true}
+	for _, name := range names {
+		exec, ok := s.executors[name]
+		if !ok {
+			return res, errors.New("Not found executor " + name)
+		}
+
+		view := convertHealth2ExecutorHealthView(name, exec.Health())
+		if exec.Error() != nil {
+			view.Ready = false
+			view.Error = exec.Error().Error()
+		}
+		res.Ready = res.Ready && view.Ready
+		res.Executors = append(res.Executors, view)
+	}
+
+	return res, nil
+}
+
+func (s *executorService) Graph(name string) (proto.GraphView, error) {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+
+	exec, ok := s.executors[name]
+	if !ok {
+		return proto.GraphView{}, errors.New("Not found executor " + name)
+	}
+
+	return convertGraph2GraphView(name, exec.Graph()), nil
+}
+
+func convertGraph2GraphView(name string, g executor.Graph) proto.GraphView {
+	view := proto.GraphView{Name: name}
+	for _, n := range g.Nodes {
+		view.Nodes = append(view.Nodes, proto.GraphNodeView{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			Processor: n.Processor,
+			Field:     n.Field,
+			Type:      n.Type,
+			Name:      n.Name,
+			Missing:   n.Missing,
+		})
+	}
+	for _, e := range g.Edges {
+		view.Edges = append(view.Edges, proto.GraphEdgeView{
+			From: e.From,
+			To:   e.To,
+			Type: e.Type,
+			Name: e.Name,
+		})
+	}
+	return view
+}
+
+func convertHealth2ExecutorHealthView(name string, h executor.Health) proto.ExecutorHealthView {
+	view := proto.ExecutorHealthView{
+		Name:  name,
+		Ready: h.Ready,
+		State: h.State,
+	}
+	for _, c := range h.Components {
+		view.Components = append(view.Components, convertComponentHealth(c))
+	}
+	return view
+}
+
+func convertComponentHealth(c executor.ComponentHealth) proto.ComponentHealthView {
+	return proto.ComponentHealthView{
+		Name:      c.Name,
+		Healthy:   c.Healthy,
+		Error:     c.Error,
+		CheckTime: c.CheckTime.Format(time.RFC3339),
+	}
+}
+
 func convertExecutor2ExecutorView(p Executor) *proto.ExecutorView {
-	return nil
-	//var streamError string
-	//var streamErrorCount int
-	//p.Monitor().Do(func(root, namespace string, kv expvar.KeyValue) {
-	//	switch kv.Key {
-	//	case pipeliner.METRICS_KEY_STREAM_ERROR:
-	//		streamError = kv.Value.String()
-	//	case pipeliner.METRICS_KEY_STREAM_ERROR_COUNT:
-	//		i, _ := strconv.Atoi(kv.Value.String())
-	//		streamErrorCount += i
-	//	}
-	//
-	//})
-	//
-	//return &proto.ExecutorView{
-	//	Name:          p.Name(),
-	//	State:         p.State().String(),
-	//	Schedule:      p.Config().Schedule,
-	//	Bootstrap:     p.Config().Bootstrap,
-	//	StartTime:     p.Monitor().Get(pipeliner.METRICS_KEY_EXECUTOR_START_TIME).String(),
-	//	ExitTime:      p.Monitor().Get(pipeliner.METRICS_KEY_EXECUTOR_EXIT_TIME).String(),
-	//	RunTimes:      p.Monitor().Get(pipeliner.METRICS_KEY_EXECUTOR_RUN_TIMES).String(),
-	//	NextRunTime:   p.Monitor().Get(pipeliner.METRICS_KEY_EXECUTOR_NEXT_RUN_TIME).String(),
-	//	LastStartTime: p.Monitor().Get(pipeliner.METRICS_KEY_EXECUTOR_LAST_START_TIME).String(),
-	//	LastEndTime:   p.Monitor().Get(pipeliner.METRICS_KEY_EXECUTOR_LAST_END_TIME).String(),
-	//	Components:    convertComponents(p.ListComponents()),
-	//	Processors:    convertProcessors(p.ListProcessors()),
-	//	RawConfig:     mustMarshalConfig(p.GetConfig()),
-	//	Error: func() string {
-	//		if p.Error() != nil {
-	//			return p.Error().Error()
-	//		}
-	//		return ""
-	//	}(),
-	//	StreamError:      streamError,
-	//	StreamErrorCount: streamErrorCount,
-	//}
+	// TODO 调度时间和stream错误等监控指标需要通过子进程的/metrics获取
+	view := &proto.ExecutorView{
+		Name:       p.Name(),
+		State:      p.State().String(),
+		Components: convertComponents(p.ListComponents(), p.Health()),
+		Processors: convertProcessors(p.ListProcessors()),
+		RawConfig:  []byte(p.Config()),
+	}
+	if p.Error() != nil {
+		view.Error = p.Error().Error()
+	}
+	return view
 }
 
 //func mustMarshalConfig(config executor.Config) []byte {
@@ -324,10 +401,15 @@ func convertExecutor2ExecutorView(p Executor) *proto.ExecutorView {
 //	return b
 //}
 
-func convertComponents(comps []executor.Component) []proto.ComponentView {
+func convertComponents(comps []executor.Component, health executor.Health) []proto.ComponentView {
+	healthMap := map[string]executor.ComponentHealth{}
+	for _, h := range health.Components {
+		healthMap[h.Name] = h
+	}
+
 	var res []proto.ComponentView
 	for _, c := range comps {
-		res = append(res, proto.ComponentView{
+		view := proto.ComponentView{
 			Name:         c.Name,
 			RawConfig:    c.RawConfig,
 			SampleConfig: c.Factory.SampleConfig(),
@@ -335,7 +417,13 @@ func convertComponents(comps []executor.Component) []proto.ComponentView {
 			ReflectType:  fmt.Sprint(c.Factory.ExampleType()),
 			InjectName:   c.Component.Instance().Name(),
 			ReflectValue: c.Component.Instance().Value().String(),
-		})
+			Primary:      c.Primary,
+		}
+		if h, ok := healthMap[view.InjectName]; ok {
+			hv := convertComponentHealth(h)
+			view.Health = &hv
+		}
+		res = append(res, view)
 	}
 
 	return res
@@ -344,12 +432,48 @@ func convertComponents(comps []executor.Component) []proto.ComponentView {
 func convertProcessors(procs []executor.Processor) []proto.ProcessorView {
 	var res []proto.ProcessorView
 	for _, c := range procs {
-		res = append(res, proto.ProcessorView{
+		view := proto.ProcessorView{
 			Name:         c.Name,
 			RawConfig:    c.RawConfig,
 			Description:  c.Factory.Description(),
 			SampleConfig: c.Factory.SampleConfig(),
-		})
+		}
+		if c.Processor != nil {
+			setSignature(&view, c.Processor)
+		}
+		res = append(res, view)
 	}
 	return res
 }
+
+func newExecutor(_type, path string) (executor.Executor, error) {
+	f, err := executor.GetFactory(_type)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.New(string(body))
+}
+
+// sharedReferences returns the shared components referenced by the executor config at path.
+func sharedReferences(_type, path string) ([]string, error) {
+	f, err := executor.GetFactory(_type)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := f.(executor.SharedReferrer)
+	if !ok {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.SharedReferences(string(body))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/component"
 	"github.com/shima-park/lotus/pkg/rpc/proto"
 	"gopkg.in/yaml.v2"
 )
@@ -29,8 +30,9 @@ type metadata struct {
 }
 
 type registry struct {
-	PluginPaths         []string             `yaml:"plugin_paths"`
-	ExecutorConfigPaths map[string]*[]string `yaml:"executor_config_paths"`
+	PluginPaths         []string                      `yaml:"plugin_paths"`
+	ExecutorConfigPaths map[string]*[]string          `yaml:"executor_config_paths"`
+	SharedComponents    []proto.SharedComponentConfig `yaml:"shared_components"`
 }
 
 func NewMetadata(metapath string) (proto.Metadata, error) {
@@ -41,6 +43,10 @@ func NewMetadata(metapath string) (proto.Metadata, error) {
 		}
 		metapath = filepath.Join(pwd, METADATA_PATH)
 	}
+	metapath, err := filepath.Abs(metapath)
+	if err != nil {
+		return nil, err
+	}
 
 	m := &metadata{
 		metapath: metapath,
@@ -50,10 +56,12 @@ func NewMetadata(metapath string) (proto.Metadata, error) {
 		},
 	}
 
-	err := os.MkdirAll(metapath, 0750)
+	err = os.MkdirAll(metapath, 0750)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to create data path %s", metapath)
 	}
+	// 组件的状态也保存在元数据目录下, e.g. io_tail的offset
+	component.SetMetadataPath(metapath)
 
 	_, err = os.Stat(m.metafile)
 	if os.IsNotExist(err) {
@@ -150,6 +158,34 @@ func (m *metadata) RemoveExecutorConfigPath(_type, path string) error {
 	return nil
 }
 
+func (m *metadata) PutSharedComponent(conf proto.SharedComponentConfig) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, c := range m.registry.SharedComponents {
+		if c.Name == conf.Name {
+			m.registry.SharedComponents[i] = conf
+			return m.save()
+		}
+	}
+
+	m.registry.SharedComponents = append(m.registry.SharedComponents, conf)
+	return m.save()
+}
+
+func (m *metadata) RemoveSharedComponent(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, c := range m.registry.SharedComponents {
+		if c.Name == name {
+			m.registry.SharedComponents = append(m.registry.SharedComponents[:i], m.registry.SharedComponents[i+1:]...)
+			break
+		}
+	}
+	return m.save()
+}
+
 func (m *metadata) AddPluginPath(path string) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -261,7 +297,9 @@ func (m *metadata) Snapshot(do func(proto.Snapshot)) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	var s proto.Snapshot
+	s := proto.Snapshot{
+		ExecutorConfigPaths: map[string][]string{},
+	}
 	for _, pp := range m.registry.PluginPaths {
 		s.PluginPaths = append(s.PluginPaths, pp)
 	}
@@ -272,6 +310,8 @@ func (m *metadata) Snapshot(do func(proto.Snapshot)) {
 		}
 	}
 
+	s.SharedComponents = append(s.SharedComponents, m.registry.SharedComponents...)
+
 	do(s)
 }
 
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"encoding/json"
 	"sort"
 
+	"github.com/shima-park/lotus/pkg/common/schema"
 	"github.com/shima-park/lotus/pkg/processor"
 	"github.com/shima-park/lotus/pkg/rpc/proto"
 )
@@ -36,9 +38,51 @@ func (s *processorService) Find(name string) (*proto.ProcessorView, error) {
 }
 
 func newProcessorView(name string, factory processor.Factory) *proto.ProcessorView {
-	return &proto.ProcessorView{
+	view := &proto.ProcessorView{
 		Name:         name,
 		SampleConfig: factory.SampleConfig(),
 		Description:  factory.Description(),
+		Schema:       marshalSchema(processor.SchemaOf(factory)),
+	}
+	if example := factory.Example(); example != nil {
+		setSignature(view, example)
+	}
+	return view
+}
+
+// marshalSchema returns the JSON of the schema, or nil if there is none.
+func marshalSchema(s *schema.Schema) json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// setSignature fills the request and response fields of the processor p.
+func setSignature(view *proto.ProcessorView, p processor.Processor) {
+	sig, err := processor.SignatureOf(p)
+	if err != nil {
+		return
+	}
+
+	view.Typed = sig.Typed
+	for _, f := range sig.Request {
+		view.Request = append(view.Request, convertProcessorField(f))
+	}
+	for _, f := range sig.Response {
+		view.Response = append(view.Response, convertProcessorField(f))
+	}
+}
+
+func convertProcessorField(f processor.Field) proto.ProcessorFieldView {
+	return proto.ProcessorFieldView{
+		Name:       f.Name,
+		Type:       f.Type,
+		InjectName: f.InjectName,
+		Options:    f.Options,
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/shima-park/lotus/pkg/common/log"
+	"github.com/shima-park/lotus/pkg/component"
 	"github.com/shima-park/lotus/pkg/rpc/proto"
 )
 
@@ -21,7 +22,7 @@ func NewService(metadata proto.Metadata) *Service {
 	s := &Service{
 		metadata:  metadata,
 		Executor:  NewExecutorService(metadata),
-		Component: NewComponentService(),
+		Component: NewComponentService(metadata),
 		Processor: NewProcessorService(),
 		Plugin:    NewPluginService(metadata),
 	}
@@ -43,6 +44,18 @@ func (s *Service) Start() error {
 			}
 		}
 
+		// 共享组件需要在executor之前声明, 否则pipeline无法引用
+		for _, conf := range snapshot.SharedComponents {
+			err := component.DeclareShared(component.SharedConfig{
+				Name:      conf.Name,
+				Component: conf.Component,
+				RawConfig: conf.RawConfig,
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
 		for _type, paths := range snapshot.ExecutorConfigPaths {
 			for _, path := range paths {
 				if isNotExists(path) {